@@ -0,0 +1,101 @@
+/* adk.go - additional decryption keys added to all encryptions
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// Additional decryption keys (ADKs) are escrow keys, e.g. of a company,
+// which can decrypt everything encrypted by the application. Unlike the
+// gpg option --encrypt-to in gpg.conf they are added by the library, so
+// they apply to every home directory and can not be left out by a
+// configuration.
+
+package gpggohigh
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+var additionalKeys struct {
+	sync.RWMutex
+	fingerprints []string
+}
+
+// SetAdditionalDecryptionKeys sets the fingerprints of the keys added as
+// recipients to all following public key encryptions of the package
+// functions and of all sessions. An encryption fails, if one of the keys
+// is not in the keyring. The keys are listed in the AdditionalKeys of
+// the audit events of the encryptions. Symmetric encryptions are not
+// affected. nil removes the keys.
+func SetAdditionalDecryptionKeys(fingerprints []string) error {
+	normalized := make([]string, 0, len(fingerprints))
+	for _, fpr := range fingerprints {
+		fpr = NormalizeFingerprint(fpr)
+		if !isFingerprint(fpr) {
+			return fmt.Errorf("SetAdditionalDecryptionKeys - not a fingerprint: %q", fpr)
+		}
+		if !slices.Contains(normalized, fpr) {
+			normalized = append(normalized, fpr)
+		}
+	}
+
+	additionalKeys.Lock()
+	defer additionalKeys.Unlock()
+	if len(normalized) == 0 {
+		normalized = nil
+	}
+	additionalKeys.fingerprints = normalized
+	return nil
+}
+
+// AdditionalDecryptionKeys returns the fingerprints set by
+// SetAdditionalDecryptionKeys.
+func AdditionalDecryptionKeys() []string {
+	additionalKeys.RLock()
+	defer additionalKeys.RUnlock()
+	return slices.Clone(additionalKeys.fingerprints)
+}
+
+// appendAdditionalKeys appends the keys of the additional decryption
+// keys to the recipient keys, which are looked up using myContext.
+func appendAdditionalKeys(myContext *gpgme.Context, keys []*gpgme.Key) (
+	[]*gpgme.Key, error) {
+
+	for _, fpr := range AdditionalDecryptionKeys() {
+		if slices.ContainsFunc(keys, func(k *gpgme.Key) bool {
+			return FingerprintsEqual(k.Fingerprint(), fpr)
+		}) {
+			continue
+		}
+		found, err := findKeys(myContext, fpr, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(found) == 0 {
+			return nil, fmt.Errorf("additional decryption key %w: %s", ErrKeyNotFound, fpr)
+		}
+		logDebug("adding additional decryption key", "fingerprint", fpr)
+		keys = append(keys, found...)
+	}
+	return keys, nil
+}
+
+// EOF
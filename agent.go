@@ -0,0 +1,361 @@
+/* agent.go - gpg-agent interaction for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// The agent is asked with gpg-connect-agent, because gpgme.go can only
+// send Assuan commands to the agent of the default home directory.
+
+package gpggohigh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// gpgConnectAgentName returns the file name of gpg-connect-agent,
+// which is installed next to gpgconf.
+func gpgConnectAgentName() string {
+	if dir := gpgme.GetDirInfo("bindir"); dir != "" {
+		return filepath.Join(dir, "gpg-connect-agent")
+	}
+	return "gpg-connect-agent"
+}
+
+// agentCommand sends the Assuan command to the gpg-agent of homeDir,
+// empty for the one of SetHomeDir, and returns the status lines of the
+// response without the leading "S ". command may also be a script of
+// several lines for gpg-connect-agent.
+func agentCommand(homeDir, command string) (status []string, err error) {
+	if homeDir == "" {
+		_, homeDir = gpgEngine()
+	}
+	var args []string
+	if homeDir != "" {
+		args = append(args, "--homedir", homeDir)
+	}
+
+	// the command is sent on stdin, so it can not be seen in the
+	// process list, e.g. a preset passphrase; for the same reason only
+	// its name is logged, the one of the last line of a script
+	name := strings.Fields(command[strings.LastIndex(command, "\n")+1:])[0]
+	logDebug("agent command", "homedir", homeDir, "command", name)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(gpgConnectAgentName(), args...)
+	cmd.Stdin = strings.NewReader(command + "\n/bye\n")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return nil, fmt.Errorf("gpg-connect-agent failed: %w", err)
+		}
+		return nil, fmt.Errorf("gpg-connect-agent failed: %w: %s", err, msg)
+	}
+
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		switch {
+		case strings.HasPrefix(line, "S "):
+			status = append(status, line[2:])
+		case strings.HasPrefix(line, "ERR "):
+			code, _, _ := strings.Cut(line[4:], " ")
+			if value, err := strconv.ParseUint(code, 10, 32); err == nil &&
+				gpgme.ErrorCode(value&errCodeMask) == errCodeBadPassphrase {
+				return status, fmt.Errorf("agent command %s failed: %w: %s",
+					name, ErrBadPassphrase, line[4:])
+			}
+			return status, fmt.Errorf("agent command %s failed: %s", name, line[4:])
+		}
+	}
+	return status, nil
+}
+
+// agentRunning reports whether a gpg-agent runs for homeDir, empty for
+// the one of SetHomeDir, without starting one.
+func agentRunning(homeDir string) bool {
+	if homeDir == "" {
+		_, homeDir = gpgEngine()
+	}
+	args := []string{"--no-autostart"}
+	if homeDir != "" {
+		args = append(args, "--homedir", homeDir)
+	}
+	args = append(args, "GETINFO pid", "/bye")
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(gpgConnectAgentName(), args...)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return false
+	}
+	return strings.HasPrefix(stdout.String(), "D ")
+}
+
+// GnuPG daemons for LaunchDaemon, ReloadDaemon and KillDaemon.
+const (
+	DaemonAgent    = "gpg-agent"
+	DaemonDirmngr  = "dirmngr"
+	DaemonScdaemon = "scdaemon"
+	DaemonAll      = "all" // all daemons, for ReloadDaemon and KillDaemon only
+)
+
+// LaunchDaemon starts the daemon, e.g. DaemonDirmngr, for the home
+// directory, if it is not running yet, like `gpgconf --launch`.
+func LaunchDaemon(daemon string) error {
+	if err := controlDaemon("", "--launch", daemon); err != nil {
+		return fmt.Errorf("LaunchDaemon - %w", err)
+	}
+	return nil
+}
+
+// ReloadDaemon makes the running daemon re-read its configuration, like
+// `gpgconf --reload`. gpg-agent also flushes its passphrase cache.
+func ReloadDaemon(daemon string) error {
+	if err := controlDaemon("", "--reload", daemon); err != nil {
+		return fmt.Errorf("ReloadDaemon - %w", err)
+	}
+	return nil
+}
+
+// KillDaemon stops the daemon running for the home directory, like
+// `gpgconf --kill`. It is no error, if the daemon is not running.
+func KillDaemon(daemon string) error {
+	if err := controlDaemon("", "--kill", daemon); err != nil {
+		return fmt.Errorf("KillDaemon - %w", err)
+	}
+	return nil
+}
+
+// EnsureAgentRunning starts gpg-agent for the home directory, if it is
+// not running yet, e.g. before the first operation of a service.
+func EnsureAgentRunning() error {
+	return LaunchDaemon(DaemonAgent)
+}
+
+// ReloadAgent makes gpg-agent re-read its configuration and flush its
+// passphrase cache.
+func ReloadAgent() error {
+	return ReloadDaemon(DaemonAgent)
+}
+
+// KillAgent stops gpg-agent, e.g. at the end of a test.
+func KillAgent() error {
+	return KillDaemon(DaemonAgent)
+}
+
+// controlDaemon runs gpgconf with the operation for the daemon of homeDir,
+// empty for the one of SetHomeDir.
+func controlDaemon(homeDir, operation, daemon string) error {
+	switch daemon {
+	case DaemonAgent, DaemonDirmngr, DaemonScdaemon:
+	case DaemonAll:
+		if operation == "--launch" {
+			return fmt.Errorf("can not launch %q daemons", daemon)
+		}
+	default:
+		return fmt.Errorf("unknown daemon %q", daemon)
+	}
+	if homeDir == "" {
+		_, homeDir = gpgEngine()
+	}
+	_, err := runGpgconfHome(homeDir, operation, daemon)
+	return err
+}
+
+// FlushPassphraseCache removes the passphrases of the secret keys with
+// the given fingerprints from the cache of gpg-agent, so the next
+// operation asks for them again. Without fingerprints the whole cache is
+// flushed. A not running agent has nothing cached.
+func FlushPassphraseCache(fingerprints ...string) error {
+	if err := flushPassphraseCache("", fingerprints); err != nil {
+		return fmt.Errorf("FlushPassphraseCache - %w", err)
+	}
+	return nil
+}
+
+// flushPassphraseCache implements FlushPassphraseCache for homeDir, empty
+// for the one of SetHomeDir.
+func flushPassphraseCache(homeDir string, fingerprints []string) error {
+	if !agentRunning(homeDir) {
+		return nil
+	}
+	if len(fingerprints) == 0 {
+		_, err := agentCommand(homeDir, "RELOADAGENT")
+		return err
+	}
+	for _, fpr := range fingerprints {
+		// the keygrips of the primary key and of all subkeys
+		keys, err := colonKeys(homeDir, fpr, true)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if k.Keygrip == "" {
+				continue
+			}
+			_, err := agentCommand(homeDir, "CLEAR_PASSPHRASE --mode=normal "+k.Keygrip)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SecretKeyAvailability tells whether a secret key needed to decrypt
+// data is available.
+type SecretKeyAvailability struct {
+	KeyID            string // the key ID the data is encrypted to
+	Fingerprint      string // the fingerprint of the subkey, empty if unknown
+	Available        bool   // a usable secret key is present
+	OnCard           bool   // the secret key is stored on a smartcard
+	PassphraseCached bool   // the agent has the passphrase cached
+}
+
+// HaveSecretKeyFor reports whether the encrypted data cipherText can
+// probably be decrypted, i.e. a usable secret key for one of its
+// recipients is present, without decrypting it. For each recipient the
+// availability tells, whether the passphrase is cached by gpg-agent, so
+// the decryption would not ask for it.
+// Data encrypted to hidden recipients (key ID 0000000000000000) can not
+// be checked, they are reported as not available.
+func HaveSecretKeyFor(cipherText []byte) (have bool,
+	keys []SecretKeyAvailability, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return false, nil, fmt.Errorf("HaveSecretKeyFor - %w", err)
+	}
+	defer myContext.Release()
+
+	have, keys, err = haveSecretKeyFor(myContext, gpgCommand{}, cipherText)
+	if err != nil {
+		return false, nil, fmt.Errorf("HaveSecretKeyFor - %w", err)
+	}
+	return have, keys, nil
+}
+
+// haveSecretKeyFor implements HaveSecretKeyFor using myContext for the
+// key lookup and the command cmd to read the encrypted data.
+func haveSecretKeyFor(myContext *gpgme.Context, cmd gpgCommand, cipherText []byte) (
+	have bool, keys []SecretKeyAvailability, err error) {
+
+	keyIDs, err := encryptedToKeyIDs(cmd, bytes.NewReader(cipherText))
+	if err != nil {
+		return false, nil, err
+	}
+	return haveSecretKeys(myContext, keyIDs)
+}
+
+// HaveSecretKeys reports like HaveSecretKeyFor, whether a usable secret
+// key is present for one of the keys selected by key IDs or
+// fingerprints, e.g. the recipients of data about to be received.
+func HaveSecretKeys(keyIDs []string) (have bool, keys []SecretKeyAvailability,
+	err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return false, nil, fmt.Errorf("HaveSecretKeys - %w", err)
+	}
+	defer myContext.Release()
+
+	have, keys, err = haveSecretKeys(myContext, keyIDs)
+	if err != nil {
+		return false, nil, fmt.Errorf("HaveSecretKeys - %w", err)
+	}
+	return have, keys, nil
+}
+
+// haveSecretKeys implements HaveSecretKeys using myContext.
+func haveSecretKeys(myContext *gpgme.Context, keyIDs []string) (have bool,
+	keys []SecretKeyAvailability, err error) {
+
+	homeDir := contextHomeDir(myContext)
+	for _, id := range keyIDs {
+		a := SecretKeyAvailability{KeyID: id}
+		id = strings.ToUpper(strings.TrimPrefix(id, "0x"))
+		if strings.Trim(id, "0") == "" {
+			// hidden recipient
+			keys = append(keys, a)
+			continue
+		}
+
+		found, err := findKeys(myContext, id, true)
+		if err != nil {
+			return false, nil, fmt.Errorf("FindKeys failed: %w", err)
+		}
+		for _, k := range found {
+			for sk := k.SubKeys(); sk != nil; sk = sk.Next() {
+				if !strings.HasSuffix(sk.Fingerprint(), id) {
+					continue
+				}
+				a.Fingerprint = sk.Fingerprint()
+				a.Available = sk.Secret() && !sk.Revoked() && !sk.Expired() &&
+					!sk.Disabled() && !sk.Invalid() && !k.Disabled()
+				a.OnCard = sk.CardNumber() != ""
+			}
+		}
+		if a.Available && !a.OnCard {
+			a.PassphraseCached = passphraseCached(homeDir, a.Fingerprint)
+		}
+		have = have || a.Available
+		keys = append(keys, a)
+	}
+	return have, keys, nil
+}
+
+// passphraseCached reports whether gpg-agent of homeDir has the
+// passphrase of the secret key of the subkey fingerprint cached.
+func passphraseCached(homeDir, fingerprint string) bool {
+	keys, err := colonKeys(homeDir, fingerprint, true)
+	grip := keys[fingerprint].Keygrip
+	if err != nil || grip == "" {
+		return false
+	}
+	status, err := agentCommand(homeDir, "KEYINFO "+grip)
+	if err != nil || len(status) == 0 {
+		return false
+	}
+	// KEYINFO <keygrip> <type> <serialno> <idstr> <cached> <protection> ...
+	fields := strings.Fields(status[0])
+	return len(fields) > 5 && fields[5] == "1"
+}
+
+// encryptedToKeyIDs returns the key IDs of the recipients of the
+// encrypted data read from r, without decrypting it.
+func encryptedToKeyIDs(cmd gpgCommand, r io.Reader) (keyIDs []string, err error) {
+	info, err := listPackets(cmd, r)
+	if err != nil {
+		return nil, err
+	}
+	return info.KeyIDs(), nil
+}
+
+// EOF
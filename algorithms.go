@@ -0,0 +1,264 @@
+/* algorithms.go - algorithm selection for encryption for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// gpgme has no context options for the compression and the cipher, so
+// encryption with EncryptOptions is done by calling gpg directly.
+
+package gpggohigh
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Compression algorithms for EncryptOptions.
+const (
+	CompressDefault = ""      // the preferences of the recipients
+	CompressNone    = "none"  // no compression, e.g. for already compressed data
+	CompressZIP     = "zip"   // ZIP (RFC 1951)
+	CompressZLIB    = "zlib"  // ZLIB (RFC 1950)
+	CompressBZIP2   = "bzip2" // BZip2
+)
+
+// EncryptOptions selects the algorithms used by EncryptFileOptions and
+// EncryptBytesOptions. The zero value uses the defaults of gpg, which
+// follow the preferences of the recipient keys. Only OpenPGP is supported.
+type EncryptOptions struct {
+	// Compression is one of the Compress constants.
+	Compression string
+	// Cipher forces the symmetric cipher, e.g. "AES256", like the gpg
+	// option --cipher-algo. The preferences of the recipients are
+	// ignored, so they must be able to decrypt it.
+	Cipher string
+	// AEAD requests OCB encrypted data instead of CFB with MDC, like the
+	// gpg option --force-ocb. It needs GnuPG 2.4 or later.
+	AEAD bool
+	// RemoveSource removes the source file of EncryptFileOptions after
+	// the encrypted file is completely written. RemoveSourceOverwrite
+	// overwrites it before, which does not reliably destroy the content
+	// on copy-on-write file systems, flash storage or with snapshots.
+	// If the encryption fails, the source file is kept.
+	// EncryptBytesOptions ignores it.
+	RemoveSource RemoveMode
+	// Tee are further destinations receiving the encrypted data while it
+	// is written, e.g. an upload, so the data is encrypted only once and
+	// the output file is not read back. If the encryption fails, they
+	// may have received a part of the data.
+	Tee []io.Writer
+	// PreserveMetadata copies the permissions and the modification time
+	// of the source file to the encrypted file, e.g. for backups.
+	// EncryptBytesOptions ignores it.
+	PreserveMetadata bool
+}
+
+// EncryptFileOptions encrypts a file like EncryptFile with the
+// algorithms selected by opts. If armored is true, the output is ASCII
+// armored and the default destination has the extension `.asc`.
+func EncryptFileOptions(sourceFilename, destinationFilename string,
+	recipients []string, sign, armored bool, opts EncryptOptions) (err error) {
+
+	err = encryptFileOptions(gpgCommand{}, sourceFilename, destinationFilename,
+		recipients, sign, armored, opts)
+	if err != nil {
+		return fmt.Errorf("EncryptFile - %w", err)
+	}
+	return nil
+}
+
+// encryptFileOptions implements EncryptFileOptions running the command cmd.
+func encryptFileOptions(cmd gpgCommand, sourceFilename, destinationFilename string,
+	recipients []string, sign, armored bool, opts EncryptOptions) (err error) {
+
+	destination := optionsDestination(sourceFilename, destinationFilename, armored)
+	defer func() {
+		audit(AuditEncrypt, "EncryptFile", err, func(event *AuditEvent) {
+			event.Recipients = recipientFingerprints(cmd.homeDir, recipients)
+			// empty for a removed source
+			event.Input, event.InputSHA256 = sourceFilename, hashFile(sourceFilename)
+			if err == nil {
+				event.Output, event.OutputSHA256 = destination, hashFile(destination)
+			}
+		})
+	}()
+
+	args, err := encryptFileArgs(recipients, sign, armored, opts)
+	if err != nil {
+		return err
+	}
+	if len(opts.Tee) > 0 {
+		err = encryptFileTee(cmd, args, sourceFilename, destination, opts.Tee)
+	} else {
+		err = encryptFileTo(cmd, args, sourceFilename, destination)
+	}
+	if err != nil {
+		return err
+	}
+	if opts.PreserveMetadata {
+		if err := copyMetadata(sourceFilename, destination); err != nil {
+			return fmt.Errorf("copying the metadata failed: %w", err)
+		}
+	}
+	return removeSource(sourceFilename, opts.RemoveSource)
+}
+
+// encryptFileTo runs the command cmd with the encryption arguments args
+// to let gpg write the encrypted file destination.
+func encryptFileTo(cmd gpgCommand, args []string, sourceFilename,
+	destination string) error {
+
+	outFilename, err := tempName(destination)
+	if err != nil {
+		return err
+	}
+	cmd.args = append(args, "--yes", "--output", outFilename, "--", sourceFilename)
+	if _, err = cmd.run(); err != nil {
+		_ = os.Remove(outFilename)
+		return err
+	}
+	return commitFile(outFilename, destination)
+}
+
+// encryptFileTee runs the command cmd with the encryption arguments args
+// and writes its output to the file destination and the writers tee.
+func encryptFileTee(cmd gpgCommand, args []string, sourceFilename,
+	destination string, tee []io.Writer) error {
+
+	fhOut, err := createTemp(destination)
+	if err != nil {
+		return err
+	}
+	defer fhOut.Close()
+
+	cmd.args = append(args, "--output", "-", "--", sourceFilename)
+	cmd.stdout = io.MultiWriter(append([]io.Writer{fhOut}, tee...)...)
+	if _, err = cmd.run(); err != nil {
+		_ = os.Remove(fhOut.Name())
+		return err
+	}
+	return commitTemp(fhOut, destination)
+}
+
+// optionsDestination returns the destination of EncryptFileOptions, the
+// source file name with the extension `.gpg` or `.asc` if none is given.
+func optionsDestination(sourceFilename, destinationFilename string, armored bool) string {
+	if destinationFilename != "" {
+		return destinationFilename
+	}
+	if armored {
+		return sourceFilename + ".asc"
+	}
+	return sourceFilename + ".gpg"
+}
+
+// encryptFileArgs returns the gpg arguments for the encryption of a file
+// like encryptArgs and checks the options only used for files.
+func encryptFileArgs(recipients []string, sign, armored bool, opts EncryptOptions) (
+	[]string, error) {
+
+	if opts.RemoveSource < RemoveSourceKeep || opts.RemoveSource > RemoveSourceOverwrite {
+		return nil, fmt.Errorf("unknown remove mode %d", opts.RemoveSource)
+	}
+	return encryptArgs(recipients, sign, armored, opts)
+}
+
+// EncryptBytesOptions encrypts a memory buffer like EncryptBytes with the
+// algorithms selected by opts.
+func EncryptBytesOptions(plainText []byte, recipients []string, sign, armored bool,
+	opts EncryptOptions) (cipherText []byte, err error) {
+
+	cipherText, err = encryptBytesOptions(gpgCommand{}, plainText, recipients,
+		sign, armored, opts)
+	if err != nil {
+		return nil, fmt.Errorf("EncryptBytes - %w", err)
+	}
+	return cipherText, nil
+}
+
+// encryptBytesOptions implements EncryptBytesOptions running the command cmd.
+func encryptBytesOptions(cmd gpgCommand, plainText []byte, recipients []string,
+	sign, armored bool, opts EncryptOptions) (cipherText []byte, err error) {
+
+	defer func() {
+		audit(AuditEncrypt, "EncryptBytes", err, func(event *AuditEvent) {
+			event.Recipients = recipientFingerprints(cmd.homeDir, recipients)
+			event.InputSHA256 = hashBytes(plainText)
+			if err == nil {
+				event.OutputSHA256 = hashBytes(cipherText)
+			}
+		})
+	}()
+
+	args, err := encryptArgs(recipients, sign, armored, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	cmd.args = args
+	cmd.stdin = bytes.NewReader(plainText)
+	cmd.stdout = io.MultiWriter(append([]io.Writer{&out}, opts.Tee...)...)
+	if _, err := cmd.run(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// encryptArgs returns the gpg arguments for an encryption like the gpgme
+// based one, which trusts all recipient keys, with the algorithms of opts.
+func encryptArgs(recipients []string, sign, armored bool, opts EncryptOptions) (
+	[]string, error) {
+
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no recipients given")
+	}
+
+	args := []string{"--encrypt", "--trust-model", "always"}
+	if sign {
+		args = append(args, "--sign")
+	}
+	if armored {
+		args = append(args, "--armor")
+	}
+	for _, r := range recipients {
+		args = append(args, "--recipient", r)
+	}
+	for _, fpr := range AdditionalDecryptionKeys() {
+		args = append(args, "--recipient", fpr)
+	}
+
+	switch opts.Compression {
+	case CompressDefault:
+	case CompressNone, CompressZIP, CompressZLIB, CompressBZIP2:
+		args = append(args, "--compress-algo", opts.Compression)
+	default:
+		return nil, fmt.Errorf("unknown compression algorithm %q", opts.Compression)
+	}
+	if opts.Cipher != "" {
+		args = append(args, "--cipher-algo", opts.Cipher)
+	}
+	if opts.AEAD {
+		args = append(args, "--force-ocb")
+	}
+	return args, nil
+}
+
+// EOF
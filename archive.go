@@ -0,0 +1,346 @@
+/* archive.go - encryption of directory trees for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// EncryptDirectory packs the directory tree dir into a tar archive and
+// encrypts it to the recipients, like `gpgtar --encrypt` does. The
+// archive can also be extracted with gpgtar.
+// The entries are stored below the base name of dir.
+// If destinationFilename is empty, dir is used with an added `.tar.gpg`
+// extension.
+// Regular files, directories and symbolic links are archived, other
+// file types result in an error.
+func EncryptDirectory(dir, destinationFilename string, recipients []string,
+	sign bool) error {
+	return EncryptDirectoryCtx(context.Background(), dir, destinationFilename,
+		recipients, sign)
+}
+
+// EncryptDirectoryCtx encrypts a directory tree like EncryptDirectory.
+// ctx is checked while the files are archived and encrypted, see
+// EncryptStreamCtx.
+func EncryptDirectoryCtx(ctx context.Context, dir, destinationFilename string,
+	recipients []string, sign bool) error {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return fmt.Errorf("EncryptDirectory - %w", err)
+	}
+	defer myContext.Release()
+
+	return encryptDirectory(ctx, myContext, dir, destinationFilename, recipients, sign)
+}
+
+// encryptDirectory implements EncryptDirectory using myContext.
+func encryptDirectory(ctx context.Context, myContext *gpgme.Context, dir,
+	destinationFilename string, recipients []string, sign bool) (err error) {
+
+	dir = filepath.Clean(dir)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("EncryptDirectory - Stat failed: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("EncryptDirectory - %s is not a directory", dir)
+	}
+
+	destination := destinationFilename
+	if destination == "" {
+		destination = dir + ".tar.gpg"
+	}
+
+	fhOut, err := createTemp(destination)
+	if err != nil {
+		return fmt.Errorf("EncryptDirectory - Create (out) failed: %w", err)
+	}
+	defer fhOut.Close()
+
+	// the archive is written to the pipe while it is encrypted
+	pr, pw := io.Pipe()
+	tarDone := make(chan error, 1)
+	go func() {
+		err := writeTar(ctx, dir, pw)
+		pw.CloseWithError(err)
+		tarDone <- err
+	}()
+
+	err = encryptStream(ctx, myContext, "EncryptDirectory", pr, fhOut, recipients, sign)
+	pr.Close() // stops the archive writer if the encryption failed early
+	if tarErr := <-tarDone; tarErr != nil && !errors.Is(tarErr, io.ErrClosedPipe) {
+		err = fmt.Errorf("EncryptDirectory - archiving failed: %w", tarErr)
+	}
+	if err != nil {
+		_ = os.Remove(fhOut.Name())
+		return err
+	}
+
+	err = commitTemp(fhOut, destination)
+	if err != nil {
+		return fmt.Errorf("EncryptDirectory - writing %s failed: %w", destination, err)
+	}
+	return nil
+}
+
+// writeTar writes the directory tree dir as tar archive to w. The entry
+// names start with the base name of dir.
+func writeTar(ctx context.Context, dir string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	base := filepath.Base(dir)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		switch {
+		case info.Mode().IsRegular(), info.IsDir():
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err = os.Readlink(path)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: unsupported file type %s", path, info.Mode().Type())
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(filepath.Join(base, rel))
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		fh, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer fh.Close()
+		_, err = io.Copy(tw, fh)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// DecryptArchive decrypts an archive created by EncryptDirectory or
+// gpgtar and extracts it into the directory destinationDir, which is
+// created if needed. Signatures contained in the data are verified.
+// Entries which would be written outside of destinationDir, e.g. by
+// absolute names, `..` or symbolic links, are rejected, as are symbolic
+// links with an absolute target or `..` in the target, existing files
+// and entry types other than files, directories and symbolic links.
+// The return values are the same as for DecryptStream.
+func DecryptArchive(cypherFilename, destinationDir string) (
+	decryptionResult gpgme.DecryptResultType, signatures []gpgme.Signature,
+	warning string, err error) {
+	return DecryptArchiveCtx(context.Background(), cypherFilename, destinationDir)
+}
+
+// DecryptArchiveCtx decrypts and extracts an archive like DecryptArchive.
+// ctx is checked while the data is decrypted and extracted, see
+// DecryptStreamCtx.
+func DecryptArchiveCtx(ctx context.Context, cypherFilename, destinationDir string) (
+	decryptionResult gpgme.DecryptResultType, signatures []gpgme.Signature,
+	warning string, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		err = fmt.Errorf("DecryptArchive - %w", err)
+		return
+	}
+	defer myContext.Release()
+
+	return decryptArchive(ctx, myContext, cypherFilename, destinationDir)
+}
+
+// decryptArchive implements DecryptArchive using myContext.
+func decryptArchive(ctx context.Context, myContext *gpgme.Context,
+	cypherFilename, destinationDir string) (
+	decryptionResult gpgme.DecryptResultType, signatures []gpgme.Signature,
+	warning string, err error) {
+
+	fhIn, err := os.Open(cypherFilename)
+	if err != nil {
+		err = fmt.Errorf("DecryptArchive - Open (in) failed: %w", err)
+		return
+	}
+	defer fhIn.Close()
+
+	err = os.MkdirAll(destinationDir, 0700)
+	if err != nil {
+		err = fmt.Errorf("DecryptArchive - Mkdir failed: %w", err)
+		return
+	}
+
+	// the archive is extracted while it is decrypted
+	pr, pw := io.Pipe()
+	tarDone := make(chan error, 1)
+	go func() {
+		err := extractTar(pr, destinationDir)
+		pr.CloseWithError(err)
+		tarDone <- err
+	}()
+
+	decryptionResult, _, signatures, warning, err = decryptStream(ctx, myContext,
+		"DecryptArchive", fhIn, pw)
+	pw.CloseWithError(err)
+	if tarErr := <-tarDone; tarErr != nil {
+		err = errors.Join(err, fmt.Errorf("DecryptArchive - extracting failed: %w", tarErr))
+	}
+	return
+}
+
+// extractTar extracts the tar archive read from r into dir.
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			// consume the padding, so the writer is not blocked
+			_, _ = io.Copy(io.Discard, r)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.FromSlash(strings.TrimSuffix(hdr.Name, "/"))
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("%s: entry outside of the destination", hdr.Name)
+		}
+		target := filepath.Join(dir, name)
+		if err := checkNoSymlink(dir, filepath.Dir(name)); err != nil {
+			return fmt.Errorf("%s: %w", hdr.Name, err)
+		}
+
+		mode := hdr.FileInfo().Mode().Perm()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.Mkdir(target, mode|0700)
+			if errors.Is(err, fs.ErrExist) {
+				if info, statErr := os.Lstat(target); statErr == nil && info.IsDir() {
+					err = nil
+				}
+			}
+		case tar.TypeReg:
+			err = extractFile(tr, target, mode)
+		case tar.TypeSymlink:
+			// without `..` a relative link can not point upwards, even
+			// when resolved through other links
+			link := filepath.FromSlash(hdr.Linkname)
+			if filepath.IsAbs(link) || hasDotDot(link) {
+				return fmt.Errorf("%s: link target %s may be outside of the destination",
+					hdr.Name, hdr.Linkname)
+			}
+			err = os.Symlink(hdr.Linkname, target)
+		default:
+			return fmt.Errorf("%s: unsupported entry type %q", hdr.Name, hdr.Typeflag)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// extractFile writes the file content read from r to the new file target.
+func extractFile(r io.Reader, target string, mode fs.FileMode) error {
+	fh, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fh, r)
+	if closeErr := fh.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// hasDotDot reports whether the path contains a `..` element.
+func hasDotDot(path string) bool {
+	for _, part := range strings.Split(path, string(filepath.Separator)) {
+		if part == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNoSymlink returns an error, if one of the existing directories of
+// the relative path rel below dir is a symbolic link. Otherwise an entry
+// could be written through an extracted link to anywhere.
+func checkNoSymlink(dir, rel string) error {
+	path := dir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if part == "." || part == "" {
+			continue
+		}
+		path = filepath.Join(path, part)
+		info, err := os.Lstat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("path contains the symbolic link %s", path)
+		}
+	}
+	return nil
+}
+
+// EOF
@@ -0,0 +1,151 @@
+/* archive_test.go - tests of the archive extraction
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tarEntry is an entry of a crafted tar stream.
+type tarEntry struct {
+	name     string
+	typeflag byte
+	link     string
+	body     string
+}
+
+// tarStream returns the tar archive of the entries.
+func tarStream(t *testing.T, entries []tarEntry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.link,
+			Mode: 0644, Size: int64(len(e.body))}
+		if e.typeflag == tar.TypeDir {
+			hdr.Mode = 0755
+		}
+		if e.typeflag != tar.TypeReg {
+			hdr.Size = 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body[:hdr.Size])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestExtractTar(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing map[string]string // files in the destination before
+		entries  []tarEntry
+		wantErr  bool
+		want     map[string]string // files in the destination after
+	}{
+		{"files, directories and links", nil,
+			[]tarEntry{{"d/", tar.TypeDir, "", ""}, {"d/f", tar.TypeReg, "", "hello"},
+				{"d/l", tar.TypeSymlink, "f", ""}, {"d/", tar.TypeDir, "", ""},
+				{"./top", tar.TypeReg, "", "top"}},
+			false, map[string]string{"d/f": "hello", "d/l": "hello", "top": "top"}},
+		{"parent", nil,
+			[]tarEntry{{"../x", tar.TypeReg, "", "evil"}},
+			true, nil},
+		{"parent below a directory", nil,
+			[]tarEntry{{"d/", tar.TypeDir, "", ""}, {"d/../../x", tar.TypeReg, "", "evil"}},
+			true, nil},
+		{"absolute", nil,
+			[]tarEntry{{"/x", tar.TypeReg, "", "evil"}},
+			true, nil},
+		{"file through a symlink", nil,
+			[]tarEntry{{"d/", tar.TypeDir, "", ""}, {"l", tar.TypeSymlink, "d", ""},
+				{"l/x", tar.TypeReg, "", "evil"}},
+			true, nil},
+		{"directory through a symlink", nil,
+			[]tarEntry{{"d/", tar.TypeDir, "", ""}, {"l", tar.TypeSymlink, "d", ""},
+				{"l/sub/", tar.TypeDir, "", ""}},
+			true, nil},
+		{"file over a symlink", nil,
+			[]tarEntry{{"f", tar.TypeReg, "", "hello"}, {"l", tar.TypeSymlink, "f", ""},
+				{"l", tar.TypeReg, "", "evil"}},
+			true, map[string]string{"f": "hello"}},
+		{"link target with parent", nil,
+			[]tarEntry{{"l", tar.TypeSymlink, "../x", ""}},
+			true, nil},
+		{"link target with parent inside", nil,
+			[]tarEntry{{"d/", tar.TypeDir, "", ""}, {"l", tar.TypeSymlink, "d/../../x", ""}},
+			true, nil},
+		{"absolute link target", nil,
+			[]tarEntry{{"l", tar.TypeSymlink, "/x", ""}},
+			true, nil},
+		{"existing file", map[string]string{"f": "old"},
+			[]tarEntry{{"f", tar.TypeReg, "", "new"}},
+			true, map[string]string{"f": "old"}},
+		{"existing file as directory", map[string]string{"d": "old"},
+			[]tarEntry{{"d/", tar.TypeDir, "", ""}},
+			true, map[string]string{"d": "old"}},
+		{"hard link", nil,
+			[]tarEntry{{"f", tar.TypeReg, "", "hello"}, {"h", tar.TypeLink, "f", ""}},
+			true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dir := filepath.Join(root, "dest")
+			if err := os.Mkdir(dir, 0700); err != nil {
+				t.Fatal(err)
+			}
+			for name, content := range tt.existing {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := extractTar(tarStream(t, tt.entries), dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractTar error = %v, want error %v", err, tt.wantErr)
+			}
+			if _, err := os.Lstat(filepath.Join(root, "x")); err == nil {
+				t.Error("an entry was written outside of the destination")
+			}
+			for name, content := range tt.want {
+				got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+				if err != nil {
+					t.Error(err)
+				} else if string(got) != content {
+					t.Errorf("%s = %q, want %q", name, got, content)
+				}
+			}
+		})
+	}
+}
+
+// EOF
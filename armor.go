@@ -0,0 +1,411 @@
+/* armor.go - ASCII armor without a crypto operation
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// gpgme only armors the output of its operations, `gpg --enarmor` always
+// uses the block type "PGP ARMORED FILE", so the armor (RFC 4880,
+// section 6) is implemented here.
+
+package gpggohigh
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Block types of the ASCII armor, the label of the BEGIN line.
+const (
+	ArmorMessage    = "PGP MESSAGE"
+	ArmorPublicKey  = "PGP PUBLIC KEY BLOCK"
+	ArmorPrivateKey = "PGP PRIVATE KEY BLOCK"
+	ArmorSignature  = "PGP SIGNATURE"
+	// ArmorSignedMessage starts a clearsigned message, which is not
+	// supported by Dearmor.
+	ArmorSignedMessage = "PGP SIGNED MESSAGE"
+)
+
+// Errors of Dearmor and DearmorReader.
+var (
+	ErrNotArmored = errors.New("no ASCII armor found")
+	ErrArmorCRC   = errors.New("ASCII armor checksum mismatch")
+)
+
+const (
+	armorBegin      = "-----BEGIN "
+	armorEnd        = "-----END "
+	armorDashes     = "-----"
+	armorLineLength = 64 // characters of base64 per line, like gpg
+	crc24Init       = 0xB704CE
+	crc24Poly       = 0x1864CFB
+)
+
+// crc24 updates the armor checksum crc with data.
+func crc24(crc uint32, data []byte) uint32 {
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= crc24Poly
+			}
+		}
+	}
+	return crc & 0xFFFFFF
+}
+
+// Armor returns the binary OpenPGP data in ASCII armor with the block
+// type blockType, e.g. ArmorPublicKey.
+func Armor(data []byte, blockType string) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := NewArmorWriter(&buf, blockType)
+	if err != nil {
+		return nil, fmt.Errorf("Armor - %w", err)
+	}
+	_, _ = w.Write(data) // writing to a bytes.Buffer does not fail
+	_ = w.Close()
+	return buf.Bytes(), nil
+}
+
+// NewArmorWriter returns a writer armoring the data written to it with
+// the block type blockType to w. Close writes the end of the armor, it
+// does not close w.
+func NewArmorWriter(w io.Writer, blockType string) (io.WriteCloser, error) {
+	if blockType == "" || strings.ContainsAny(blockType, "\r\n-") {
+		return nil, fmt.Errorf("invalid armor block type %q", blockType)
+	}
+	aw := &armorWriter{w: w, blockType: blockType, crc: crc24Init}
+	aw.lines = &lineWriter{w: w}
+	aw.encoder = base64.NewEncoder(base64.StdEncoding, aw.lines)
+	return aw, nil
+}
+
+// armorWriter implements NewArmorWriter.
+type armorWriter struct {
+	w         io.Writer
+	blockType string
+	lines     *lineWriter
+	encoder   io.WriteCloser
+	crc       uint32
+	started   bool
+}
+
+// Write implements io.Writer.
+func (aw *armorWriter) Write(p []byte) (int, error) {
+	if err := aw.start(); err != nil {
+		return 0, err
+	}
+	aw.crc = crc24(aw.crc, p)
+	return aw.encoder.Write(p)
+}
+
+// start writes the BEGIN line once.
+func (aw *armorWriter) start() error {
+	if aw.started {
+		return nil
+	}
+	aw.started = true
+	_, err := fmt.Fprintf(aw.w, "%s%s%s\n\n", armorBegin, aw.blockType, armorDashes)
+	return err
+}
+
+// Close writes the rest of the data, the checksum and the END line.
+func (aw *armorWriter) Close() error {
+	if err := aw.start(); err != nil {
+		return err
+	}
+	if err := aw.encoder.Close(); err != nil {
+		return err
+	}
+	if aw.lines.column > 0 {
+		if _, err := io.WriteString(aw.w, "\n"); err != nil {
+			return err
+		}
+	}
+	crc := []byte{byte(aw.crc >> 16), byte(aw.crc >> 8), byte(aw.crc)}
+	_, err := fmt.Fprintf(aw.w, "=%s\n%s%s%s\n", base64.StdEncoding.EncodeToString(crc),
+		armorEnd, aw.blockType, armorDashes)
+	return err
+}
+
+// lineWriter breaks the base64 text written to it into lines.
+type lineWriter struct {
+	w      io.Writer
+	column int
+}
+
+// Write implements io.Writer.
+func (lw *lineWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		chunk := p[:min(len(p), armorLineLength-lw.column)]
+		m, err := lw.w.Write(chunk)
+		n += m
+		if err != nil {
+			return n, err
+		}
+		p = p[m:]
+		lw.column += m
+		if lw.column == armorLineLength {
+			if _, err := io.WriteString(lw.w, "\n"); err != nil {
+				return n, err
+			}
+			lw.column = 0
+		}
+	}
+	return n, nil
+}
+
+// ArmorReader returns a reader of the data read from r in ASCII armor
+// with the block type blockType. It has to be closed, if it is not read
+// to the end.
+func ArmorReader(r io.Reader, blockType string) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		aw, err := NewArmorWriter(pw, blockType)
+		if err == nil {
+			_, err = io.Copy(aw, r)
+		}
+		if err == nil {
+			err = aw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// Dearmor returns the binary data of the first ASCII armored block of
+// data. Text before the block is skipped. The error matches
+// ErrNotArmored, if there is no armored block, and ErrArmorCRC, if the
+// checksum does not match.
+func Dearmor(data []byte) ([]byte, error) {
+	r, err := DearmorReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("Dearmor - %w", err)
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("Dearmor - %w", err)
+	}
+	return plain, nil
+}
+
+// DearmorReader returns a reader of the binary data of the first ASCII
+// armored block read from r like Dearmor. The armor header is read
+// immediately, errors of the data are returned by the reader.
+// Clearsigned messages are not supported.
+func DearmorReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	blockType, _, err := readArmorHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	if blockType == ArmorSignedMessage {
+		return nil, fmt.Errorf("clearsigned messages are not supported")
+	}
+	return &dearmorReader{br: br, blockType: blockType, crc: crc24Init}, nil
+}
+
+// ArmorHeader is a header line of the armor, e.g. "Comment: ...".
+type ArmorHeader struct {
+	Key, Value string
+}
+
+// ArmorInfo describes an ASCII armored block, see IdentifyBytes.
+type ArmorInfo struct {
+	// BlockType is the label of the BEGIN line, e.g. ArmorMessage.
+	BlockType string
+	// Headers are the armor headers, e.g. Version and Comment, or Hash
+	// for a clearsigned message.
+	Headers []ArmorHeader
+	// HasCRC tells whether the armor has the optional checksum, for a
+	// clearsigned message the one of its signature.
+	HasCRC bool
+	// CRCValid tells whether the checksum matches the data. It is false
+	// without checksum or if the armor is damaged.
+	CRCValid bool
+}
+
+// inspectArmor describes the first ASCII armored block read from r and
+// reads it up to its end to check the checksum. It returns ErrNotArmored
+// if r has no armored block.
+func inspectArmor(r io.Reader) (info ArmorInfo, err error) {
+	br := bufio.NewReader(r)
+	info.BlockType, info.Headers, err = readArmorHeader(br)
+	if err != nil {
+		return info, err
+	}
+	blockType := info.BlockType
+	if blockType == ArmorSignedMessage {
+		// the checksum is the one of the following signature
+		blockType, _, err = readArmorHeader(br)
+		if err != nil {
+			return info, nil
+		}
+	}
+	d := &dearmorReader{br: br, blockType: blockType, crc: crc24Init}
+	_, err = io.Copy(io.Discard, d)
+	info.HasCRC = d.hasCRC
+	info.CRCValid = d.hasCRC && err == nil
+	return info, nil
+}
+
+// readArmorHeader reads up to the first line of the base64 data and
+// returns the block type and the headers.
+func readArmorHeader(br *bufio.Reader) (blockType string, headers []ArmorHeader,
+	err error) {
+
+	for {
+		line, err := br.ReadString('\n')
+		trimmed := strings.TrimSpace(line)
+		if t, ok := strings.CutPrefix(trimmed, armorBegin); ok {
+			blockType, ok = strings.CutSuffix(t, armorDashes)
+			if ok {
+				break
+			}
+		}
+		if err == io.EOF {
+			return "", nil, ErrNotArmored
+		}
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	for {
+		line, err := br.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" && err == nil {
+			return blockType, headers, nil
+		}
+		key, value, found := strings.Cut(line, ": ")
+		if !found {
+			if err == nil {
+				err = fmt.Errorf("invalid armor header %q", line)
+			}
+			return "", nil, fmt.Errorf("reading armor of %s failed: %w", blockType,
+				unexpectedEOF(err))
+		}
+		headers = append(headers, ArmorHeader{Key: key, Value: value})
+		if err != nil {
+			return "", nil, fmt.Errorf("reading armor of %s failed: %w", blockType,
+				unexpectedEOF(err))
+		}
+	}
+}
+
+// unexpectedEOF returns io.ErrUnexpectedEOF for io.EOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// dearmorReader implements DearmorReader.
+type dearmorReader struct {
+	br        *bufio.Reader
+	blockType string
+	pending   string // base64 text not yet decoded
+	decoded   []byte // decoded data not yet read
+	crc       uint32
+	hasCRC    bool  // the checksum line was read
+	err       error // returned after decoded was read, io.EOF at the end
+}
+
+// Read implements io.Reader.
+func (d *dearmorReader) Read(p []byte) (int, error) {
+	for len(d.decoded) == 0 && d.err == nil {
+		d.err = d.readLine()
+	}
+	if len(d.decoded) > 0 {
+		n := copy(p, d.decoded)
+		d.decoded = d.decoded[n:]
+		return n, nil
+	}
+	return 0, d.err
+}
+
+// readLine decodes the next line of the armor.
+func (d *dearmorReader) readLine() error {
+	line, err := d.br.ReadString('\n')
+	line = strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(line, armorEnd):
+		if line != armorEnd+d.blockType+armorDashes {
+			return fmt.Errorf("armor of %s ends with %q", d.blockType, line)
+		}
+		if err := d.decode(true); err != nil {
+			return err
+		}
+		return io.EOF
+	case len(line) == 5 && line[0] == '=':
+		// the checksum
+		if err := d.decode(true); err != nil {
+			return err
+		}
+		d.hasCRC = true
+		crc, err := base64.StdEncoding.DecodeString(line[1:])
+		if err != nil || len(crc) != 3 {
+			return fmt.Errorf("invalid armor checksum %q", line)
+		}
+		if uint32(crc[0])<<16|uint32(crc[1])<<8|uint32(crc[2]) != d.crc {
+			return ErrArmorCRC
+		}
+		return d.expectEnd()
+	case err != nil:
+		return fmt.Errorf("armor of %s: %w", d.blockType, unexpectedEOF(err))
+	}
+	d.pending += line
+	return d.decode(false)
+}
+
+// expectEnd reads the END line after the checksum.
+func (d *dearmorReader) expectEnd() error {
+	line, err := d.br.ReadString('\n')
+	if strings.TrimSpace(line) == armorEnd+d.blockType+armorDashes {
+		return io.EOF
+	}
+	if err != nil {
+		return fmt.Errorf("armor of %s: %w", d.blockType, unexpectedEOF(err))
+	}
+	return fmt.Errorf("armor of %s: END line missing", d.blockType)
+}
+
+// decode decodes the complete groups of the pending base64 text, or all
+// of it if final is true.
+func (d *dearmorReader) decode(final bool) error {
+	n := len(d.pending)
+	if !final {
+		n -= n % 4
+	}
+	data, err := base64.StdEncoding.DecodeString(d.pending[:n])
+	if err != nil {
+		return fmt.Errorf("armor of %s: %w", d.blockType, err)
+	}
+	d.pending = d.pending[n:]
+	d.crc = crc24(d.crc, data)
+	d.decoded = append(d.decoded, data...)
+	return nil
+}
+
+// EOF
@@ -0,0 +1,166 @@
+/* armor_test.go - tests of the ASCII armor
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCRC24(t *testing.T) {
+	tests := []struct {
+		data string
+		want uint32
+	}{
+		{"", crc24Init},
+		// the check value of the CRC-24 of RFC 4880
+		{"123456789", 0x21CF02},
+	}
+	for _, tt := range tests {
+		if got := crc24(crc24Init, []byte(tt.data)); got != tt.want {
+			t.Errorf("crc24(%q) = %06X, want %06X", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestArmorRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, 2, 3, 47, 48, 49, 1000} {
+		data := bytes.Repeat([]byte{0x99, 0x01, 0x0d}, size)[:size]
+		armored, err := Armor(data, ArmorPublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(string(armored), "\n") {
+			if len(line) > armorLineLength {
+				t.Errorf("size %d: line longer than %d: %q", size, armorLineLength, line)
+			}
+		}
+		got, err := Dearmor(armored)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("size %d: round trip changed the data", size)
+		}
+	}
+}
+
+func TestNewArmorWriterBlockType(t *testing.T) {
+	for _, blockType := range []string{"", "PGP\nMESSAGE", "PGP-MESSAGE"} {
+		if _, err := NewArmorWriter(io.Discard, blockType); err == nil {
+			t.Errorf("block type %q accepted", blockType)
+		}
+	}
+}
+
+func TestDearmor(t *testing.T) {
+	const begin = "-----BEGIN PGP MESSAGE-----\n"
+	const end = "-----END PGP MESSAGE-----\n"
+	tests := []struct {
+		name    string
+		armor   string
+		want    string
+		wantErr error // matched with errors.Is, if not nil
+		fails   bool
+	}{
+		{name: "checksum", armor: begin + "\naGVsbG8=\n=R/WK\n" + end, want: "hello"},
+		{name: "no checksum", armor: begin + "\naGVsbG8=\n" + end, want: "hello"},
+		{name: "text before and headers", want: "hello",
+			armor: "Some text\n" + begin + "Version: 1\nComment: a: b\n\naGVs\nbG8=\n" + end},
+		{name: "CRLF", armor: strings.ReplaceAll(begin+"\naGVsbG8=\n=R/WK\n"+end, "\n", "\r\n"),
+			want: "hello"},
+		{name: "bad checksum", armor: begin + "\naGVsbG8=\n=AAAA\n" + end,
+			wantErr: ErrArmorCRC},
+		{name: "not armored", armor: "hello\n", wantErr: ErrNotArmored},
+		{name: "other END", armor: begin + "\naGVsbG8=\n-----END PGP SIGNATURE-----\n",
+			fails: true},
+		{name: "no END", armor: begin + "\naGVsbG8=\n", wantErr: io.ErrUnexpectedEOF},
+		{name: "invalid header", armor: begin + "Version\n\naGVsbG8=\n" + end, fails: true},
+		{name: "invalid base64", armor: begin + "\naGV*bG8=\n" + end, fails: true},
+		{name: "clearsigned", armor: "-----BEGIN PGP SIGNED MESSAGE-----\n\nhello\n",
+			fails: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Dearmor([]byte(tt.armor))
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Dearmor error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.fails:
+				if err == nil {
+					t.Fatalf("Dearmor = %q, want an error", got)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case string(got) != tt.want:
+				t.Errorf("Dearmor = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInspectArmor(t *testing.T) {
+	tests := []struct {
+		name  string
+		armor string
+		want  ArmorInfo
+	}{
+		{"valid", "-----BEGIN PGP MESSAGE-----\nComment: x\n\naGVsbG8=\n=R/WK\n" +
+			"-----END PGP MESSAGE-----\n",
+			ArmorInfo{BlockType: ArmorMessage, Headers: []ArmorHeader{{"Comment", "x"}},
+				HasCRC: true, CRCValid: true}},
+		{"bad checksum", "-----BEGIN PGP MESSAGE-----\n\naGVsbG8=\n=AAAA\n" +
+			"-----END PGP MESSAGE-----\n",
+			ArmorInfo{BlockType: ArmorMessage, HasCRC: true}},
+		{"no checksum", "-----BEGIN PGP SIGNATURE-----\n\naGVsbG8=\n" +
+			"-----END PGP SIGNATURE-----\n",
+			ArmorInfo{BlockType: ArmorSignature}},
+		{"clearsigned", "-----BEGIN PGP SIGNED MESSAGE-----\nHash: SHA256\n\nhello\n" +
+			"-----BEGIN PGP SIGNATURE-----\n\naGVsbG8=\n=R/WK\n-----END PGP SIGNATURE-----\n",
+			ArmorInfo{BlockType: ArmorSignedMessage, Headers: []ArmorHeader{{"Hash", "SHA256"}},
+				HasCRC: true, CRCValid: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := inspectArmor(strings.NewReader(tt.armor))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.BlockType != tt.want.BlockType || got.HasCRC != tt.want.HasCRC ||
+				got.CRCValid != tt.want.CRCValid ||
+				len(got.Headers) != len(tt.want.Headers) {
+				t.Fatalf("inspectArmor = %+v, want %+v", got, tt.want)
+			}
+			for i, h := range got.Headers {
+				if h != tt.want.Headers[i] {
+					t.Errorf("header %d = %+v, want %+v", i, h, tt.want.Headers[i])
+				}
+			}
+		})
+	}
+}
+
+// EOF
@@ -0,0 +1,90 @@
+/* atomic.go - crash safe output files for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// Output files are written to a temporary file in the destination
+// directory first, which is flushed to disk and renamed to the
+// destination when complete. So a crash or a failed operation never
+// leaves a truncated file at the destination.
+
+package gpggohigh
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// tempName returns the name of a not yet existing temporary file next
+// to destination, for output written by gpg. See commitFile.
+func tempName(destination string) (string, error) {
+	// the random string collision probability is 1/62^8 = 4.58e-15
+	random, err := RandomString(8)
+	if err != nil {
+		return "", err
+	}
+	return destination + "." + random + ".tmp", nil
+}
+
+// createTemp creates a temporary file next to destination for output
+// written by gpggohigh. See commitTemp.
+func createTemp(destination string) (*os.File, error) {
+	return os.CreateTemp(filepath.Dir(destination), ".gpggohigh-*.tmp")
+}
+
+// commitFile flushes the complete file temp to disk and renames it to
+// destination. If this fails, temp is removed.
+func commitFile(temp, destination string) error {
+	fh, err := os.OpenFile(temp, os.O_RDWR, 0)
+	if err != nil {
+		_ = os.Remove(temp)
+		return err
+	}
+	return commitTemp(fh, destination)
+}
+
+// commitTemp flushes the complete file fh to disk, closes it and renames
+// it to destination. If this fails, the file is removed.
+func commitTemp(fh *os.File, destination string) error {
+	err := fh.Sync()
+	if closeErr := fh.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(fh.Name(), destination)
+	}
+	if err != nil {
+		_ = os.Remove(fh.Name())
+		return err
+	}
+	syncDir(filepath.Dir(destination))
+	return nil
+}
+
+// syncDir flushes the directory entries of dir to disk, so a rename
+// survives a crash. Errors are ignored, not all systems support this.
+func syncDir(dir string) {
+	fh, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = fh.Sync()
+	_ = fh.Close()
+}
+
+// EOF
@@ -0,0 +1,231 @@
+/* audit.go - journal of the cryptographic operations for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// The audit hook records the encryptions, decryptions, signatures,
+// verifications and key imports of the package functions and of all
+// sessions, e.g. to prove what was encrypted to whom. The hashes of the
+// data are only computed, if a hook is set.
+
+package gpggohigh
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// AuditOperation is the kind of an audited operation.
+type AuditOperation string
+
+// Audited operations.
+const (
+	AuditEncrypt AuditOperation = "encrypt"
+	AuditDecrypt AuditOperation = "decrypt"
+	AuditSign    AuditOperation = "sign"
+	AuditVerify  AuditOperation = "verify"
+	AuditImport  AuditOperation = "import"
+)
+
+// Status values of an AuditEvent.
+const (
+	AuditStatusOK     = "ok"
+	AuditStatusFailed = "failed"
+)
+
+// AuditEvent is the record of one operation.
+type AuditEvent struct {
+	Time      time.Time      `json:"time"`
+	Operation AuditOperation `json:"operation"`
+	Function  string         `json:"function"` // e.g. "EncryptFile"
+	Status    string         `json:"status"`   // AuditStatusOK or AuditStatusFailed
+	Error     string         `json:"error,omitempty"`
+	// Recipients are the fingerprints of the recipient keys of an
+	// encryption, or the key IDs the decrypted data was encrypted to.
+	Recipients []string `json:"recipients,omitempty"`
+	// AdditionalKeys are the fingerprints of the additional decryption
+	// keys added to the recipients of an encryption, see
+	// SetAdditionalDecryptionKeys.
+	AdditionalKeys []string `json:"additional_keys,omitempty"`
+	// Signers are the fingerprints of the signing keys, or those of the
+	// verified signatures.
+	Signers []string `json:"signers,omitempty"`
+	// Keys are the fingerprints of the imported keys.
+	Keys []string `json:"keys,omitempty"`
+	// Input and Output are the file names of file operations.
+	Input  string `json:"input,omitempty"`
+	Output string `json:"output,omitempty"`
+	// InputSHA256 and OutputSHA256 are the hex encoded hashes of the data
+	// of file and memory buffer operations, streams are not hashed.
+	InputSHA256  string `json:"input_sha256,omitempty"`
+	OutputSHA256 string `json:"output_sha256,omitempty"`
+}
+
+// AuditFunc receives the audit events. It is called after the operation
+// and may be called concurrently.
+type AuditFunc func(event AuditEvent)
+
+var auditHook struct {
+	sync.RWMutex
+	fn AuditFunc
+}
+
+// SetAuditHook sets the function receiving the audit events of all
+// following operations, nil disables the auditing.
+func SetAuditHook(fn AuditFunc) {
+	auditHook.Lock()
+	defer auditHook.Unlock()
+	auditHook.fn = fn
+}
+
+// AuditJSONLines returns an AuditFunc writing each event as a line of
+// JSON to w, e.g. an append-only log file. Write errors are ignored, the
+// operations are not failed by the auditing.
+func AuditJSONLines(w io.Writer) AuditFunc {
+	var mu sync.Mutex
+	return func(event AuditEvent) {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = w.Write(append(line, '\n'))
+	}
+}
+
+// audit passes the event of the operation op of the function fn, which
+// ended with err, to the audit hook and logs it. fill sets the details of
+// the event, it is only called, if a hook is set.
+func audit(op AuditOperation, fn string, err error, fill func(event *AuditEvent)) {
+	logDebug("operation done", "operation", op, "function", fn, "error", err)
+
+	auditHook.RLock()
+	hook := auditHook.fn
+	auditHook.RUnlock()
+	if hook == nil {
+		return
+	}
+
+	event := AuditEvent{
+		Time:      time.Now(),
+		Operation: op,
+		Function:  fn,
+		Status:    AuditStatusOK,
+	}
+	if err != nil {
+		event.Status = AuditStatusFailed
+		event.Error = err.Error()
+	}
+	if op == AuditEncrypt {
+		event.AdditionalKeys = AdditionalDecryptionKeys()
+	}
+	if fill != nil {
+		fill(&event)
+	}
+	hook(event)
+}
+
+// hashBytes returns the hex encoded SHA-256 hash of data.
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hashFile returns the hex encoded SHA-256 hash of the file name, empty
+// if it can not be read. Further names are hashed as continuation of the
+// data, e.g. the parts of a split file.
+func hashFile(name string, more ...string) string {
+	h := sha256.New()
+	for _, n := range append([]string{name}, more...) {
+		fh, err := os.Open(n)
+		if err != nil {
+			return ""
+		}
+		_, err = io.Copy(h, fh)
+		fh.Close()
+		if err != nil {
+			return ""
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// keyFingerprints returns the fingerprints of keys.
+func keyFingerprints(keys []*gpgme.Key) (fingerprints []string) {
+	for _, k := range keys {
+		fingerprints = append(fingerprints, k.Fingerprint())
+	}
+	return fingerprints
+}
+
+// signatureFingerprints returns the fingerprints of the signing keys of
+// signatures.
+func signatureFingerprints(signatures []gpgme.Signature) (fingerprints []string) {
+	for _, sig := range signatures {
+		fingerprints = append(fingerprints, sig.Fingerprint)
+	}
+	return fingerprints
+}
+
+// recipientFingerprints returns the fingerprints of the keys of
+// recipients in the home directory homeDir, for encryptions done by gpg
+// directly. If they can not be looked up, recipients are returned as
+// given.
+func recipientFingerprints(homeDir string, recipients []string) []string {
+	myContext, err := newContext(SessionOptions{HomeDir: homeDir})
+	if err != nil {
+		return recipients
+	}
+	defer myContext.Release()
+	keys, err := findRecipients(myContext, recipients)
+	if err != nil {
+		return recipients
+	}
+	return keyFingerprints(keys)
+}
+
+// createdSignatureFingerprints returns the fingerprints of the signing
+// keys of the SIG_CREATED status lines of gpg.
+func createdSignatureFingerprints(status []gpgStatus) (fingerprints []string) {
+	for _, s := range status {
+		// <type> <pk algo> <hash algo> <class> <timestamp> <key fpr>
+		if s.Keyword == "SIG_CREATED" && len(s.Args) > 5 {
+			fingerprints = append(fingerprints, s.Args[5])
+		}
+	}
+	return fingerprints
+}
+
+// decryptRecipients returns the key IDs the decrypted data of result was
+// encrypted to.
+func decryptRecipients(result gpgme.DecryptResultType) (keyIDs []string) {
+	for _, r := range result.Recipients {
+		keyIDs = append(keyIDs, r.KeyID)
+	}
+	return keyIDs
+}
+
+// EOF
@@ -0,0 +1,127 @@
+/* backend.go - the gpgme implementation of backend.Backend
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/gnupg-com/gpggohigh/backend"
+	"github.com/kulbartsch/gpgme"
+)
+
+// sessionBackend implements backend.Backend with a Session.
+type sessionBackend struct {
+	s *Session
+}
+
+// Backend returns the session as backend.Backend, so application logic
+// written against the interface can use gpgme in production and the
+// in-memory backend.Fake in unit tests. The backend uses the options of
+// the session and is valid until the session is closed.
+func (s *Session) Backend() backend.Backend {
+	return sessionBackend{s: s}
+}
+
+// Encrypt implements backend.Backend.
+func (b sessionBackend) Encrypt(plainText []byte, recipients []string, sign bool) (
+	[]byte, error) {
+	return b.s.EncryptBytes(plainText, recipients, sign)
+}
+
+// Decrypt implements backend.Backend.
+func (b sessionBackend) Decrypt(cipherText []byte) (result backend.DecryptResult,
+	err error) {
+
+	plainText, decryptionResult, filename, signatures, _, err := b.s.DecryptBytes(cipherText)
+	if err != nil {
+		return result, err
+	}
+	result = backend.DecryptResult{
+		PlainText:  plainText,
+		Recipients: decryptRecipients(decryptionResult),
+		Signatures: backendSignatures(signatures),
+		Filename:   filename,
+	}
+	return result, nil
+}
+
+// Sign implements backend.Backend.
+func (b sessionBackend) Sign(plainText []byte, signWith string) ([]byte, error) {
+	signed, _, err := b.s.SignBytes(plainText, signWith, gpgme.SigModeNormal)
+	return signed, err
+}
+
+// Verify implements backend.Backend.
+func (b sessionBackend) Verify(signed []byte) (plainText []byte,
+	signatures []backend.Signature, err error) {
+
+	plainText, sigs, _, err := b.s.VerifyBytes(signed)
+	// verifyBytes reports reading until the end of the data as io.EOF
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+	if len(sigs) == 0 {
+		return nil, nil, fmt.Errorf("VerifyBytes - %w", backend.ErrNoData)
+	}
+	return plainText, backendSignatures(sigs), nil
+}
+
+// KeyList implements backend.Backend.
+func (b sessionBackend) KeyList(pattern string, secretOnly bool) (
+	keys []backend.Key, err error) {
+
+	found, err := b.s.KeyListFiltered(pattern, KeyFilter{SecretOnly: secretOnly})
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range found {
+		key := backend.Key{
+			Fingerprint: k.Fingerprint,
+			CanEncrypt:  k.CanEncrypt,
+			CanSign:     k.CanSign,
+			Secret:      k.Secret,
+			Revoked:     k.Revoked,
+			Expired:     k.Expired,
+			Expires:     k.Expires,
+		}
+		for _, uid := range k.UserIDs {
+			key.UserIDs = append(key.UserIDs, uid.UserID)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// backendSignatures converts verified signatures for backend.Backend.
+func backendSignatures(signatures []gpgme.Signature) (sigs []backend.Signature) {
+	for _, sig := range signatures {
+		sigs = append(sigs, backend.Signature{
+			Fingerprint: sig.Fingerprint,
+			Good:        ClassifySignature(sig) == SignatureValid,
+			Status:      CondErrStr(sig.Status, ""),
+			Timestamp:   sig.Timestamp,
+		})
+	}
+	return sigs
+}
+
+// EOF
@@ -0,0 +1,92 @@
+/* backend.go - the operations of gpggohigh as interface
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// Package backend defines the cryptographic operations used by
+// applications of gpggohigh as the interface Backend, so application
+// logic can be unit tested with the in-memory Fake.
+//
+// This package does not import gpgme, so it builds without cgo and
+// libgpgme. The implementation using gpgme is returned by the Backend
+// method of a gpggohigh.Session.
+package backend
+
+import (
+	"errors"
+	"time"
+)
+
+// Errors of the operations, the implementations wrap them, so they can
+// be tested with errors.Is.
+var (
+	ErrNoKey       = errors.New("no usable key found")
+	ErrNoSecretKey = errors.New("no secret key")
+	ErrNoData      = errors.New("no valid OpenPGP data found")
+)
+
+// Key is a key of the keyring.
+type Key struct {
+	Fingerprint string
+	UserIDs     []string
+	CanEncrypt  bool
+	CanSign     bool
+	Secret      bool // the secret key is available
+	Revoked     bool
+	Expired     bool
+	Expires     time.Time // zero if the key does not expire
+}
+
+// Signature is a verified signature.
+type Signature struct {
+	Fingerprint string    // of the signing (sub)key
+	Good        bool      // the signature is valid and the key is known
+	Status      string    // why the signature is not good, empty if it is
+	Timestamp   time.Time // creation time of the signature
+}
+
+// DecryptResult is the result of a decryption.
+type DecryptResult struct {
+	PlainText  []byte
+	Recipients []string    // key IDs the data was encrypted to
+	Signatures []Signature // of signed and encrypted data
+	Filename   string      // the file name embedded in the data, if any
+}
+
+// Backend are the operations of gpggohigh used by applications.
+// Recipients and signers are selected like for gpg, e.g. by fingerprint
+// or mail address.
+type Backend interface {
+	// Encrypt encrypts plainText to the recipients, signed with the
+	// default key if sign is true.
+	Encrypt(plainText []byte, recipients []string, sign bool) (cipherText []byte, err error)
+	// Decrypt decrypts cipherText and verifies its signatures.
+	Decrypt(cipherText []byte) (result DecryptResult, err error)
+	// Sign signs plainText with the key signWith. The signed data
+	// contains plainText.
+	Sign(plainText []byte, signWith string) (signed []byte, err error)
+	// Verify verifies the signatures of signed and returns the data
+	// without the signatures.
+	Verify(signed []byte) (plainText []byte, signatures []Signature, err error)
+	// KeyList returns the keys matching pattern, all keys if it is
+	// empty. If secretOnly is true, only keys with a secret key are
+	// returned.
+	KeyList(pattern string, secretOnly bool) (keys []Key, err error)
+}
+
+// EOF
@@ -0,0 +1,242 @@
+/* fake.go - an in-memory Backend for unit tests
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fakeMagic starts the data produced by Fake.
+const fakeMagic = "GPGGOHIGH-FAKE\n"
+
+// fakeMessage is the content of the data produced by Fake. The data is
+// not encrypted at all.
+type fakeMessage struct {
+	Recipients []string  `json:"recipients,omitempty"` // fingerprints, encrypted data only
+	Signer     string    `json:"signer,omitempty"`     // fingerprint, signed data only
+	SignedAt   time.Time `json:"signed_at,omitempty"`
+	Data       []byte    `json:"data"`
+}
+
+// Fake is an in-memory Backend for unit tests of code using a Backend.
+// It behaves like the gpgme implementation for the keys added with
+// AddKey, but does not encrypt or sign: its data only works with a Fake.
+// Keys are selected by a fingerprint, the end of it (a key ID) or a part
+// of a user ID, ignoring the case. A Fake may be used by several
+// goroutines.
+type Fake struct {
+	mu   sync.Mutex
+	keys []Key
+	// DefaultKey is the key used by Encrypt for signing, the first key
+	// with a secret key if empty.
+	DefaultKey string
+}
+
+// NewFake returns a Fake with the keys.
+func NewFake(keys ...Key) *Fake {
+	f := &Fake{}
+	for _, k := range keys {
+		f.AddKey(k)
+	}
+	return f
+}
+
+// AddKey adds the key k to the keyring of f. A key with the same
+// fingerprint is replaced.
+func (f *Fake) AddKey(k Key) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.keys {
+		if strings.EqualFold(f.keys[i].Fingerprint, k.Fingerprint) {
+			f.keys[i] = k
+			return
+		}
+	}
+	f.keys = append(f.keys, k)
+}
+
+// find returns the keys matching pattern. The caller holds the lock.
+func (f *Fake) find(pattern string, secretOnly bool) (keys []Key) {
+	pattern = strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(pattern), "0x"))
+	for _, k := range f.keys {
+		if secretOnly && !k.Secret {
+			continue
+		}
+		match := pattern == "" || strings.HasSuffix(strings.ToUpper(k.Fingerprint), pattern)
+		for _, uid := range k.UserIDs {
+			match = match || strings.Contains(strings.ToUpper(uid), pattern)
+		}
+		if match {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// usable returns the first key matching pattern, which is usable for
+// encryption or, if sign is true, signing. The caller holds the lock.
+func (f *Fake) usable(pattern string, sign bool) (Key, error) {
+	for _, k := range f.find(pattern, sign) {
+		if k.Revoked || k.Expired || (!k.Expires.IsZero() && k.Expires.Before(time.Now())) {
+			continue
+		}
+		if sign && k.CanSign || !sign && k.CanEncrypt {
+			return k, nil
+		}
+	}
+	if sign {
+		return Key{}, fmt.Errorf("signer %q: %w", pattern, ErrNoSecretKey)
+	}
+	return Key{}, fmt.Errorf("recipient %q: %w", pattern, ErrNoKey)
+}
+
+// Encrypt implements Backend.
+func (f *Fake) Encrypt(plainText []byte, recipients []string, sign bool) (
+	cipherText []byte, err error) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no recipients given")
+	}
+	m := fakeMessage{Data: plainText}
+	for _, r := range recipients {
+		k, err := f.usable(r, false)
+		if err != nil {
+			return nil, err
+		}
+		m.Recipients = append(m.Recipients, k.Fingerprint)
+	}
+	if sign {
+		k, err := f.usable(f.DefaultKey, true)
+		if err != nil {
+			return nil, err
+		}
+		m.Signer, m.SignedAt = k.Fingerprint, time.Now()
+	}
+	return encodeFake(m)
+}
+
+// Decrypt implements Backend.
+func (f *Fake) Decrypt(cipherText []byte) (result DecryptResult, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m, err := decodeFake(cipherText)
+	if err != nil {
+		return result, err
+	}
+	if len(m.Recipients) == 0 {
+		return result, fmt.Errorf("not encrypted: %w", ErrNoData)
+	}
+
+	found := false
+	for _, r := range m.Recipients {
+		found = found || len(f.find(r, true)) > 0
+		// key IDs like reported by gpgme
+		result.Recipients = append(result.Recipients, r[max(len(r)-16, 0):])
+	}
+	if !found {
+		return DecryptResult{}, ErrNoSecretKey
+	}
+	result.PlainText = m.Data
+	if m.Signer != "" {
+		result.Signatures = []Signature{f.signature(m)}
+	}
+	return result, nil
+}
+
+// Sign implements Backend.
+func (f *Fake) Sign(plainText []byte, signWith string) (signed []byte, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	k, err := f.usable(signWith, true)
+	if err != nil {
+		return nil, err
+	}
+	return encodeFake(fakeMessage{Signer: k.Fingerprint, SignedAt: time.Now(),
+		Data: plainText})
+}
+
+// Verify implements Backend.
+func (f *Fake) Verify(signed []byte) (plainText []byte, signatures []Signature,
+	err error) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m, err := decodeFake(signed)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(m.Recipients) > 0 || m.Signer == "" {
+		return nil, nil, fmt.Errorf("not signed: %w", ErrNoData)
+	}
+	return m.Data, []Signature{f.signature(m)}, nil
+}
+
+// signature returns the verification result of the signed message m.
+// The caller holds the lock.
+func (f *Fake) signature(m fakeMessage) Signature {
+	sig := Signature{Fingerprint: m.Signer, Timestamp: m.SignedAt}
+	switch keys := f.find(m.Signer, false); {
+	case len(keys) == 0:
+		sig.Status = "no public key"
+	case keys[0].Revoked:
+		sig.Status = "key revoked"
+	default:
+		sig.Good = true
+	}
+	return sig
+}
+
+// KeyList implements Backend.
+func (f *Fake) KeyList(pattern string, secretOnly bool) (keys []Key, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.find(pattern, secretOnly), nil
+}
+
+// encodeFake returns the data of the message m.
+func encodeFake(m fakeMessage) ([]byte, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(fakeMagic), data...), nil
+}
+
+// decodeFake returns the message of data produced by encodeFake.
+func decodeFake(data []byte) (m fakeMessage, err error) {
+	rest, ok := bytes.CutPrefix(data, []byte(fakeMagic))
+	if !ok {
+		return m, ErrNoData
+	}
+	if err := json.Unmarshal(rest, &m); err != nil {
+		return m, fmt.Errorf("%w: %w", ErrNoData, err)
+	}
+	return m, nil
+}
+
+// EOF
@@ -0,0 +1,168 @@
+/* batch.go - parallel encryption of many files for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// BatchOptions configures EncryptFiles.
+type BatchOptions struct {
+	Workers int            // parallel gpgme contexts, 0 means one per CPU
+	Sign    bool           // sign the files, see EncryptFile
+	Session SessionOptions // configuration of the gpgme contexts
+
+	// Pool, if set, provides the gpgme contexts instead of a pool created
+	// for the batch with the options Session. Its armor setting decides
+	// the extension then.
+	Pool *ContextPool
+}
+
+// BatchResult is the result of one file of a batch operation.
+type BatchResult struct {
+	Filename    string // the source file
+	Destination string // the encrypted file
+	Err         error  // nil if the file was processed successfully
+}
+
+// EncryptFiles encrypts each of the files to the recipients, saving the
+// encrypted file with an added `.gpg` extension like EncryptFile, or
+// `.asc` if opts.Session.Armor is set.
+// The files are processed by a pool of workers, each using a session of
+// a ContextPool for all its files.
+// The results are in the order of files. If a file failed, err reports
+// the number of failed files, the details are in the results.
+func EncryptFiles(files, recipients []string, opts BatchOptions) (
+	results []BatchResult, err error) {
+	return EncryptFilesCtx(context.Background(), files, recipients, opts)
+}
+
+// EncryptFilesCtx encrypts files like EncryptFiles. When ctx is done,
+// the running operations stop at their next data transfer, see
+// EncryptFileCtx, and the remaining files are reported with the error
+// of ctx.
+func EncryptFilesCtx(ctx context.Context, files, recipients []string,
+	opts BatchOptions) (results []BatchResult, err error) {
+
+	sessionOpts := opts.Session
+	if opts.Pool != nil {
+		sessionOpts = opts.Pool.opts
+	}
+	extension := ".gpg"
+	if sessionOpts.Armor {
+		extension = ".asc"
+	}
+	results = make([]BatchResult, len(files))
+	for i, f := range files {
+		results[i] = BatchResult{Filename: f, Destination: f + extension}
+	}
+
+	runBatch(ctx, opts.Pool, sessionOpts, opts.Workers, len(files),
+		func(myContext *gpgme.Context, i int) {
+			r := &results[i]
+			r.Err = encryptFileCtx(ctx, myContext, r.Filename, r.Destination,
+				recipients, opts.Sign, nil)
+		},
+		func(i int, err error) {
+			results[i].Err = err
+		})
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("EncryptFiles - %d of %d files failed", failed, len(files))
+	}
+	return results, nil
+}
+
+// runBatch calls job for the indexes 0 to n-1 in up to workers
+// goroutines, one per CPU if workers is less than 1. Each goroutine holds
+// a session of pool and passes its gpgme context to all its jobs. If pool
+// is nil, a pool with the options opts is created for the batch.
+// fail is called instead of job with the error of ctx for the indexes
+// left when ctx is done, and with the error of the pool, if no session
+// can be acquired.
+func runBatch(ctx context.Context, pool *ContextPool, opts SessionOptions,
+	workers, n int, job func(myContext *gpgme.Context, i int),
+	fail func(i int, err error)) {
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = min(workers, n)
+	if workers == 0 {
+		return
+	}
+	if pool == nil {
+		var err error
+		pool, err = NewContextPool(opts, workers)
+		if err != nil {
+			for i := 0; i < n; i++ {
+				fail(i, err)
+			}
+			return
+		}
+		defer pool.Close()
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := pool.Do(func(s *Session) error {
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				for i := range jobs {
+					if err := ctx.Err(); err != nil {
+						fail(i, err)
+						continue
+					}
+					job(s.ctx, i)
+				}
+				return nil
+			})
+			if err != nil {
+				for i := range jobs {
+					fail(i, err)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// EOF
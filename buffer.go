@@ -0,0 +1,111 @@
+/* buffer.go - reading gpgme data objects for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// DefaultReadBufferSize is the size of the chunks, in which the results
+// of memory buffer operations are read from gpgme.
+const DefaultReadBufferSize = 64 * 1024
+
+var (
+	readBufferSize atomic.Int64
+	readBuffers    sync.Pool
+)
+
+// SetReadBufferSize sets the size of the chunks, in which the results of
+// memory buffer operations like SignBytes and VerifyBytes are read from
+// gpgme. Larger chunks need fewer calls into gpgme for large data. A size
+// less than 1 restores DefaultReadBufferSize.
+func SetReadBufferSize(size int) {
+	if size < 1 {
+		size = 0
+	}
+	readBufferSize.Store(int64(size))
+}
+
+// ReadBufferSize returns the size set with SetReadBufferSize.
+func ReadBufferSize() int {
+	if size := readBufferSize.Load(); size > 0 {
+		return int(size)
+	}
+	return DefaultReadBufferSize
+}
+
+// getReadBuffer returns a chunk buffer of the current size, reusing one
+// returned with putReadBuffer if possible.
+func getReadBuffer() *[]byte {
+	size := ReadBufferSize()
+	if buf, ok := readBuffers.Get().(*[]byte); ok && len(*buf) == size {
+		return buf
+	}
+	buf := make([]byte, size)
+	return &buf
+}
+
+// putReadBuffer returns a chunk buffer for reuse.
+func putReadBuffer(buf *[]byte) {
+	readBuffers.Put(buf)
+}
+
+// readData rewinds a gpgme data object and returns its complete content.
+// If the size of the data is known, the result is allocated only once.
+func readData(data *gpgme.Data) ([]byte, error) {
+	size, err := data.Seek(0, io.SeekEnd)
+	if err != nil {
+		// not seekable, let the buffer grow
+		size = 0
+	}
+	err = data.Rewind()
+	if err != nil {
+		return nil, fmt.Errorf("Rewind failed: %w", err)
+	}
+
+	// one byte more, so the final read returning io.EOF does not grow it
+	var result bytes.Buffer
+	result.Grow(int(size) + 1)
+
+	part := getReadBuffer()
+	defer putReadBuffer(part)
+	for {
+		n, err := data.Read(*part)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("Read failed: %w", err)
+		}
+		if n > 0 {
+			result.Write((*part)[:n])
+		}
+		if err == io.EOF || n == 0 {
+			break
+		}
+	}
+	return result.Bytes(), nil
+}
+
+// EOF
@@ -0,0 +1,295 @@
+/* colons.go - key listings in the colon format of gpg --with-colons
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// The colon format is described in doc/DETAILS of GnuPG. The records
+// are written with the number of fields gpg 2.4 writes, fields not
+// known from the KeyType are left empty.
+
+package gpggohigh
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// KeysToColons returns the keys in the format of gpg --with-colons
+// --with-keygrip --list-keys, or --list-secret-keys for secret keys, so
+// existing parsers of the gpg output can read them. The key signatures
+// are written as sig and rev records, if the keys were listed with them.
+// Not reported by gpgme.go are the creation times and hashes of the user
+// IDs and the algorithms and classes of the signatures, these fields are
+// empty, like the algorithms and keygrips of keys listed without their
+// details, see KeyFilter.WithDetails.
+func KeysToColons(keys []KeyType) []byte {
+	var b bytes.Buffer
+	for _, key := range keys {
+		writeKeyColons(&b, key)
+	}
+	return b.Bytes()
+}
+
+// writeKeyColons writes the records of key to b.
+func writeKeyColons(b *bytes.Buffer, key KeyType) {
+	validity := colonKeyValidity(key)
+	for i, sk := range key.SubKeys {
+		fields := make([]string, 20)
+		switch {
+		case i == 0 && key.Protocol == gpgme.ProtocolCMS:
+			fields[0] = "crt"
+			if key.Secret {
+				fields[0] = "crs"
+			}
+		case i == 0:
+			fields[0] = "pub"
+			if key.Secret {
+				fields[0] = "sec"
+			}
+		default:
+			fields, fields[0] = fields[:18], "sub"
+			if key.Secret {
+				fields[0] = "ssb"
+			}
+		}
+
+		switch {
+		case sk.Revoked:
+			fields[1] = "r"
+		case sk.Expired:
+			fields[1] = "e"
+		case sk.Invalid:
+			fields[1] = "i"
+		default:
+			fields[1] = validity
+		}
+		if sk.Length > 0 {
+			fields[2] = strconv.Itoa(sk.Length)
+		}
+		fields[3] = colonAlgo(sk)
+		fields[4] = sk.KeyID
+		fields[5] = colonTimeField(sk.Created)
+		fields[6] = colonTimeField(sk.Expires)
+		fields[11] = sk.Capabilities
+		if i == 0 {
+			fields[8] = colonValidity(key.OwnerTrust)
+			fields[11] += colonKeyCapabilities(key)
+			fields[19] = "0"
+		}
+		switch {
+		case sk.CardNumber != "":
+			fields[14] = sk.CardNumber
+		case sk.Secret:
+			fields[14] = "+"
+		case key.Secret:
+			// gpgme.go lists stubs of missing secret keys as public
+			fields[14] = "#"
+		}
+		fields[16] = sk.Curve
+		writeColonRecord(b, fields)
+		writeColonRecord(b, []string{"fpr", "", "", "", "", "", "", "", "", sk.Fingerprint})
+		if sk.Keygrip != "" {
+			writeColonRecord(b, []string{"grp", "", "", "", "", "", "", "", "", sk.Keygrip})
+		}
+
+		// the user IDs follow the primary key
+		if i == 0 {
+			for _, uid := range key.UserIDs {
+				writeUserIDColons(b, uid)
+			}
+		}
+	}
+}
+
+// writeUserIDColons writes the uid record of uid and its signatures to b.
+func writeUserIDColons(b *bytes.Buffer, uid KeyUserIDsType) {
+	fields := make([]string, 20)
+	fields[0] = "uid"
+	switch {
+	case uid.Revoked:
+		fields[1] = "r"
+	case uid.Invalid:
+		fields[1] = "i"
+	default:
+		fields[1] = colonValidity(uid.Validity)
+	}
+	fields[9] = escapeColon(uid.UserID)
+	fields[19] = "0"
+	writeColonRecord(b, fields)
+
+	issuers := make([]string, 0, len(uid.Signatures))
+	for issuer := range uid.Signatures {
+		issuers = append(issuers, issuer)
+	}
+	slices.Sort(issuers)
+	for _, issuer := range issuers {
+		for _, sig := range uid.Signatures[issuer] {
+			fields := make([]string, 16)
+			fields[0] = "sig"
+			if sig.Revoked {
+				fields[0] = "rev"
+			}
+			switch {
+			case sig.Invalid:
+				fields[1] = "-"
+			case sig.Expired:
+				fields[1] = "e"
+			}
+			fields[4] = sig.IssuerKeyID
+			fields[5] = colonTimeField(sig.CreationTime)
+			fields[6] = colonTimeField(sig.ExpirationTime)
+			fields[9] = escapeColon(sig.UID)
+			writeColonRecord(b, fields)
+		}
+	}
+}
+
+// writeColonRecord writes the fields as one record to b.
+func writeColonRecord(b *bytes.Buffer, fields []string) {
+	b.WriteString(strings.Join(fields, ":"))
+	b.WriteString(":\n")
+}
+
+// colonKeyValidity returns the validity of the key in the colon format,
+// which is the best validity of its user IDs.
+func colonKeyValidity(key KeyType) string {
+	switch {
+	case key.Revoked:
+		return "r"
+	case key.Expired:
+		return "e"
+	case key.Invalid:
+		return "i"
+	}
+	validity := gpgme.ValidityUnknown
+	for _, uid := range key.UserIDs {
+		if !uid.Revoked && !uid.Invalid && uid.Validity > validity {
+			validity = uid.Validity
+		}
+	}
+	return colonValidity(validity)
+}
+
+// colonValidity returns the letter of the validity or owner trust.
+func colonValidity(v gpgme.Validity) string {
+	switch v {
+	case gpgme.ValidityUndefined:
+		return "q"
+	case gpgme.ValidityNever:
+		return "n"
+	case gpgme.ValidityMarginal:
+		return "m"
+	case gpgme.ValidityFull:
+		return "f"
+	case gpgme.ValidityUltimate:
+		return "u"
+	}
+	return "-"
+}
+
+// colonKeyCapabilities returns the upper case capabilities of the whole
+// key, followed by "D" for a disabled key.
+func colonKeyCapabilities(key KeyType) string {
+	var caps string
+	if key.CanEncrypt {
+		caps += "E"
+	}
+	if key.CanSign {
+		caps += "S"
+	}
+	if key.CanCertify {
+		caps += "C"
+	}
+	if key.CanAuthenticate {
+		caps += "A"
+	}
+	if key.Disabled {
+		caps += "D"
+	}
+	return caps
+}
+
+// colonAlgo returns the OpenPGP number of the algorithm of the key sk.
+// gpgme has no names for the numbers of ECDSA and EdDSA, these keys are
+// recognized by the curve.
+func colonAlgo(sk SubKeyType) string {
+	switch sk.PubkeyAlgo {
+	case "RSA":
+		return "1"
+	case "RSA-E":
+		return "2"
+	case "RSA-S":
+		return "3"
+	case "ELG-E":
+		return "16"
+	case "DSA":
+		return "17"
+	case "ECC", "ECDH":
+		return "18"
+	case "ECDSA":
+		return "19"
+	case "ELG":
+		return "20"
+	case "EdDSA":
+		return "22"
+	}
+	switch {
+	case sk.Curve == "ed25519" || sk.Curve == "ed448":
+		return "22"
+	case sk.Curve == "cv25519" || sk.Curve == "cv448":
+		return "18"
+	case sk.Curve != "" && strings.Contains(sk.Capabilities, "e"):
+		return "18"
+	case sk.Curve != "":
+		return "19"
+	}
+	return ""
+}
+
+// colonTimeField returns t as seconds since the epoch, empty for the zero
+// time.
+func colonTimeField(t time.Time) string {
+	if t.IsZero() || t.Unix() <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+// escapeColon escapes a string field of the colon listing like gpg, the
+// colon, the backslash and control characters as \xHH, see
+// unescapeColon.
+func escapeColon(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c == 0x7f || c == ':' || c == '\\' {
+			fmt.Fprintf(&b, `\x%02x`, c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// EOF
@@ -0,0 +1,159 @@
+/* colons_test.go - tests of the colon listing
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+func TestKeysToColons(t *testing.T) {
+	created := time.Unix(1735689600, 0)
+	key := KeyType{
+		Fingerprint: "B5DDE31CF970C0F41F6B9E13FA62705D7381A2A9",
+		CanEncrypt:  true,
+		CanSign:     true,
+		CanCertify:  true,
+		OwnerTrust:  gpgme.ValidityUltimate,
+		SubKeys: []SubKeyType{
+			{Fingerprint: "B5DDE31CF970C0F41F6B9E13FA62705D7381A2A9",
+				KeyID: "FA62705D7381A2A9", PubkeyAlgo: "EdDSA", Length: 255,
+				Curve: "ed25519", Capabilities: "sc", Created: created},
+			{Fingerprint: "1842BCB5A9CD2E6CA59033664FED432D3C1327F3",
+				KeyID: "4FED432D3C1327F3", PubkeyAlgo: "ECDH", Length: 255,
+				Curve: "cv25519", Capabilities: "e", Created: created,
+				Expires: time.Unix(1767225600, 0),
+				Keygrip: "0123456789ABCDEF0123456789ABCDEF01234567"},
+		},
+		UserIDs: []KeyUserIDsType{
+			{UserID: "Alice Test <alice@example.org>", Validity: gpgme.ValidityUltimate},
+		},
+	}
+	want := "pub:u:255:22:FA62705D7381A2A9:1735689600:::u:::scESC:::::ed25519:::0:\n" +
+		"fpr:::::::::B5DDE31CF970C0F41F6B9E13FA62705D7381A2A9:\n" +
+		"uid:u::::::::Alice Test <alice@example.org>::::::::::0:\n" +
+		"sub:u:255:18:4FED432D3C1327F3:1735689600:1767225600:::::e:::::cv25519::\n" +
+		"fpr:::::::::1842BCB5A9CD2E6CA59033664FED432D3C1327F3:\n" +
+		"grp:::::::::0123456789ABCDEF0123456789ABCDEF01234567:\n"
+	if got := string(KeysToColons([]KeyType{key})); got != want {
+		t.Errorf("KeysToColons =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestColonKeyValidity(t *testing.T) {
+	uids := []KeyUserIDsType{
+		{Validity: gpgme.ValidityUltimate, Revoked: true},
+		{Validity: gpgme.ValidityMarginal},
+		{Validity: gpgme.ValidityFull, Invalid: true},
+	}
+	tests := []struct {
+		name string
+		key  KeyType
+		want string
+	}{
+		{"revoked", KeyType{Revoked: true, UserIDs: uids}, "r"},
+		{"expired", KeyType{Expired: true, UserIDs: uids}, "e"},
+		{"invalid", KeyType{Invalid: true, UserIDs: uids}, "i"},
+		{"best valid user ID", KeyType{UserIDs: uids}, "m"},
+		{"no user IDs", KeyType{}, "-"},
+	}
+	for _, tt := range tests {
+		if got := colonKeyValidity(tt.key); got != tt.want {
+			t.Errorf("%s: colonKeyValidity = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestColonAlgo(t *testing.T) {
+	tests := []struct {
+		sk   SubKeyType
+		want string
+	}{
+		{SubKeyType{PubkeyAlgo: "RSA"}, "1"},
+		{SubKeyType{PubkeyAlgo: "DSA"}, "17"},
+		{SubKeyType{PubkeyAlgo: "ELG-E"}, "16"},
+		{SubKeyType{PubkeyAlgo: "EdDSA"}, "22"},
+		{SubKeyType{PubkeyAlgo: "ECDH"}, "18"},
+		{SubKeyType{Curve: "ed448"}, "22"},
+		{SubKeyType{Curve: "cv25519"}, "18"},
+		{SubKeyType{Curve: "nistp256", Capabilities: "e"}, "18"},
+		{SubKeyType{Curve: "nistp256", Capabilities: "s"}, "19"},
+		{SubKeyType{PubkeyAlgo: "unknown"}, ""},
+	}
+	for _, tt := range tests {
+		if got := colonAlgo(tt.sk); got != tt.want {
+			t.Errorf("colonAlgo(%+v) = %q, want %q", tt.sk, got, tt.want)
+		}
+	}
+}
+
+func TestColonTime(t *testing.T) {
+	tests := []struct {
+		field string
+		want  time.Time
+	}{
+		{"1735689600", time.Unix(1735689600, 0)},
+		{"0", time.Time{}},
+		{"", time.Time{}},
+		{"-1", time.Time{}},
+		{"20250101T000000", time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := colonTime(tt.field); !got.Equal(tt.want) {
+			t.Errorf("colonTime(%q) = %v, want %v", tt.field, got, tt.want)
+		}
+		if tt.want.IsZero() {
+			continue
+		}
+		if got := colonTimeField(tt.want); got != tt.field {
+			t.Errorf("colonTimeField(%v) = %q, want %q", tt.want, got, tt.field)
+		}
+	}
+	if got := colonTimeField(time.Time{}); got != "" {
+		t.Errorf("colonTimeField of the zero time = %q, want empty", got)
+	}
+}
+
+func TestEscapeColon(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Alice <alice@example.org>", "Alice <alice@example.org>"},
+		{"a:b", `a\x3ab`},
+		{`a\b`, `a\x5cb`},
+		{"tab\there\n", `tab\x09here\x0a`},
+		{"del\x7f", `del\x7f`},
+		{"Jürgen", "Jürgen"},
+	}
+	for _, tt := range tests {
+		got := escapeColon(tt.in)
+		if got != tt.want {
+			t.Errorf("escapeColon(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if back := unescapeColon(got); back != tt.in {
+			t.Errorf("unescapeColon(%q) = %q, want %q", got, back, tt.in)
+		}
+	}
+}
+
+// EOF
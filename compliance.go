@@ -0,0 +1,106 @@
+/* compliance.go - de-vs compliance reporting for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// gpgme.go reports the compliance of a single operation only for the
+// decryption (DecryptResultType.IsDEVS). For the other operations the
+// compliance is derived from the engine: gpg in de-vs mode refuses
+// algorithms and keys, which are not approved for VS-NfD, for encryption
+// and signing. Whether the installed GnuPG version itself is approved is
+// reported by gpgconf.
+
+package gpggohigh
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// ComplianceDEVS is the compliance mode for the German VS-NfD
+// (restricted) classification.
+const ComplianceDEVS = "de-vs"
+
+// ErrNotCompliant is returned by a Session with RequireCompliance, if the
+// engine or an operation is not de-vs compliant.
+var ErrNotCompliant = errors.New("not de-vs compliant")
+
+// ComplianceInfo tells whether an operation is de-vs compliant.
+type ComplianceInfo struct {
+	Mode       string // the compliance mode of gpg, e.g. "gnupg" or ComplianceDEVS
+	EngineDEVS bool   // the GnuPG version is approved for VS-NfD
+	Beta       bool   // reported by a beta version, so the approval is not final
+	DEVS       bool   // the operation is de-vs compliant
+}
+
+// EngineCompliance returns the compliance of the engine, which is the one
+// of encryption and signing operations: they are de-vs compliant, if gpg
+// runs in de-vs mode and its version is approved.
+func EngineCompliance() (info ComplianceInfo, err error) {
+	info, err = engineCompliance("")
+	if err != nil {
+		return info, fmt.Errorf("EngineCompliance - %w", err)
+	}
+	return info, nil
+}
+
+// engineCompliance implements EngineCompliance for homeDir, empty for the
+// one of SetHomeDir.
+func engineCompliance(homeDir string) (info ComplianceInfo, err error) {
+	if homeDir == "" {
+		_, homeDir = gpgEngine()
+	}
+	out, err := runGpgconfHome(homeDir, "--list-options", "gpg")
+	if err != nil {
+		return info, err
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		// name:flags:level:description:type:alt-type:argname:default:argdef:value
+		fields := strings.Split(line, ":")
+		if len(fields) < 10 {
+			continue
+		}
+		value := fields[9]
+		if value == "" {
+			value = fields[7]
+		}
+		switch fields[0] {
+		case "compliance":
+			// the value of string options is prefixed with a quote
+			info.Mode = unescapeStatus(strings.TrimPrefix(value, `"`))
+		case "compliance_de_vs":
+			info.EngineDEVS = value != "" && value != "0"
+		}
+	}
+	info.DEVS = info.Mode == ComplianceDEVS && info.EngineDEVS
+	return info, nil
+}
+
+// DecryptCompliance returns the compliance of a decryption with the
+// result, given the compliance of the engine.
+func DecryptCompliance(engine ComplianceInfo, result gpgme.DecryptResultType) ComplianceInfo {
+	info := engine
+	info.Beta = info.Beta || result.BetaCompliance
+	info.DEVS = result.IsDEVS
+	return info
+}
+
+// EOF
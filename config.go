@@ -0,0 +1,298 @@
+/* config.go - GnuPG configuration files for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// The configuration files of the home directory have one option per
+// line, written like the command line option without the leading dashes,
+// e.g. `trust-model tofu+pgp`. Empty lines and lines starting with `#`
+// are comments. The files are changed line by line, so comments and the
+// order of the options are kept.
+
+package gpggohigh
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// Configuration files in the GnuPG home directory.
+const (
+	ConfigGpg      = "gpg.conf"     // options of gpg, e.g. default-key, trust-model
+	ConfigCommon   = "common.conf"  // options of all GnuPG components, e.g. use-keyboxd
+	ConfigDirmngr  = "dirmngr.conf" // options of dirmngr, e.g. keyserver
+	ConfigGpgAgent = "gpg-agent.conf"
+)
+
+// configLockTimeout is how long ModifyConfig waits for a lock held by
+// another process.
+const configLockTimeout = 5 * time.Second
+
+// ErrConfigLocked is returned, if a configuration file stays locked by
+// another process.
+var ErrConfigLocked = errors.New("configuration file is locked")
+
+// ConfigFile is a GnuPG configuration file read by ReadConfig.
+type ConfigFile struct {
+	Path  string // the file name including the home directory
+	lines []string
+}
+
+// ReadConfig reads the configuration file name, e.g. ConfigGpg, of the
+// home directory. A missing file reads as an empty one.
+func ReadConfig(name string) (config *ConfigFile, err error) {
+	config, err = readConfig(configPath("", name))
+	if err != nil {
+		return nil, fmt.Errorf("ReadConfig - %w", err)
+	}
+	return config, nil
+}
+
+// ModifyConfig reads the configuration file name of the home directory,
+// calls fn to change it and writes it back, if fn returns no error. The
+// file is locked against concurrent changes by other users of this
+// function and replaced atomically.
+func ModifyConfig(name string, fn func(config *ConfigFile) error) error {
+	err := modifyConfig(configPath("", name), fn)
+	if err != nil {
+		return fmt.Errorf("ModifyConfig - %w", err)
+	}
+	return nil
+}
+
+// SetConfigOption sets the option of the configuration file name to
+// value, see ConfigFile.Set.
+func SetConfigOption(name, option, value string) error {
+	return ModifyConfig(name, func(config *ConfigFile) error {
+		config.Set(option, value)
+		return nil
+	})
+}
+
+// configPath returns the file name of the configuration file name in
+// homeDir. An empty homeDir is the home directory of SetHomeDir or the
+// default one.
+func configPath(homeDir, name string) string {
+	if homeDir == "" {
+		_, homeDir = gpgEngine()
+	}
+	if homeDir == "" {
+		homeDir = gpgme.GetDirInfo("homedir")
+	}
+	return filepath.Join(homeDir, name)
+}
+
+// readConfig reads the configuration file path.
+func readConfig(path string) (*ConfigFile, error) {
+	config := &ConfigFile{Path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if text != "" {
+		config.lines = strings.Split(text, "\n")
+	}
+	return config, nil
+}
+
+// modifyConfig implements ModifyConfig for the file path.
+func modifyConfig(path string, fn func(config *ConfigFile) error) error {
+	unlock, err := lockConfig(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	config, err := readConfig(path)
+	if err != nil {
+		return err
+	}
+	if err = fn(config); err != nil {
+		return err
+	}
+	return config.write()
+}
+
+// lockConfig creates the lock file of the configuration file path and
+// returns the function removing it.
+func lockConfig(path string) (unlock func(), err error) {
+	lockName := path + ".lock"
+	deadline := time.Now().Add(configLockTimeout)
+	for {
+		fh, err := os.OpenFile(lockName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			fmt.Fprintf(fh, "%d\n", os.Getpid())
+			_ = fh.Close()
+			return func() { _ = os.Remove(lockName) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s: %w", lockName, ErrConfigLocked)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Write writes the configuration file back, e.g. after ReadConfig and
+// Set. It is not locked, use ModifyConfig for that.
+func (c *ConfigFile) Write() error {
+	if err := c.write(); err != nil {
+		return fmt.Errorf("ConfigFile.Write - %w", err)
+	}
+	return nil
+}
+
+// write replaces the configuration file atomically, keeping its mode.
+func (c *ConfigFile) write() error {
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0700); err != nil {
+		return err
+	}
+	fh, err := createTemp(c.Path)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	if info, err := os.Stat(c.Path); err == nil {
+		_ = fh.Chmod(info.Mode().Perm())
+	}
+	text := strings.Join(c.lines, "\n")
+	if text != "" {
+		text += "\n"
+	}
+	if _, err = fh.WriteString(text); err != nil {
+		_ = os.Remove(fh.Name())
+		return err
+	}
+	return commitTemp(fh, c.Path)
+}
+
+// parseConfigLine returns the option and the value of a line, an empty
+// option for a comment.
+func parseConfigLine(line string) (option, value string) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' {
+		return "", ""
+	}
+	i := strings.IndexAny(line, " \t")
+	if i < 0 {
+		return line, ""
+	}
+	return line[:i], strings.TrimSpace(line[i+1:])
+}
+
+// Has reports whether the option is set.
+func (c *ConfigFile) Has(option string) bool {
+	_, ok := c.Get(option)
+	return ok
+}
+
+// Get returns the value of the last occurrence of the option, which is
+// the effective one for single value options. The value of a flag like
+// `no-greeting` is empty.
+func (c *ConfigFile) Get(option string) (value string, ok bool) {
+	for _, line := range c.lines {
+		if o, v := parseConfigLine(line); o == option {
+			value, ok = v, true
+		}
+	}
+	return value, ok
+}
+
+// GetAll returns the values of all occurrences of the option, e.g. for
+// `keyserver` or `group`.
+func (c *ConfigFile) GetAll(option string) (values []string) {
+	for _, line := range c.lines {
+		if o, v := parseConfigLine(line); o == option {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Options returns the options set in the file in their order.
+func (c *ConfigFile) Options() (options []string) {
+	for _, line := range c.lines {
+		if o, _ := parseConfigLine(line); o != "" {
+			options = append(options, o)
+		}
+	}
+	return options
+}
+
+// Set sets the option to value, an empty value for a flag. The first
+// occurrence of the option is replaced and further ones are removed, so
+// comments around it are kept. If the option is not set, it is appended.
+func (c *ConfigFile) Set(option, value string) {
+	line := configLine(option, value)
+	found := false
+	lines := c.lines[:0]
+	for _, l := range c.lines {
+		if o, _ := parseConfigLine(l); o == option {
+			if found {
+				continue
+			}
+			found = true
+			l = line
+		}
+		lines = append(lines, l)
+	}
+	c.lines = lines
+	if !found {
+		c.lines = append(c.lines, line)
+	}
+}
+
+// Add appends another occurrence of the option, for options which may be
+// given several times.
+func (c *ConfigFile) Add(option, value string) {
+	c.lines = append(c.lines, configLine(option, value))
+}
+
+// Unset removes all occurrences of the option.
+func (c *ConfigFile) Unset(option string) {
+	lines := c.lines[:0]
+	for _, l := range c.lines {
+		if o, _ := parseConfigLine(l); o != option {
+			lines = append(lines, l)
+		}
+	}
+	c.lines = lines
+}
+
+// configLine returns the line setting option to value.
+func configLine(option, value string) string {
+	if value == "" {
+		return option
+	}
+	return option + " " + value
+}
+
+// EOF
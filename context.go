@@ -0,0 +1,87 @@
+/* context.go - cancellation support for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// gpgme.go offers no way to cancel a running operation. But when the
+// data is passed through Go callbacks, an error returned by a callback
+// makes gpgme abort the operation. So the data is wrapped into readers
+// and writers which fail as soon as the context is done.
+//
+// This only takes effect when gpgme transfers data. An operation waiting
+// for gpg-agent or pinentry, e.g. for a passphrase, or one which has
+// already read all of its input from memory, is not interrupted and
+// ends when the engine returns. gpgme.go has no way to stop it earlier.
+
+package gpggohigh
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// ctxReader is an io.Reader which fails when ctx is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// ctxWriter is an io.Writer which fails when ctx is done.
+type ctxWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (c ctxWriter) Write(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.w.Write(p)
+}
+
+// newDataBytesCtx returns a gpgme data object reading b. If ctx can be
+// canceled, the data is read through a ctxReader, otherwise gpgme reads
+// the memory directly.
+func newDataBytesCtx(ctx context.Context, b []byte) (*gpgme.Data, error) {
+	if ctx.Done() == nil {
+		return gpgme.NewDataBytes(b)
+	}
+	return gpgme.NewDataReader(ctxReader{ctx: ctx, r: bytes.NewReader(b)})
+}
+
+// ctxError returns the error of ctx if it is done, so the caller can
+// check for context.Canceled or context.DeadlineExceeded, and err
+// otherwise.
+func ctxError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// EOF
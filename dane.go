@@ -0,0 +1,131 @@
+/* dane.go - OPENPGPKEY DNS records
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// RFC 7929 publishes the key of a mail address in the OPENPGPKEY
+// record of `<hash>._openpgpkey.<domain>`, where hash is the hex encoded
+// SHA-256 hash of the local part truncated to 28 octets. Like gpg the
+// local part is hashed in lower case, see section 3 of the RFC.
+
+package gpggohigh
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// OpenpgpkeyRecordType is an OPENPGPKEY DNS record.
+type OpenpgpkeyRecordType struct {
+	// Name is the fully qualified owner name, e.g.
+	// "c93f1e40...e3._openpgpkey.example.org.".
+	Name string
+	// Data is the record data, the binary key with only the user IDs of
+	// the mail address and without third-party signatures.
+	Data []byte
+	// Zone is the record as line of a zone file, with the base64 encoded
+	// data.
+	Zone string
+}
+
+// OpenpgpkeyName returns the owner name of the OPENPGPKEY record of the
+// mail address, e.g.
+// "c93f1e400f26708f98cb19d936620da35eec8f72e57f9eec01c1afd6._openpgpkey.example.com."
+// for "hugh@example.com".
+func OpenpgpkeyName(address string) (string, error) {
+	local, domain, found := strings.Cut(strings.TrimSpace(address), "@")
+	if !found || local == "" || domain == "" || strings.Contains(domain, "@") {
+		return "", fmt.Errorf("OpenpgpkeyName - not a mail address: %q", address)
+	}
+	sum := sha256.Sum256([]byte(strings.ToLower(local)))
+	return hex.EncodeToString(sum[:28]) + "._openpgpkey." +
+		strings.TrimSuffix(strings.ToLower(domain), ".") + ".", nil
+}
+
+// GenerateOpenpgpkeyRecord returns the OPENPGPKEY DNS record publishing
+// the key with the given fingerprint for the mail address email, which
+// must be the address of a user ID of the key. The error matches
+// ErrKeyNotFound with errors.Is, if the key is unknown.
+func GenerateOpenpgpkeyRecord(fingerprint, email string) (
+	record OpenpgpkeyRecordType, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return record, fmt.Errorf("GenerateOpenpgpkeyRecord - %w", err)
+	}
+	defer myContext.Release()
+
+	record, err = generateOpenpgpkeyRecord(myContext,
+		gpgCommand{homeDir: contextHomeDir(myContext)}, fingerprint, email)
+	if err != nil {
+		return record, fmt.Errorf("GenerateOpenpgpkeyRecord - %w", err)
+	}
+	return record, nil
+}
+
+// generateOpenpgpkeyRecord implements GenerateOpenpgpkeyRecord, the key
+// is looked up using myContext and exported running the command cmd.
+func generateOpenpgpkeyRecord(myContext *gpgme.Context, cmd gpgCommand,
+	fingerprint, email string) (record OpenpgpkeyRecordType, err error) {
+
+	fingerprint = NormalizeFingerprint(fingerprint)
+	if !isFingerprint(fingerprint) {
+		return record, fmt.Errorf("not a fingerprint: %q", fingerprint)
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	record.Name, err = OpenpgpkeyName(email)
+	if err != nil {
+		return record, err
+	}
+
+	keys, err := keyListFiltered(context.Background(), myContext, fingerprint,
+		KeyFilter{WithoutSignatures: true})
+	if err != nil {
+		return record, err
+	}
+	if len(keys) == 0 {
+		return record, fmt.Errorf("%w: %s", ErrKeyNotFound, fingerprint)
+	}
+	// gpg exports a key without user IDs, if none matches
+	hasAddress := false
+	for _, uid := range keys[0].UserIDs {
+		if strings.EqualFold(uid.Address, email) && !uid.Revoked && !uid.Invalid {
+			hasAddress = true
+			break
+		}
+	}
+	if !hasAddress {
+		return record, fmt.Errorf("key %s has no valid user ID with %s", fingerprint, email)
+	}
+
+	record.Data, err = exportAddressKeys(cmd, email, []string{keys[0].Fingerprint})
+	if err != nil {
+		return record, err
+	}
+	record.Zone = fmt.Sprintf("%s IN OPENPGPKEY %s", record.Name,
+		base64.StdEncoding.EncodeToString(record.Data))
+	return record, nil
+}
+
+// EOF
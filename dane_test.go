@@ -0,0 +1,55 @@
+/* dane_test.go - tests of the OPENPGPKEY records
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import "testing"
+
+func TestOpenpgpkeyName(t *testing.T) {
+	const hugh = "c93f1e400f26708f98cb19d936620da35eec8f72e57f9eec01c1afd6"
+	tests := []struct {
+		address, want string
+		fails         bool
+	}{
+		// the example of RFC 7929
+		{address: "hugh@example.com", want: hugh + "._openpgpkey.example.com."},
+		{address: "Hugh@Example.COM", want: hugh + "._openpgpkey.example.com."},
+		{address: " hugh@example.com. ", want: hugh + "._openpgpkey.example.com."},
+		{address: "example.com", fails: true},
+		{address: "@example.com", fails: true},
+		{address: "hugh@", fails: true},
+		{address: "hugh@a@example.com", fails: true},
+	}
+	for _, tt := range tests {
+		got, err := OpenpgpkeyName(tt.address)
+		switch {
+		case tt.fails:
+			if err == nil {
+				t.Errorf("OpenpgpkeyName(%q) = %q, want an error", tt.address, got)
+			}
+		case err != nil:
+			t.Errorf("OpenpgpkeyName(%q): %v", tt.address, err)
+		case got != tt.want:
+			t.Errorf("OpenpgpkeyName(%q) = %q, want %q", tt.address, got, tt.want)
+		}
+	}
+}
+
+// EOF
@@ -0,0 +1,128 @@
+/* decryptkeys.go - keys of the recipients of decrypted data for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// gpgme only reports the key IDs the data was encrypted to and for each
+// whether the secret key is missing, so the keys are looked up in the
+// keyring to tell who the recipients are.
+
+package gpggohigh
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// RecipientKeyType is a recipient of decrypted data with its key.
+type RecipientKeyType struct {
+	KeyID       string           // the key ID the data was encrypted to
+	PubkeyAlgo  gpgme.PubkeyAlgo // the algorithm of the recipient key
+	Status      error            // e.g. no secret key, nil if the key could be used
+	Fingerprint string           // of the primary key, empty if not in the keyring
+	SubKey      string           // fingerprint of the subkey with the key ID
+	UserID      string           // the primary user ID, empty if not in the keyring
+	Secret      bool             // the secret key is available
+}
+
+// DecryptKeysType tells which key decrypted data and who else can.
+type DecryptKeysType struct {
+	// UsedKey is the recipient whose secret key decrypted the data, nil
+	// if the data was only symmetrically encrypted or the key is unknown,
+	// e.g. for a hidden recipient.
+	UsedKey *RecipientKeyType
+	// OtherRecipients are the other recipients of the data.
+	OtherRecipients []RecipientKeyType
+}
+
+// DecryptKeys looks up the recipients of decrypted data of the result of
+// a decryption in the keyring. The used key is the first recipient with
+// an available secret key, which gpg tries first.
+func DecryptKeys(result gpgme.DecryptResultType) (keys DecryptKeysType, err error) {
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return keys, fmt.Errorf("DecryptKeys - %w", err)
+	}
+	defer myContext.Release()
+
+	keys, err = decryptKeys(myContext, result)
+	if err != nil {
+		return keys, fmt.Errorf("DecryptKeys - %w", err)
+	}
+	return keys, nil
+}
+
+// decryptKeys implements DecryptKeys using myContext.
+func decryptKeys(myContext *gpgme.Context, result gpgme.DecryptResultType) (
+	keys DecryptKeysType, err error) {
+
+	for _, r := range result.Recipients {
+		rk := RecipientKeyType{KeyID: r.KeyID, PubkeyAlgo: r.PubkeyAlgo, Status: r.Status}
+		if strings.Trim(r.KeyID, "0") != "" {
+			if err := resolveRecipientKey(myContext, &rk); err != nil {
+				return keys, err
+			}
+		}
+		if keys.UsedKey == nil && rk.Status == nil && rk.Secret {
+			keys.UsedKey = &rk
+			continue
+		}
+		keys.OtherRecipients = append(keys.OtherRecipients, rk)
+	}
+	return keys, nil
+}
+
+// resolveRecipientKey sets the key facts of rk from the keyring.
+func resolveRecipientKey(myContext *gpgme.Context, rk *RecipientKeyType) error {
+	found, err := keyList(context.Background(), myContext, rk.KeyID)
+	if err != nil {
+		return err
+	}
+	for _, k := range found {
+		for _, sk := range k.SubKeys {
+			if !strings.EqualFold(sk.KeyID, rk.KeyID) {
+				continue
+			}
+			rk.Fingerprint, rk.SubKey = k.Fingerprint, sk.Fingerprint
+			if len(k.UserIDs) > 0 {
+				rk.UserID = k.UserIDs[0].UserID
+			}
+		}
+	}
+	if rk.SubKey == "" {
+		return nil
+	}
+
+	secret, err := findKeys(myContext, rk.SubKey, true)
+	if err != nil {
+		return fmt.Errorf("FindKeys failed: %w", err)
+	}
+	for _, k := range secret {
+		for sk := k.SubKeys(); sk != nil; sk = sk.Next() {
+			if sk.Fingerprint() == rk.SubKey {
+				rk.Secret = sk.Secret() || sk.CardNumber() != ""
+			}
+		}
+	}
+	return nil
+}
+
+// EOF
@@ -21,8 +21,15 @@
 package gpggohigh
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/kulbartsch/gpgme"
 )
@@ -40,101 +47,228 @@ import (
 func ModRecipients(operation gpgme.EncryptFlag, filename, backupExtension string,
 	recipients []string) (err error) {
 
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return fmt.Errorf("ModRecipients - %w", err)
+	}
+	defer myContext.Release()
+
+	_, err = modRecipients(myContext, operation, filename, recipients,
+		RecipientsOptions{BackupExtension: backupExtension}, false)
+	return err
+}
+
+// RecipientsOptions are the options of ModRecipientsOptions.
+type RecipientsOptions struct {
+	// BackupExtension is the extension for the backup of the original
+	// file, like for ModRecipients. If empty, no backup is made.
+	BackupExtension string
+	// DryRun resolves the recipients and reports the planned recipients
+	// without rewriting the file.
+	DryRun bool
+}
+
+// RecipientsResult reports the recipients of a file modified by
+// ModRecipientsOptions. Recipients are given by the fingerprint of their
+// primary key, or by the key ID the file is encrypted to, if the key is
+// not in the keyring.
+type RecipientsResult struct {
+	Previous   []string // the recipients before the modification
+	Resolved   []string // the keys selected by the recipients given
+	Recipients []string // who can decrypt the file now, with DryRun who could
+	Backup     string   // the file name of the backup, empty if none
+}
+
+// ModRecipientsOptions adds or changes recipients of an encrypted file
+// like ModRecipients and returns the resulting recipients, which are read
+// from the rewritten file. With opts.DryRun the file is not changed and
+// the recipients it would have are returned.
+func ModRecipientsOptions(operation gpgme.EncryptFlag, filename string,
+	recipients []string, opts RecipientsOptions) (result RecipientsResult, err error) {
+
+	// prepare the gpgme context
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return result, fmt.Errorf("ModRecipients - %w", err)
+	}
+	defer myContext.Release()
+
+	return modRecipients(myContext, operation, filename, recipients, opts, true)
+}
+
+// modRecipients implements ModRecipientsOptions using myContext.
+// Only if report is true, the previous and the resulting recipients are
+// read from the files, which runs gpg for each of them.
+func modRecipients(myContext *gpgme.Context, operation gpgme.EncryptFlag,
+	filename string, recipients []string, opts RecipientsOptions, report bool) (
+	result RecipientsResult, err error) {
+
 	// check the operation
 	if operation != gpgme.EncryptAddRecp && operation != gpgme.EncryptChgRecp {
-		return fmt.Errorf("ModRecipients - invalid operation: %v", operation)
+		return result, fmt.Errorf("ModRecipients - invalid operation: %v", operation)
 	}
+	defer func() {
+		if opts.DryRun {
+			return
+		}
+		audit(AuditEncrypt, "ModRecipients", err, func(event *AuditEvent) {
+			event.Recipients = result.Resolved
+			event.Input = filename
+			if err == nil {
+				event.Output, event.OutputSHA256 = filename, hashFile(filename)
+			}
+		})
+	}()
 
 	// check the filename does exist and is a readable file
 	fileStat, err := os.Stat(filename)
 	if err != nil {
-		return fmt.Errorf("ModRecipients - file does not exist: %w", err)
+		return result, fmt.Errorf("ModRecipients - file does not exist: %w", err)
 	}
 	if fileStat.IsDir() {
-		return fmt.Errorf("ModRecipients - file is a directory: %w", err)
+		return result, fmt.Errorf("ModRecipients - file is a directory: %w", err)
 	}
 
-	// prepare the gpgme context
-
-	myContext, err := gpgme.New()
+	thisRecipients, err := findRecipients(myContext, recipients)
 	if err != nil {
-		return fmt.Errorf("ModRecipients - gpgme.New failed: %w", err)
+		return result, fmt.Errorf("ModRecipients - FindKeys failed: %w", err)
+	}
+	for _, k := range thisRecipients {
+		result.Resolved = append(result.Resolved, k.Fingerprint())
 	}
-	defer myContext.Release()
 
-	err = myContext.SetProtocol(gpgme.ProtocolOpenPGP)
-	if err != nil {
-		return fmt.Errorf("ModRecipients - SetProtocol failed: %w", err)
+	cmd := gpgCommand{homeDir: contextHomeDir(myContext)}
+	if report || opts.DryRun {
+		result.Previous, err = fileRecipients(myContext, cmd, filename)
+		if err != nil {
+			return result, fmt.Errorf("ModRecipients - reading recipients failed: %w", err)
+		}
+	}
+
+	if opts.DryRun {
+		if operation == gpgme.EncryptAddRecp {
+			result.Recipients = append(result.Recipients, result.Previous...)
+		}
+		for _, fpr := range result.Resolved {
+			if !slices.Contains(result.Recipients, fpr) {
+				result.Recipients = append(result.Recipients, fpr)
+			}
+		}
+		return result, nil
 	}
 
 	dataIn, err := gpgme.NewData()
 	if err != nil {
-		return fmt.Errorf("ModRecipients - NewData (in) failed: %w", err)
+		return result, fmt.Errorf("ModRecipients - NewData (in) failed: %w", err)
 	}
 	defer dataIn.Close()
 
 	err = dataIn.SetFileName(filename)
 	if err != nil {
-		return fmt.Errorf("ModRecipients - SetFileName (in) failed: %w", err)
+		return result, fmt.Errorf("ModRecipients - SetFileName (in) failed: %w", err)
 	}
 
 	dataOut, err := gpgme.NewData()
 	if err != nil {
-		return fmt.Errorf("ModRecipients - NewData (out) failed: %w", err)
+		return result, fmt.Errorf("ModRecipients - NewData (out) failed: %w", err)
 	}
 	defer dataOut.Close()
 
-	randomFilePart := "." + RandomString(8)
+	random, err := RandomString(8)
+	if err != nil {
+		return result, fmt.Errorf("ModRecipients - %w", err)
+	}
+	randomFilePart := "." + random
 	// the random string collision probability is 1/62^8 = 4.58e-15
 	outFilename := filename + randomFilePart + ".tmp"
 	err = dataOut.SetFileName(outFilename)
 	if err != nil {
-		return fmt.Errorf("ModRecipients - SetFileName (out) failed: %w", err)
+		return result, fmt.Errorf("ModRecipients - SetFileName (out) failed: %w", err)
 	}
-
-	var thisRecipients []*gpgme.Key
-	for _, r := range recipients {
-		keys, err := gpgme.FindKeys(r, false)
-		if err != nil {
-			return fmt.Errorf("ModRecipients - FindKeys failed: %w", err)
+	committed := false
+	defer func() {
+		if !committed {
+			_ = os.Remove(outFilename)
 		}
-		thisRecipients = append(thisRecipients, keys...)
-	}
+	}()
 
 	// do the recipient modification
 	err = myContext.Encrypt(thisRecipients,
 		operation|gpgme.EncryptFile,
 		dataIn, dataOut)
 	if err != nil {
-		return fmt.Errorf("ModRecipients - Encrypt failed: %w", err)
+		return result, fmt.Errorf("ModRecipients - Encrypt failed: %w", err)
 	}
 
-	// rename the files
 	err = dataOut.Close()
 	if err != nil {
-		return fmt.Errorf("ModRecipients - Close (out) failed: %w", err)
+		return result, fmt.Errorf("ModRecipients - Close (out) failed: %w", err)
 	}
 	err = dataIn.Close()
 	if err != nil {
-		return fmt.Errorf("ModRecipients - Close (in) failed: %w", err)
+		return result, fmt.Errorf("ModRecipients - Close (in) failed: %w", err)
+	}
+
+	// read the recipients before the file is replaced, so nothing fails
+	// afterwards
+	if report {
+		result.Recipients, err = fileRecipients(myContext, cmd, outFilename)
+		if err != nil {
+			return result, fmt.Errorf("ModRecipients - reading recipients failed: %w", err)
+		}
 	}
-	if backupExtension != "" {
-		err = os.Rename(filename, filename+randomFilePart+backupExtension)
+
+	// rename the files, the original is restored, if the new one can
+	// not take its place
+	if opts.BackupExtension != "" {
+		backup := filename + randomFilePart + opts.BackupExtension
+		err = os.Rename(filename, backup)
+		if err != nil {
+			return result, fmt.Errorf("ModRecipients - file rename (1) failed: %w", err)
+		}
+		err = os.Rename(outFilename, filename)
 		if err != nil {
-			return fmt.Errorf("ModRecipients - file rename (1) failed: %w", err)
+			_ = os.Rename(backup, filename)
+			return result, fmt.Errorf("ModRecipients - file rename (2) failed: %w", err)
 		}
+		result.Backup = backup
 	} else { // no backup
-		err = os.Remove(filename)
+		err = os.Rename(outFilename, filename)
 		if err != nil {
-			return fmt.Errorf("ModRecipients - file remove failed: %w", err)
+			return result, fmt.Errorf("ModRecipients - file rename failed: %w", err)
 		}
 	}
-	err = os.Rename(outFilename, filename)
+	committed = true
+	return result, nil
+}
+
+// fileRecipients returns the recipients of the encrypted file filename by
+// the fingerprint of their primary key, or by key ID if the key is not
+// known to myContext.
+func fileRecipients(myContext *gpgme.Context, cmd gpgCommand, filename string) (
+	recipients []string, err error) {
+
+	fh, err := os.Open(filename)
 	if err != nil {
-		return fmt.Errorf("ModRecipients - file rename (2) failed: %w", err)
+		return nil, err
 	}
+	defer fh.Close()
 
-	return nil
+	keyIDs, err := encryptedToKeyIDs(cmd, fh)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range keyIDs {
+		keys, err := findKeys(myContext, id, false)
+		if err == nil && len(keys) == 1 {
+			id = keys[0].Fingerprint()
+		}
+		if !slices.Contains(recipients, id) {
+			recipients = append(recipients, id)
+		}
+	}
+	return recipients, nil
 }
 
 // EncryptFile encrypts a file with the recipients.
@@ -148,17 +282,103 @@ func ModRecipients(operation gpgme.EncryptFlag, filename, backupExtension string
 func EncryptFile(sourceFilename, destinationFilename string,
 	recipients []string, sign bool) (err error) {
 
-	myContext, err := gpgme.New()
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return fmt.Errorf("EncryptFile - %w", err)
+	}
+	defer myContext.Release()
+
+	return withRetry(context.Background(), nil, "", func() error {
+		return encryptFile(myContext, sourceFilename, destinationFilename, recipients,
+			sign, false)
+	})
+}
+
+// EncryptFileStrict encrypts a file like EncryptFile, but fails with
+// ErrRecipientNotFound or ErrAmbiguousRecipient if a recipient does not
+// select exactly one usable key. See ResolveRecipients.
+func EncryptFileStrict(sourceFilename, destinationFilename string,
+	recipients []string, sign bool) (err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return fmt.Errorf("EncryptFile - %w", err)
+	}
+	defer myContext.Release()
+
+	return encryptFile(myContext, sourceFilename, destinationFilename, recipients, sign, true)
+}
+
+// EncryptFileWithPolicy encrypts a file like EncryptFile, but fails with
+// a *RecipientPolicyError, matching ErrRecipientDenied, if a recipient
+// key is rejected by policy. Nothing is encrypted in that case.
+func EncryptFileWithPolicy(sourceFilename, destinationFilename string,
+	recipients []string, sign bool, policy RecipientPolicy) (err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return fmt.Errorf("EncryptFile - %w", err)
+	}
+	defer myContext.Release()
+
+	err = checkRecipientPolicy(myContext, recipients, policy)
+	if err != nil {
+		return fmt.Errorf("EncryptFile - %w", err)
+	}
+	return encryptFile(myContext, sourceFilename, destinationFilename, recipients, sign, false)
+}
+
+// EncryptFileSigned encrypts and signs a file like EncryptFile with sign
+// set, but signs with the keys selected by signers instead of the default
+// key of gpg.conf.
+func EncryptFileSigned(sourceFilename, destinationFilename string,
+	recipients, signers []string) (err error) {
+	return encryptFileSigned(SessionOptions{}, sourceFilename, destinationFilename,
+		recipients, signers)
+}
+
+// encryptFileSigned implements EncryptFileSigned with a context
+// configured by opts. A new context is used, because gpgme.go can not
+// clear the signers added to a context.
+func encryptFileSigned(opts SessionOptions, sourceFilename, destinationFilename string,
+	recipients, signers []string) (err error) {
+
+	myContext, err := newContext(opts)
 	if err != nil {
-		return fmt.Errorf("EncryptFile - gpgme.New failed: %w", err)
+		return fmt.Errorf("EncryptFile - %w", err)
 	}
 	defer myContext.Release()
 
-	err = myContext.SetProtocol(gpgme.ProtocolOpenPGP)
+	keys, err := findSigners(myContext, signers)
 	if err != nil {
-		return fmt.Errorf("EncryptFile - SetProtocol failed: %w", err)
+		return fmt.Errorf("EncryptFile - %w", err)
+	}
+	for _, k := range keys {
+		err = myContext.SignersAdd(k)
+		if err != nil {
+			return fmt.Errorf("EncryptFile - SignersAdd failed: %w", err)
+		}
 	}
 
+	return encryptFile(myContext, sourceFilename, destinationFilename, recipients, true, false)
+}
+
+// encryptFile implements EncryptFile and EncryptFileStrict using myContext.
+func encryptFile(myContext *gpgme.Context, sourceFilename, destinationFilename string,
+	recipients []string, sign, strict bool) (err error) {
+
+	var destination string
+	var thisRecipients []*gpgme.Key
+	defer func() {
+		audit(AuditEncrypt, "EncryptFile", err, func(event *AuditEvent) {
+			event.Recipients = keyFingerprints(thisRecipients)
+			event.Input, event.InputSHA256 = sourceFilename, hashFile(sourceFilename)
+			if err == nil {
+				event.Output, event.OutputSHA256 = destination, hashFile(destination)
+			}
+		})
+	}()
+
 	dataIn, err := gpgme.NewData()
 	if err != nil {
 		return fmt.Errorf("EncryptFile - NewData (in) failed: %w", err)
@@ -176,24 +396,27 @@ func EncryptFile(sourceFilename, destinationFilename string,
 	}
 	defer dataOut.Close()
 
-	var destination string
 	if destinationFilename == "" {
-		destination = sourceFilename + ".gpg"
+		destination = sourceFilename + encryptedExtension(myContext)
 	} else {
 		destination = destinationFilename
 	}
-	err = dataOut.SetFileName(destination)
+	outFilename, err := tempName(destination)
+	if err != nil {
+		return fmt.Errorf("EncryptFile - %w", err)
+	}
+	err = dataOut.SetFileName(outFilename)
 	if err != nil {
 		return fmt.Errorf("EncryptFile - SetFileName (out) failed: %w", err)
 	}
 
-	var thisRecipients []*gpgme.Key
-	for _, r := range recipients {
-		keys, err := gpgme.FindKeys(r, false)
-		if err != nil {
-			return fmt.Errorf("EncryptFile - FindKeys (out) failed: %w", err)
-		}
-		thisRecipients = append(thisRecipients, keys...)
+	if strict {
+		thisRecipients, err = strictRecipients(myContext, recipients)
+	} else {
+		thisRecipients, err = findRecipients(myContext, recipients)
+	}
+	if err != nil {
+		return fmt.Errorf("EncryptFile - FindKeys failed: %w", err)
 	}
 
 	if sign {
@@ -206,10 +429,69 @@ func EncryptFile(sourceFilename, destinationFilename string,
 			dataIn, dataOut)
 	}
 	if err != nil {
+		_ = os.Remove(outFilename)
 		return fmt.Errorf("EncryptFile - Encrypt failed: %w", err)
 	}
-	return err
 
+	err = commitFile(outFilename, destination)
+	if err != nil {
+		return fmt.Errorf("EncryptFile - writing %s failed: %w", destination, err)
+	}
+	return nil
+}
+
+// EncryptFileArmored encrypts a file like EncryptFile. If armored is
+// true, the output is ASCII armored and the default destination has the
+// extension `.asc` instead of `.gpg`, e.g. for mail attachments.
+func EncryptFileArmored(sourceFilename, destinationFilename string,
+	recipients []string, sign, armored bool) (err error) {
+
+	myContext, err := newContext(SessionOptions{Armor: armored})
+	if err != nil {
+		return fmt.Errorf("EncryptFile - %w", err)
+	}
+	defer myContext.Release()
+
+	return encryptFile(myContext, sourceFilename, destinationFilename, recipients, sign, false)
+}
+
+// EncryptFileToWriter encrypts the file sourceFilename like EncryptFile
+// and writes the encrypted data to w. If armored is true, the output is
+// ASCII armored.
+func EncryptFileToWriter(sourceFilename string, w io.Writer, recipients []string,
+	sign, armored bool) (err error) {
+
+	myContext, err := newContext(SessionOptions{Armor: armored})
+	if err != nil {
+		return fmt.Errorf("EncryptFile - %w", err)
+	}
+	defer myContext.Release()
+
+	return encryptFileToWriter(context.Background(), myContext, sourceFilename, w,
+		recipients, sign)
+}
+
+// encryptFileToWriter implements EncryptFileToWriter using myContext.
+func encryptFileToWriter(ctx context.Context, myContext *gpgme.Context,
+	sourceFilename string, w io.Writer, recipients []string, sign bool) error {
+
+	fhIn, err := os.Open(sourceFilename)
+	if err != nil {
+		return fmt.Errorf("EncryptFile - Open (in) failed: %w", err)
+	}
+	defer fhIn.Close()
+
+	return encryptStream(ctx, myContext, "EncryptFile", fhIn, w, recipients, sign)
+}
+
+// encryptedExtension returns the file extension of encrypted files
+// created with myContext, `.asc` for ASCII armored and `.gpg` for
+// binary output.
+func encryptedExtension(myContext *gpgme.Context) string {
+	if myContext.Armor() {
+		return ".asc"
+	}
+	return ".gpg"
 }
 
 // DecryptFile decrypts the named in cypherFilename file to clearFilename.
@@ -220,9 +502,41 @@ func EncryptFile(sourceFilename, destinationFilename string,
 // If the clearFilename exists, an error is returned.
 func DecryptFile(cypherFilename, clearFilename string) (decryptionResult gpgme.DecryptResultType,
 	filename string, signatures []gpgme.Signature, warning string, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		err = fmt.Errorf("DecryptFile - %w", err)
+		return
+	}
+	defer myContext.Release()
+
+	err = withRetry(context.Background(), nil, "", func() (err error) {
+		decryptionResult, filename, signatures, warning, err = decryptFile(myContext,
+			cypherFilename, clearFilename)
+		return err
+	})
+	return
+}
+
+// decryptFile implements DecryptFile using myContext.
+func decryptFile(myContext *gpgme.Context, cypherFilename, clearFilename string) (
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
 	warning = ""
 	err = nil
 
+	var destination string
+	defer func() {
+		audit(AuditDecrypt, "DecryptFile", err, func(event *AuditEvent) {
+			event.Recipients = decryptRecipients(decryptionResult)
+			event.Signers = signatureFingerprints(signatures)
+			event.Input, event.InputSHA256 = cypherFilename, hashFile(cypherFilename)
+			if err == nil {
+				event.Output, event.OutputSHA256 = destination, hashFile(destination)
+			}
+		})
+	}()
+
 	fileStat, err := os.Stat(cypherFilename)
 	if err != nil {
 		err = fmt.Errorf("DecryptFile - file does not exist: %w", err)
@@ -233,19 +547,6 @@ func DecryptFile(cypherFilename, clearFilename string) (decryptionResult gpgme.D
 		return
 	}
 
-	myContext, err := gpgme.New()
-	if err != nil {
-		err = fmt.Errorf("DecryptFile - gpgme.New failed: %w", err)
-		return
-	}
-	defer myContext.Release()
-
-	err = myContext.SetProtocol(gpgme.ProtocolOpenPGP)
-	if err != nil {
-		err = fmt.Errorf("DecryptFile - SetProtocol failed: %w", err)
-		return
-	}
-
 	dataIn, err := gpgme.NewData()
 	if err != nil {
 		err = fmt.Errorf("DecryptFile - NewData (in) failed: %w", err)
@@ -266,27 +567,18 @@ func DecryptFile(cypherFilename, clearFilename string) (decryptionResult gpgme.D
 	}
 	defer dataOut.Close()
 
-	var destination string
-	if clearFilename == "" {
-		// check if the cypherFilename has a `.gpg` extension
-		if len(cypherFilename) > 4 && (cypherFilename[len(cypherFilename)-4:] == ".gpg" ||
-			cypherFilename[len(cypherFilename)-4:] == ".pgp" ||
-			cypherFilename[len(cypherFilename)-4:] == ".asc") {
-			destination = cypherFilename[:len(cypherFilename)-4]
-		} else {
-			err = fmt.Errorf("DecryptFile - no destination filename given, and no `.gpg` or `.pgp` or `.asc` extension found")
-			return
-		}
-	} else {
-		destination = clearFilename
-	}
-	_, err = os.Stat(destination)
-	if err == nil {
-		err = fmt.Errorf("DecryptFile - destination file exists: %s", destination)
+	destination, err = decryptDestination(cypherFilename, clearFilename)
+	if err != nil {
+		err = fmt.Errorf("DecryptFile - %w", err)
 		return
 	}
 
-	err = dataOut.SetFileName(destination)
+	outFilename, err := tempName(destination)
+	if err != nil {
+		err = fmt.Errorf("DecryptFile - %w", err)
+		return
+	}
+	err = dataOut.SetFileName(outFilename)
 	if err != nil {
 		err = fmt.Errorf("DecryptFile - SetFileName (out) failed: %w", err)
 		return
@@ -297,12 +589,23 @@ func DecryptFile(cypherFilename, clearFilename string) (decryptionResult gpgme.D
 		// continue on "No data" error (but note it), end otherwise
 		if err.Error() == "No data" {
 			warning = "DecryptFile - DecryptVerify: no encrypted data"
+			err = nil
 		} else {
+			_ = os.Remove(outFilename)
 			err = fmt.Errorf("DecryptFile - DecryptVerify failed: %w", err)
 			return
 		}
 	}
 
+	// without encrypted data gpg may not have written the file
+	if _, statErr := os.Lstat(outFilename); warning == "" || statErr == nil {
+		err = commitFile(outFilename, destination)
+		if err != nil {
+			err = fmt.Errorf("DecryptFile - writing %s failed: %w", destination, err)
+			return
+		}
+	}
+
 	decryptionResult, err = myContext.DecryptResult()
 	if err != nil {
 		err = fmt.Errorf("DecryptFile - DecryptResult failed: %w", err)
@@ -321,4 +624,829 @@ func DecryptFile(cypherFilename, clearFilename string) (decryptionResult gpgme.D
 	return
 }
 
+// DecryptOptions configures DecryptFileOptions.
+type DecryptOptions struct {
+	// Overwrite replaces an existing destination file. The file is
+	// replaced only after the decryption succeeded.
+	Overwrite bool
+	// Extensions are removed from the name of the encrypted file to get
+	// the destination, if none is given. If empty, `.gpg`, `.pgp` and
+	// `.asc` are used.
+	Extensions []string
+	// Output receives the decrypted data instead of a file, e.g.
+	// os.Stdout. The destination file name and the metadata options are
+	// not used then.
+	Output io.Writer
+	// UseEmbeddedFilename names the decrypted file after the original
+	// file name stored in the encrypted data, like the gpg option
+	// --use-embedded-filename. Only the base name is used, the file is
+	// created in the directory of the encrypted file. If the data has no
+	// usable file name, the destination is determined as usual.
+	UseEmbeddedFilename bool
+	// Tee are further destinations receiving the decrypted data while it
+	// is written to the file or Output. If the decryption fails, they may
+	// have received a part of the data.
+	Tee []io.Writer
+	// PreserveMetadata copies the permissions and the modification time
+	// of the encrypted file to the decrypted file, e.g. to restore a
+	// backup encrypted with EncryptOptions.PreserveMetadata.
+	PreserveMetadata bool
+	// RestoreTimestamp sets the modification time of the decrypted file
+	// to the time stored in the encrypted data, which gpg sets to the
+	// time of the encryption. The data is decrypted by gpg directly then,
+	// instead of through gpgme. It takes precedence over the time of
+	// PreserveMetadata.
+	RestoreTimestamp bool
+}
+
+// DecryptFileOptions decrypts a file like DecryptFile with the behavior
+// for the destination configured by opts.
+func DecryptFileOptions(cypherFilename, clearFilename string, opts DecryptOptions) (
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		err = fmt.Errorf("DecryptFile - %w", err)
+		return
+	}
+	defer myContext.Release()
+
+	return decryptFileOptions(context.Background(), myContext, gpgCommand{},
+		cypherFilename, clearFilename, opts)
+}
+
+// decryptFileOptions implements DecryptFileOptions using myContext, or
+// running the command cmd for RestoreTimestamp.
+func decryptFileOptions(ctx context.Context, myContext *gpgme.Context, cmd gpgCommand,
+	cypherFilename, clearFilename string, opts DecryptOptions) (
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+
+	fhIn, err := os.Open(cypherFilename)
+	if err != nil {
+		err = fmt.Errorf("DecryptFile - Open (in) failed: %w", err)
+		return
+	}
+	defer fhIn.Close()
+
+	return decryptReaderOptions(ctx, myContext, cmd, fhIn, []string{cypherFilename},
+		cypherFilename, clearFilename, opts)
+}
+
+// decryptReaderOptions decrypts the encrypted data read from fhIn like
+// DecryptFileOptions, the destination is derived from cypherFilename.
+// sources are the files fhIn reads, for the metadata of the decrypted
+// file. With RestoreTimestamp the data is decrypted running the command
+// cmd.
+func decryptReaderOptions(ctx context.Context, myContext *gpgme.Context, cmd gpgCommand,
+	fhIn io.Reader, sources []string, cypherFilename, clearFilename string,
+	opts DecryptOptions) (
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+
+	if opts.Output != nil {
+		return decryptStream(ctx, myContext, "DecryptFile", fhIn,
+			io.MultiWriter(append([]io.Writer{opts.Output}, opts.Tee...)...))
+	}
+
+	extensions := opts.Extensions
+	if len(extensions) == 0 {
+		extensions = defaultDecryptExtensions
+	}
+	var destination string
+	if !opts.UseEmbeddedFilename {
+		destination, err = decryptDestinationExt(cypherFilename, clearFilename,
+			extensions, opts.Overwrite)
+		if err != nil {
+			err = fmt.Errorf("DecryptFile - %w", err)
+			return
+		}
+	}
+
+	// the data is written to a temporary file first, so a failed
+	// decryption keeps an existing file; the embedded file name is only
+	// known after the decryption
+	tempFor := destination
+	if tempFor == "" {
+		tempFor = cypherFilename
+	}
+	fhOut, err := createTemp(tempFor)
+	if err != nil {
+		err = fmt.Errorf("DecryptFile - Create (out) failed: %w", err)
+		return
+	}
+	defer fhOut.Close()
+
+	out := io.MultiWriter(append([]io.Writer{fhOut}, opts.Tee...)...)
+	var literal time.Time
+	if opts.RestoreTimestamp {
+		if cmd.homeDir == "" {
+			cmd.homeDir = contextHomeDir(myContext)
+		}
+		decryptionResult, filename, signatures, warning, literal, err = decryptLiteral(
+			cmd, "DecryptFile", ctxReader{ctx: ctx, r: fhIn}, out)
+	} else {
+		decryptionResult, filename, signatures, warning, err = decryptStream(ctx,
+			myContext, "DecryptFile", fhIn, out)
+	}
+	if err == nil && opts.UseEmbeddedFilename {
+		embedded := decryptionResult.Filename
+		if embedded == "" {
+			// signed data has the file name in the verify result
+			embedded = filename
+		}
+		destination, err = embeddedDestination(cypherFilename, clearFilename,
+			embedded, extensions, opts.Overwrite)
+		if err != nil {
+			err = fmt.Errorf("DecryptFile - %w", err)
+		}
+	}
+	if err == nil {
+		// before the rename, so a failure keeps an existing file
+		err = setMetadata(sources, fhOut.Name(), opts, literal)
+		if err != nil {
+			err = fmt.Errorf("DecryptFile - %w", err)
+		}
+	}
+	if err != nil {
+		_ = os.Remove(fhOut.Name())
+		return
+	}
+	err = commitTemp(fhOut, destination)
+	if err != nil {
+		err = fmt.Errorf("DecryptFile - writing %s failed: %w", destination, err)
+	}
+	return
+}
+
+// embeddedDestination returns the destination named after the embedded
+// file name in the directory of cypherFilename. If the embedded name is
+// not usable, the destination is derived like decryptDestinationExt.
+func embeddedDestination(cypherFilename, clearFilename, embedded string,
+	extensions []string, overwrite bool) (string, error) {
+
+	name := sanitizeFilename(embedded)
+	if name == "" {
+		return decryptDestinationExt(cypherFilename, clearFilename, extensions, overwrite)
+	}
+	destination := filepath.Join(filepath.Dir(cypherFilename), name)
+	if !overwrite {
+		if _, err := os.Stat(destination); err == nil {
+			return "", fmt.Errorf("destination file exists: %s", destination)
+		}
+	}
+	return destination, nil
+}
+
+// sanitizeFilename returns the base name of the file name stored in
+// encrypted data, which may come from another system, or "" if it is not
+// usable as file name.
+func sanitizeFilename(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(path.Base(name))
+	switch name {
+	case "", ".", "..", "/", "_CONSOLE":
+		// _CONSOLE marks data for display only (--for-your-eyes-only)
+		return ""
+	}
+	return name
+}
+
+// defaultDecryptExtensions are the extensions removed from the name of
+// an encrypted file to get the name of the decrypted file.
+var defaultDecryptExtensions = []string{".gpg", ".pgp", ".asc"}
+
+// decryptDestination returns the name of the file to write the
+// decrypted data of cypherFilename to. If clearFilename is empty, it is
+// derived from cypherFilename by removing the extension `.gpg`, `.pgp`
+// or `.asc`. The destination must not exist.
+func decryptDestination(cypherFilename, clearFilename string) (string, error) {
+	return decryptDestinationExt(cypherFilename, clearFilename,
+		defaultDecryptExtensions, false)
+}
+
+// decryptDestinationExt returns the destination like decryptDestination,
+// removing one of the extensions. If overwrite is true, the destination
+// may exist.
+func decryptDestinationExt(cypherFilename, clearFilename string,
+	extensions []string, overwrite bool) (string, error) {
+
+	destination := clearFilename
+	if destination == "" {
+		for _, ext := range extensions {
+			if len(cypherFilename) > len(ext) && strings.HasSuffix(cypherFilename, ext) {
+				destination = strings.TrimSuffix(cypherFilename, ext)
+				break
+			}
+		}
+		if destination == "" {
+			return "", fmt.Errorf("no destination filename given, and no `%s` extension found",
+				strings.Join(extensions, "` or `"))
+		}
+	}
+	if overwrite {
+		return destination, nil
+	}
+	_, err := os.Stat(destination)
+	if err == nil {
+		return "", fmt.Errorf("destination file exists: %s", destination)
+	}
+	return destination, nil
+}
+
+// EncryptFileCtx encrypts a file like EncryptFile. The operation stops
+// at the next read or write of the data after ctx is done, like
+// EncryptStreamCtx, and the incomplete destination file is removed.
+// The file is read and written through gpggohigh instead of gpg to be
+// able to cancel the operation, so the original file name is not
+// stored in the encrypted data.
+func EncryptFileCtx(ctx context.Context, sourceFilename, destinationFilename string,
+	recipients []string, sign bool) (err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return fmt.Errorf("EncryptFile - %w", err)
+	}
+	defer myContext.Release()
+
+	return encryptFileCtx(ctx, myContext, sourceFilename, destinationFilename,
+		recipients, sign, nil)
+}
+
+// encryptFileCtx implements EncryptFileCtx using myContext. If progress
+// is not nil, it gets the progress of reading the source file.
+func encryptFileCtx(ctx context.Context, myContext *gpgme.Context,
+	sourceFilename, destinationFilename string, recipients []string,
+	sign bool, progress ProgressFunc) (err error) {
+
+	destination := destinationFilename
+	if destination == "" {
+		destination = sourceFilename + encryptedExtension(myContext)
+	}
+
+	fhIn, err := os.Open(sourceFilename)
+	if err != nil {
+		return fmt.Errorf("EncryptFile - Open (in) failed: %w", err)
+	}
+	defer fhIn.Close()
+
+	fhOut, err := createTemp(destination)
+	if err != nil {
+		return fmt.Errorf("EncryptFile - Create (out) failed: %w", err)
+	}
+	defer fhOut.Close()
+
+	err = encryptStream(ctx, myContext, "EncryptFile", fileProgressReader(fhIn, progress),
+		fhOut, recipients, sign)
+	if err != nil {
+		_ = os.Remove(fhOut.Name())
+		return err
+	}
+	err = commitTemp(fhOut, destination)
+	if err != nil {
+		return fmt.Errorf("EncryptFile - writing %s failed: %w", destination, err)
+	}
+	return nil
+}
+
+// DecryptFileCtx decrypts a file like DecryptFile. The operation stops
+// at the next read or write of the data after ctx is done, like
+// DecryptStreamCtx, and the incomplete destination file is removed.
+func DecryptFileCtx(ctx context.Context, cypherFilename, clearFilename string) (
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		err = fmt.Errorf("DecryptFile - %w", err)
+		return
+	}
+	defer myContext.Release()
+
+	return decryptFileCtx(ctx, myContext, cypherFilename, clearFilename, nil)
+}
+
+// decryptFileCtx implements DecryptFileCtx using myContext. If progress
+// is not nil, it gets the progress of reading the encrypted file.
+func decryptFileCtx(ctx context.Context, myContext *gpgme.Context,
+	cypherFilename, clearFilename string, progress ProgressFunc) (
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+
+	destination, err := decryptDestination(cypherFilename, clearFilename)
+	if err != nil {
+		err = fmt.Errorf("DecryptFile - %w", err)
+		return
+	}
+
+	fhIn, err := os.Open(cypherFilename)
+	if err != nil {
+		err = fmt.Errorf("DecryptFile - Open (in) failed: %w", err)
+		return
+	}
+	defer fhIn.Close()
+
+	fhOut, err := createTemp(destination)
+	if err != nil {
+		err = fmt.Errorf("DecryptFile - Create (out) failed: %w", err)
+		return
+	}
+	defer fhOut.Close()
+
+	decryptionResult, filename, signatures, warning, err = decryptStream(ctx,
+		myContext, "DecryptFile", fileProgressReader(fhIn, progress), fhOut)
+	if err != nil {
+		_ = os.Remove(fhOut.Name())
+		return
+	}
+	err = commitTemp(fhOut, destination)
+	if err != nil {
+		err = fmt.Errorf("DecryptFile - writing %s failed: %w", destination, err)
+	}
+	return
+}
+
+// EncryptBytes encrypts a memory buffer to the recipients and returns
+// the cipher text.
+//
+//   - plainText: the data to be encrypted
+//   - recipients: a slice of texts to select recipients
+//   - sign: if true, the data is signed as well; the user to sign with
+//     should be configured in gpg.conf
+//   - armored: if true, the output will be ASCII armored
+//   - cipherText: the encrypted data
+//   - err: an error if the encryption fails
+func EncryptBytes(plainText []byte, recipients []string, sign, armored bool) (
+	cipherText []byte, err error) {
+	return EncryptBytesCtx(context.Background(), plainText, recipients, sign, armored)
+}
+
+// EncryptBytesCtx encrypts a memory buffer like EncryptBytes. When ctx
+// is done, the encryption fails with the error of ctx at the next read
+// of plainText. It is not interrupted while gpg-agent or pinentry waits,
+// e.g. for the passphrase of the signing key, nor after plainText has
+// been read completely.
+func EncryptBytesCtx(ctx context.Context, plainText []byte, recipients []string,
+	sign, armored bool) (cipherText []byte, err error) {
+
+	myContext, err := newContext(SessionOptions{Armor: armored})
+	if err != nil {
+		return nil, fmt.Errorf("EncryptBytes - %w", err)
+	}
+	defer myContext.Release()
+
+	err = withRetry(ctx, nil, "", func() (err error) {
+		cipherText, err = encryptBytes(ctx, myContext, plainText, recipients, sign)
+		return err
+	})
+	return cipherText, err
+}
+
+// EncryptBytesWithPolicy encrypts a memory buffer like EncryptBytes, but
+// fails with a *RecipientPolicyError, matching ErrRecipientDenied, if a
+// recipient key is rejected by policy.
+func EncryptBytesWithPolicy(plainText []byte, recipients []string, sign, armored bool,
+	policy RecipientPolicy) (cipherText []byte, err error) {
+
+	myContext, err := newContext(SessionOptions{Armor: armored})
+	if err != nil {
+		return nil, fmt.Errorf("EncryptBytes - %w", err)
+	}
+	defer myContext.Release()
+
+	err = checkRecipientPolicy(myContext, recipients, policy)
+	if err != nil {
+		return nil, fmt.Errorf("EncryptBytes - %w", err)
+	}
+	return encryptBytes(context.Background(), myContext, plainText, recipients, sign)
+}
+
+// encryptBytes implements EncryptBytes using myContext.
+func encryptBytes(ctx context.Context, myContext *gpgme.Context, plainText []byte,
+	recipients []string, sign bool) (cipherText []byte, err error) {
+
+	var thisRecipients []*gpgme.Key
+	defer func() {
+		audit(AuditEncrypt, "EncryptBytes", err, func(event *AuditEvent) {
+			event.Recipients = keyFingerprints(thisRecipients)
+			event.InputSHA256 = hashBytes(plainText)
+			if err == nil {
+				event.OutputSHA256 = hashBytes(cipherText)
+			}
+		})
+	}()
+
+	dataIn, err := newDataBytesCtx(ctx, plainText)
+	if err != nil {
+		return nil, fmt.Errorf("EncryptBytes - NewData (in) failed: %w", err)
+	}
+	defer dataIn.Close()
+
+	dataOut, err := gpgme.NewData()
+	if err != nil {
+		return nil, fmt.Errorf("EncryptBytes - NewData (out) failed: %w", err)
+	}
+	defer dataOut.Close()
+
+	thisRecipients, err = findRecipients(myContext, recipients)
+	if err != nil {
+		return nil, fmt.Errorf("EncryptBytes - FindKeys failed: %w", err)
+	}
+
+	if sign {
+		err = myContext.EncryptSign(thisRecipients, gpgme.EncryptAlwaysTrust,
+			dataIn, dataOut)
+	} else {
+		err = myContext.Encrypt(thisRecipients, gpgme.EncryptAlwaysTrust,
+			dataIn, dataOut)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("EncryptBytes - Encrypt failed: %w", ctxError(ctx, err))
+	}
+
+	cipherText, err = readData(dataOut)
+	if err != nil {
+		return nil, fmt.Errorf("EncryptBytes - %w", err)
+	}
+	return cipherText, nil
+}
+
+// DecryptBytes decrypts a memory buffer and verifies the signatures
+// contained in it, if any.
+//
+//   - cipherText: the encrypted data, binary or ASCII armored
+//   - plainText: the decrypted data
+//   - decryptionResult: the result of the decryption
+//   - filename: the file name embedded in the encrypted data, if any
+//   - signatures: a slice of gpgme.Signature containing the verification results
+//   - warning: a note about a non fatal problem, e.g. no encrypted data
+//   - err: an error if the decryption fails
+func DecryptBytes(cipherText []byte) (plainText []byte,
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+	return DecryptBytesCtx(context.Background(), cipherText)
+}
+
+// DecryptBytesCtx decrypts a memory buffer like DecryptBytes. ctx is
+// only checked while gpgme reads cipherText, so a decryption waiting for
+// the passphrase in pinentry or for gpg-agent, or one which has read its
+// input already, runs until the engine returns.
+func DecryptBytesCtx(ctx context.Context, cipherText []byte) (plainText []byte,
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+
+	if pool := defaultPool.Load(); pool != nil {
+		s, err := pool.acquire()
+		if err != nil {
+			return nil, decryptionResult, "", nil, "", fmt.Errorf("DecryptBytes - %w", err)
+		}
+		defer pool.release(s)
+		return s.decryptBytes(ctx, cipherText)
+	}
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		err = fmt.Errorf("DecryptBytes - %w", err)
+		return
+	}
+	defer myContext.Release()
+
+	err = withRetry(ctx, nil, "", func() (err error) {
+		plainText, decryptionResult, filename, signatures, warning, err = decryptBytes(
+			ctx, myContext, cipherText)
+		return err
+	})
+	return
+}
+
+// decryptBytes implements DecryptBytes using myContext.
+func decryptBytes(ctx context.Context, myContext *gpgme.Context, cipherText []byte) (
+	plainText []byte, decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+
+	defer func() {
+		audit(AuditDecrypt, "DecryptBytes", err, func(event *AuditEvent) {
+			event.Recipients = decryptRecipients(decryptionResult)
+			event.Signers = signatureFingerprints(signatures)
+			event.InputSHA256 = hashBytes(cipherText)
+			if err == nil {
+				event.OutputSHA256 = hashBytes(plainText)
+			}
+		})
+	}()
+
+	dataIn, err := newDataBytesCtx(ctx, cipherText)
+	if err != nil {
+		err = fmt.Errorf("DecryptBytes - NewData (in) failed: %w", err)
+		return
+	}
+	defer dataIn.Close()
+
+	dataOut, err := gpgme.NewData()
+	if err != nil {
+		err = fmt.Errorf("DecryptBytes - NewData (out) failed: %w", err)
+		return
+	}
+	defer dataOut.Close()
+
+	err = myContext.DecryptVerify(dataIn, dataOut)
+	if err != nil {
+		// continue on "No data" error (but note it), end otherwise
+		if err.Error() == "No data" {
+			warning = "DecryptBytes - DecryptVerify: no encrypted data"
+		} else {
+			err = fmt.Errorf("DecryptBytes - DecryptVerify failed: %w", ctxError(ctx, err))
+			return
+		}
+	}
+
+	decryptionResult, err = myContext.DecryptResult()
+	if err != nil {
+		err = fmt.Errorf("DecryptBytes - DecryptResult failed: %w", err)
+		return
+	}
+
+	filename, signatures, err = myContext.VerifyResult()
+	if err != nil {
+		err = fmt.Errorf("DecryptBytes - VerifyResult failed: %w", err)
+		return
+	}
+
+	plainText, err = readData(dataOut)
+	if err != nil {
+		err = fmt.Errorf("DecryptBytes - %w", err)
+		return
+	}
+
+	return
+}
+
+// EncryptStream encrypts the data read from r to the recipients and
+// writes the cipher text to w.
+// The data is passed through gpgme in chunks, so it is never completely
+// held in memory, which makes it suitable for large data like backups
+// piped from tar.
+// recipients is a slice of texts to select recipients.
+// If sign is true the data is signed as well; the user to sign with
+// should be configured in gpg.conf.
+// If armored is true, the output is ASCII armored.
+func EncryptStream(r io.Reader, w io.Writer, recipients []string,
+	sign, armored bool) (err error) {
+	return EncryptStreamCtx(context.Background(), r, w, recipients, sign, armored)
+}
+
+// EncryptStreamCtx encrypts like EncryptStream. After ctx is done, the
+// encryption fails at the next read from r or write to w. Waiting for
+// gpg-agent or pinentry is not interrupted.
+func EncryptStreamCtx(ctx context.Context, r io.Reader, w io.Writer,
+	recipients []string, sign, armored bool) (err error) {
+
+	myContext, err := newContext(SessionOptions{Armor: armored})
+	if err != nil {
+		return fmt.Errorf("EncryptStream - %w", err)
+	}
+	defer myContext.Release()
+
+	return encryptStream(ctx, myContext, "EncryptStream", r, w, recipients, sign)
+}
+
+// encryptStream implements EncryptStream using myContext. fn is the
+// function name used in error messages.
+func encryptStream(ctx context.Context, myContext *gpgme.Context, fn string,
+	r io.Reader, w io.Writer, recipients []string, sign bool) (err error) {
+
+	dataIn, err := gpgme.NewDataReader(ctxReader{ctx: ctx, r: r})
+	if err != nil {
+		return fmt.Errorf("%s - NewData (in) failed: %w", fn, err)
+	}
+	defer dataIn.Close()
+
+	dataOut, err := gpgme.NewDataWriter(ctxWriter{ctx: ctx, w: w})
+	if err != nil {
+		return fmt.Errorf("%s - NewData (out) failed: %w", fn, err)
+	}
+	defer dataOut.Close()
+
+	return encryptData(ctx, myContext, fn, dataIn, dataOut, recipients, sign)
+}
+
+// encryptData encrypts the gpgme data dataIn to dataOut using myContext,
+// fn names the operation in errors.
+func encryptData(ctx context.Context, myContext *gpgme.Context, fn string,
+	dataIn, dataOut *gpgme.Data, recipients []string, sign bool) (err error) {
+
+	var thisRecipients []*gpgme.Key
+	defer func() {
+		audit(AuditEncrypt, fn, err, func(event *AuditEvent) {
+			event.Recipients = keyFingerprints(thisRecipients)
+		})
+	}()
+
+	thisRecipients, err = findRecipients(myContext, recipients)
+	if err != nil {
+		return fmt.Errorf("%s - FindKeys failed: %w", fn, err)
+	}
+
+	if sign {
+		err = myContext.EncryptSign(thisRecipients, gpgme.EncryptAlwaysTrust,
+			dataIn, dataOut)
+	} else {
+		err = myContext.Encrypt(thisRecipients, gpgme.EncryptAlwaysTrust,
+			dataIn, dataOut)
+	}
+	if err != nil {
+		return fmt.Errorf("%s - Encrypt failed: %w", fn, ctxError(ctx, err))
+	}
+	return nil
+}
+
+// DecryptStream decrypts the data read from r and writes the plain text
+// to w. Signatures contained in the data are verified.
+// Like EncryptStream the data is never completely held in memory.
+// The return values are the same as for DecryptBytes, without the
+// plain text.
+func DecryptStream(r io.Reader, w io.Writer) (
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+	return DecryptStreamCtx(context.Background(), r, w)
+}
+
+// DecryptStreamCtx decrypts like DecryptStream. After ctx is done, the
+// decryption fails at the next read from r or write to w. Waiting for
+// the passphrase is not interrupted.
+func DecryptStreamCtx(ctx context.Context, r io.Reader, w io.Writer) (
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		err = fmt.Errorf("DecryptStream - %w", err)
+		return
+	}
+	defer myContext.Release()
+
+	return decryptStream(ctx, myContext, "DecryptStream", r, w)
+}
+
+// decryptStream implements DecryptStream using myContext. fn is the
+// function name used in error messages.
+func decryptStream(ctx context.Context, myContext *gpgme.Context, fn string,
+	r io.Reader, w io.Writer) (decryptionResult gpgme.DecryptResultType,
+	filename string, signatures []gpgme.Signature, warning string, err error) {
+
+	dataIn, err := gpgme.NewDataReader(ctxReader{ctx: ctx, r: r})
+	if err != nil {
+		err = fmt.Errorf("%s - NewData (in) failed: %w", fn, err)
+		return
+	}
+	defer dataIn.Close()
+
+	dataOut, err := gpgme.NewDataWriter(ctxWriter{ctx: ctx, w: w})
+	if err != nil {
+		err = fmt.Errorf("%s - NewData (out) failed: %w", fn, err)
+		return
+	}
+	defer dataOut.Close()
+
+	return decryptData(ctx, myContext, fn, dataIn, dataOut)
+}
+
+// decryptData decrypts the gpgme data dataIn to dataOut using myContext
+// and verifies the contained signatures, fn names the operation in errors.
+func decryptData(ctx context.Context, myContext *gpgme.Context, fn string,
+	dataIn, dataOut *gpgme.Data) (decryptionResult gpgme.DecryptResultType,
+	filename string, signatures []gpgme.Signature, warning string, err error) {
+
+	defer func() {
+		audit(AuditDecrypt, fn, err, func(event *AuditEvent) {
+			event.Recipients = decryptRecipients(decryptionResult)
+			event.Signers = signatureFingerprints(signatures)
+		})
+	}()
+
+	err = myContext.DecryptVerify(dataIn, dataOut)
+	if err != nil {
+		// continue on "No data" error (but note it), end otherwise
+		if err.Error() == "No data" {
+			warning = fn + " - DecryptVerify: no encrypted data"
+		} else {
+			err = fmt.Errorf("%s - DecryptVerify failed: %w", fn, ctxError(ctx, err))
+			return
+		}
+	}
+
+	decryptionResult, err = myContext.DecryptResult()
+	if err != nil {
+		err = fmt.Errorf("%s - DecryptResult failed: %w", fn, err)
+		return
+	}
+
+	filename, signatures, err = myContext.VerifyResult()
+	if err != nil {
+		err = fmt.Errorf("%s - VerifyResult failed: %w", fn, err)
+		return
+	}
+
+	return
+}
+
+// EncryptFileHandle encrypts the data read from the open file in to the
+// open file out, which may also be pipes or sockets. gpgme reads and
+// writes the file descriptors directly, without copying the data through
+// Go. If armored is true, the output is ASCII armored.
+// The files are not closed.
+func EncryptFileHandle(in, out *os.File, recipients []string, sign, armored bool) (
+	err error) {
+
+	myContext, err := newContext(SessionOptions{Armor: armored})
+	if err != nil {
+		return fmt.Errorf("EncryptFileHandle - %w", err)
+	}
+	defer myContext.Release()
+
+	return encryptFileHandle(myContext, in, out, recipients, sign)
+}
+
+// encryptFileHandle implements EncryptFileHandle using myContext.
+func encryptFileHandle(myContext *gpgme.Context, in, out *os.File,
+	recipients []string, sign bool) error {
+
+	dataIn, err := gpgme.NewDataFile(in)
+	if err != nil {
+		return fmt.Errorf("EncryptFileHandle - NewData (in) failed: %w", err)
+	}
+	defer dataIn.Close()
+
+	dataOut, err := gpgme.NewDataFile(out)
+	if err != nil {
+		return fmt.Errorf("EncryptFileHandle - NewData (out) failed: %w", err)
+	}
+	defer dataOut.Close()
+
+	return encryptData(context.Background(), myContext, "EncryptFileHandle",
+		dataIn, dataOut, recipients, sign)
+}
+
+// DecryptFileHandle decrypts the data read from the open file in to the
+// open file out like EncryptFileHandle and verifies the contained
+// signatures. The return values are the same as for DecryptBytes.
+// The files are not closed.
+func DecryptFileHandle(in, out *os.File) (decryptionResult gpgme.DecryptResultType,
+	filename string, signatures []gpgme.Signature, warning string, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		err = fmt.Errorf("DecryptFileHandle - %w", err)
+		return
+	}
+	defer myContext.Release()
+
+	return decryptFileHandle(myContext, in, out)
+}
+
+// decryptFileHandle implements DecryptFileHandle using myContext.
+func decryptFileHandle(myContext *gpgme.Context, in, out *os.File) (
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+
+	dataIn, err := gpgme.NewDataFile(in)
+	if err != nil {
+		err = fmt.Errorf("DecryptFileHandle - NewData (in) failed: %w", err)
+		return
+	}
+	defer dataIn.Close()
+
+	dataOut, err := gpgme.NewDataFile(out)
+	if err != nil {
+		err = fmt.Errorf("DecryptFileHandle - NewData (out) failed: %w", err)
+		return
+	}
+	defer dataOut.Close()
+
+	return decryptData(context.Background(), myContext, "DecryptFileHandle",
+		dataIn, dataOut)
+}
+
+// findRecipients returns the keys selected by the recipients texts and
+// the additional decryption keys, looked up with the configuration of
+// myContext.
+func findRecipients(myContext *gpgme.Context, recipients []string) (
+	keys []*gpgme.Key, err error) {
+	for _, r := range recipients {
+		found, err := findKeys(myContext, r, false)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, found...)
+	}
+	return appendAdditionalKeys(myContext, keys)
+}
+
 // EOF
@@ -0,0 +1,136 @@
+/* engine.go - GnuPG installation report for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ComponentType describes a GnuPG component like gpg-agent or dirmngr.
+type ComponentType struct {
+	Name        string // e.g. "gpg-agent"
+	Description string // e.g. "Private Keys"
+	Path        string // the file name of the program
+	Installed   bool   // the program can be run
+	ConfigOK    bool   // the configuration file has no errors
+	Version     string // e.g. "2.4.7", empty if not installed
+}
+
+// EngineReportType describes the GnuPG installation used by gpggohigh, e.g.
+// for a diagnostics page.
+type EngineReportType struct {
+	Engine          string // the file name of gpg, like GpgEngineInfo
+	HomeDir         string // the home directory
+	RequiredVersion string // the minimal version of gpg needed by gpgme
+	Version         string // the version of gpg
+	Components      []ComponentType
+	// Dirs are the directories and sockets of gpgconf --list-dirs by
+	// name, e.g. "agent-socket" or "sysconfdir".
+	Dirs         map[string]string
+	AgentRunning bool           // gpg-agent runs for the home directory
+	Compliance   ComplianceInfo // the compliance of the engine
+}
+
+// EngineReport returns GpgEngineInfo extended by the components, their
+// versions and the directories as reported by gpgconf. A gpg-agent is
+// not started by this.
+func EngineReport() (report EngineReportType, err error) {
+	report.Engine, report.HomeDir, report.RequiredVersion, report.Version, err =
+		GpgEngineInfo()
+	if err != nil {
+		return report, fmt.Errorf("EngineReport - %w", err)
+	}
+	err = engineReport(&report, report.HomeDir)
+	if err != nil {
+		return report, fmt.Errorf("EngineReport - %w", err)
+	}
+	return report, nil
+}
+
+// engineReport fills the gpgconf information of report for homeDir,
+// empty for the one of SetHomeDir.
+func engineReport(report *EngineReportType, homeDir string) error {
+	if homeDir == "" {
+		_, homeDir = gpgEngine()
+	}
+
+	out, err := runGpgconfHome(homeDir, "--list-dirs")
+	if err != nil {
+		return err
+	}
+	report.Dirs = make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if ok {
+			report.Dirs[name] = unescapeStatus(value)
+		}
+	}
+	if report.HomeDir == "" {
+		report.HomeDir = report.Dirs["homedir"]
+	}
+
+	// gpgconf fails, if a component is missing, but lists all of them
+	out, _ = runGpgconfHome(homeDir, "--check-programs")
+	for _, line := range strings.Split(string(out), "\n") {
+		// name:description:path:runtime:config:
+		fields := strings.Split(line, ":")
+		if len(fields) < 5 {
+			continue
+		}
+		c := ComponentType{
+			Name:        fields[0],
+			Description: unescapeStatus(fields[1]),
+			Path:        unescapeStatus(fields[2]),
+			Installed:   fields[3] == "1",
+			ConfigOK:    fields[4] == "1",
+		}
+		if c.Installed {
+			c.Version = programVersion(c.Path)
+		}
+		report.Components = append(report.Components, c)
+	}
+
+	report.AgentRunning = agentRunning(homeDir)
+	report.Compliance, err = engineCompliance(homeDir)
+	return err
+}
+
+// programVersion returns the version a GnuPG program prints with
+// --version, e.g. "2.4.7" from "gpg (GnuPG) 2.4.7", empty on errors.
+func programVersion(path string) string {
+	var stdout bytes.Buffer
+	cmd := exec.Command(path, "--version")
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return ""
+	}
+	first, _, _ := strings.Cut(stdout.String(), "\n")
+	fields := strings.Fields(first)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[len(fields)-1]
+}
+
+// EOF
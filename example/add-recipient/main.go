@@ -55,12 +55,18 @@ func main() {
 	recipients := os.Args[3:]
 
 	// add the recipients
-	err := gpggohigh.ModRecipients(op, filename, ".bak", recipients)
+	result, err := gpggohigh.ModRecipientsOptions(op, filename, recipients,
+		gpggohigh.RecipientsOptions{BackupExtension: ".bak"})
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
+	fmt.Println("The file is now encrypted to:")
+	for _, r := range result.Recipients {
+		fmt.Println("  " + r)
+	}
+
 }
 
 // EOF
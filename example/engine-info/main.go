@@ -49,6 +49,17 @@ func main() {
 	println("RequiredVersion:", reqVer)
 	println("Version........:", version)
 
+	report, err := gpggohigh.EngineReport()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, c := range report.Components {
+		fmt.Printf("%-15s: %s %s\n", c.Name, c.Version, c.Path)
+	}
+	fmt.Printf("%-15s: %s\n", "agent-socket", report.Dirs["agent-socket"])
+	fmt.Printf("%-15s: %v\n", "agent running", report.AgentRunning)
+
 }
 
 // EOF
@@ -38,13 +38,21 @@ func main() {
 	// get the filename
 	for _, filename := range os.Args[1:] {
 		// identify the file
-		GDType, err := gpggohigh.IdentifyFile(filename)
+		result, err := gpggohigh.IdentifyFileDetails(filename)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			// os.Exit(1) // just continue with the next file
 			continue
 		}
+		GDType := result.DataType
 		fmt.Printf("File: %s, Type: %v - %s\n", filename, GDType, gpggohigh.DataTypeMapString[GDType])
+		if result.Armored {
+			fmt.Printf("  Armor: %s, checksum: %v, valid: %v\n", result.Armor.BlockType,
+				result.Armor.HasCRC, result.Armor.CRCValid)
+			for _, h := range result.Armor.Headers {
+				fmt.Printf("  %s: %s\n", h.Key, h.Value)
+			}
+		}
 	}
 }
 
@@ -27,7 +27,6 @@ import (
 	"os"
 
 	"github.com/gnupg-com/gpggohigh"
-	"github.com/kulbartsch/gpgme"
 )
 
 func main() {
@@ -72,18 +71,20 @@ func main() {
 	fmt.Println(string(plainText))
 
 	fmt.Fprintf(os.Stderr, "=== verification info ===\n")
-	fmt.Fprintf(os.Stderr, "Signatures found: %d\n", len(signatures))
-	fmt.Fprintf(os.Stderr, "Filename        : %s\n", filename)
-	var valResult string
-	for i, sig := range signatures {
-		if sig.Summary&gpgme.SigSumValid != 0 {
-			valResult = "OK"
-		} else {
-			valResult = "NOT OK"
-		}
-		fmt.Fprintf(os.Stderr, "Signature[%d]: fingerprint=%s, summary=%d, status=%v Validity=%s\n",
-			i, sig.Fingerprint, sig.Summary, sig.Status, valResult)
+	signers, err := gpggohigh.ResolveSigners(signatures)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ResolveSigners failed: %v\n", err)
+		os.Exit(1)
+	}
+	report, err := gpggohigh.RenderVerification(gpggohigh.VerificationReport{
+		Filename:   filename,
+		Signatures: signers,
+	}, gpggohigh.ReportText)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "RenderVerification failed: %v\n", err)
+		os.Exit(1)
 	}
+	os.Stderr.Write(report)
 
 	/*
 		type Signature struct {
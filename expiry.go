@@ -0,0 +1,138 @@
+/* expiry.go - keys about to expire for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// ExpiringSubKeyType is a subkey, or the primary key, about to expire.
+type ExpiringSubKeyType struct {
+	Fingerprint  string
+	Primary      bool      // it is the primary key, the whole key expires
+	Capabilities string    // e.g. "e", see SubKeyType
+	Expires      time.Time // the expiration time
+}
+
+// ExpiringKeyType is a key with a primary key or subkeys about to expire.
+type ExpiringKeyType struct {
+	Fingerprint string               // of the primary key
+	UserIDs     []string             // the valid user IDs of the key
+	Secret      bool                 // the secret key is available, the owner can renew it
+	SubKeys     []ExpiringSubKeyType // the keys about to expire
+	// LostCapabilities are the capabilities, e.g. "e" for encryption,
+	// which no subkey of the key provides anymore after the expiration.
+	LostCapabilities string
+}
+
+// ListExpiringKeys returns the keys, which expire or have a subkey
+// expiring within the duration from now, e.g. to remind the owners to
+// renew them before encrypting or signing fails. Already expired and
+// revoked keys and subkeys are not listed. If secretOnly is true, only
+// keys with a secret key are listed, i.e. the keys of the user.
+func ListExpiringKeys(within time.Duration, secretOnly bool) (
+	keys []ExpiringKeyType, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("ListExpiringKeys - %w", err)
+	}
+	defer myContext.Release()
+
+	keys, err = listExpiringKeys(myContext, within, secretOnly)
+	if err != nil {
+		return nil, fmt.Errorf("ListExpiringKeys - %w", err)
+	}
+	return keys, nil
+}
+
+// listExpiringKeys implements ListExpiringKeys using myContext.
+func listExpiringKeys(myContext *gpgme.Context, within time.Duration,
+	secretOnly bool) (keys []ExpiringKeyType, err error) {
+
+	if within <= 0 {
+		return nil, fmt.Errorf("duration must be positive, not %s", within)
+	}
+	now := time.Now()
+	limit := now.Add(within)
+	// the capabilities of the subkeys are details
+	filter := KeyFilter{SecretOnly: secretOnly, NotExpired: true, NotRevoked: true,
+		WithDetails: true}
+	err = keyListEach(context.Background(), myContext, "", filter, func(key KeyType) bool {
+		if e, ok := expiringKey(key, now, limit); ok {
+			keys = append(keys, e)
+		}
+		return true
+	})
+	return keys, err
+}
+
+// expiringKey returns the subkeys of key, which expire after now until
+// limit, and whether there are any.
+func expiringKey(key KeyType, now, limit time.Time) (e ExpiringKeyType, ok bool) {
+	e.Fingerprint = key.Fingerprint
+	e.Secret = key.Secret
+	for _, uid := range key.UserIDs {
+		if !uid.Revoked && !uid.Invalid {
+			e.UserIDs = append(e.UserIDs, uid.UserID)
+		}
+	}
+
+	primaryExpires := !key.Expires.IsZero() && key.Expires.Before(limit)
+	var remaining string // capabilities of the subkeys valid after limit
+	var before string    // capabilities of the subkeys valid now
+	for i, sk := range key.SubKeys {
+		if sk.Revoked || sk.Expired || sk.Disabled || sk.Invalid {
+			continue
+		}
+		before += sk.Capabilities
+		expires := !sk.Expires.IsZero() && sk.Expires.After(now) && sk.Expires.Before(limit)
+		if i == 0 && primaryExpires || expires {
+			e.SubKeys = append(e.SubKeys, ExpiringSubKeyType{
+				Fingerprint:  sk.Fingerprint,
+				Primary:      i == 0,
+				Capabilities: sk.Capabilities,
+				Expires:      sk.Expires,
+			})
+			continue
+		}
+		if !primaryExpires {
+			remaining += sk.Capabilities
+		}
+	}
+	if len(e.SubKeys) == 0 {
+		return e, false
+	}
+
+	for _, c := range []string{"e", "s", "c", "a"} {
+		if strings.Contains(before, c) && !strings.Contains(remaining, c) {
+			e.LostCapabilities += c
+		}
+	}
+	return e, true
+}
+
+// EOF
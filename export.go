@@ -0,0 +1,305 @@
+/* export.go - high-level key export for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// Export modes of gpgme which are not defined by gpgme.go.
+// The values are taken from gpgme.h.
+const (
+	ExportModeSecret gpgme.ExportModeFlags = 16  // GPGME_EXPORT_MODE_SECRET
+	ExportModeSSH    gpgme.ExportModeFlags = 256 // GPGME_EXPORT_MODE_SSH
+)
+
+// ExportKey exports the key with the given fingerprint.
+// If secret is true, the secret key is exported, which usually requires
+// the passphrase of the key.
+// If armored is true, the output is ASCII armored.
+func ExportKey(fingerprint string, secret, armored bool) ([]byte, error) {
+	return ExportKeyMode(fingerprint, secret, armored, 0)
+}
+
+// ExportKeyMode exports a key like ExportKey, with additional export mode
+// flags like gpgme.ExportModeMinimal to strip all but the latest self
+// signatures, or ExportModeSSH to export the key in the OpenSSH format.
+// ExportModeSSH can not be combined with secret.
+func ExportKeyMode(fingerprint string, secret, armored bool,
+	mode gpgme.ExportModeFlags) (keyData []byte, err error) {
+
+	dataOut, err := gpgme.NewData()
+	if err != nil {
+		return nil, fmt.Errorf("ExportKey - NewData (out) failed: %w", err)
+	}
+	defer dataOut.Close()
+
+	err = exportKey(fingerprint, secret, armored, mode, dataOut)
+	if err != nil {
+		return nil, fmt.Errorf("ExportKey - %w", err)
+	}
+
+	keyData, err = readData(dataOut)
+	if err != nil {
+		return nil, fmt.Errorf("ExportKey - %w", err)
+	}
+	// gpgme does not report an unknown key as an error
+	if len(keyData) == 0 {
+		return nil, fmt.Errorf("ExportKey - key not found: %s", fingerprint)
+	}
+	return keyData, nil
+}
+
+// ExportKeyToFile exports a key like ExportKey into the file filename.
+// An existing file is overwritten.
+func ExportKeyToFile(fingerprint, filename string, secret, armored bool) error {
+	return ExportKeyModeToFile(fingerprint, filename, secret, armored, 0)
+}
+
+// ExportKeyModeToFile exports a key like ExportKeyMode into the file
+// filename. An existing file is replaced, when the export succeeded;
+// otherwise it is left untouched.
+func ExportKeyModeToFile(fingerprint, filename string, secret, armored bool,
+	mode gpgme.ExportModeFlags) (err error) {
+
+	err = checkExport(fingerprint, secret, mode)
+	if err != nil {
+		return fmt.Errorf("ExportKeyToFile - %w", err)
+	}
+
+	// the temporary file is only readable by the owner, as secret keys
+	// should be
+	fh, err := createTemp(filename)
+	if err != nil {
+		return fmt.Errorf("ExportKeyToFile - Create failed: %w", err)
+	}
+	defer fh.Close()
+
+	err = exportKeyToFile(fh, fingerprint, secret, armored, mode)
+	if err != nil {
+		_ = os.Remove(fh.Name())
+		return fmt.Errorf("ExportKeyToFile - %w", err)
+	}
+
+	err = commitTemp(fh, filename)
+	if err != nil {
+		return fmt.Errorf("ExportKeyToFile - %w", err)
+	}
+	return nil
+}
+
+// exportKeyToFile exports the key with the given fingerprint into fh and
+// fails, if nothing was exported.
+func exportKeyToFile(fh *os.File, fingerprint string, secret, armored bool,
+	mode gpgme.ExportModeFlags) error {
+
+	dataOut, err := gpgme.NewDataFile(fh)
+	if err != nil {
+		return fmt.Errorf("NewData (out) failed: %w", err)
+	}
+	defer dataOut.Close()
+
+	err = exportKey(fingerprint, secret, armored, mode, dataOut)
+	if err != nil {
+		return err
+	}
+
+	info, err := fh.Stat()
+	if err != nil {
+		return fmt.Errorf("Stat failed: %w", err)
+	}
+	// gpgme does not report an unknown key as an error
+	if info.Size() == 0 {
+		return fmt.Errorf("key not found: %s", fingerprint)
+	}
+	return nil
+}
+
+// checkExport checks the arguments of an export.
+func checkExport(fingerprint string, secret bool, mode gpgme.ExportModeFlags) error {
+	if fingerprint == "" {
+		// an empty pattern would export the whole keyring
+		return fmt.Errorf("no fingerprint given")
+	}
+	if secret && mode&ExportModeSSH != 0 {
+		return fmt.Errorf("secret keys can not be exported in SSH format")
+	}
+	return nil
+}
+
+// exportKey exports the key with the given fingerprint into dataOut.
+func exportKey(fingerprint string, secret, armored bool,
+	mode gpgme.ExportModeFlags, dataOut *gpgme.Data) error {
+
+	err := checkExport(fingerprint, secret, mode)
+	if err != nil {
+		return err
+	}
+	if secret {
+		mode |= ExportModeSecret
+	}
+
+	myContext, err := newContext(SessionOptions{Armor: armored})
+	if err != nil {
+		return err
+	}
+	defer myContext.Release()
+
+	err = myContext.Export(fingerprint, mode, dataOut)
+	if err != nil {
+		return fmt.Errorf("Export failed: %w", err)
+	}
+	return nil
+}
+
+// ExportSSHPublicKey returns the authentication subkey of the key with
+// the given fingerprint as line of an OpenSSH authorized_keys file, like
+// `gpg --export-ssh-key`, e.g. "ssh-ed25519 AAAA... openpgp:0x12345678".
+// If fingerprint is the one of a subkey, this subkey is exported,
+// otherwise the newest valid authentication subkey of the key. The error
+// matches ErrKeyNotFound with errors.Is, if the key is unknown.
+func ExportSSHPublicKey(fingerprint string) (string, error) {
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return "", fmt.Errorf("ExportSSHPublicKey - %w", err)
+	}
+	defer myContext.Release()
+
+	return exportSSHPublicKey(myContext, fingerprint)
+}
+
+// exportSSHPublicKey implements ExportSSHPublicKey using myContext.
+func exportSSHPublicKey(myContext *gpgme.Context, fingerprint string) (
+	line string, err error) {
+
+	fingerprint = NormalizeFingerprint(fingerprint)
+	if fingerprint == "" {
+		return "", fmt.Errorf("ExportSSHPublicKey - no fingerprint given")
+	}
+	keys, err := keyList(context.Background(), myContext, fingerprint)
+	if err != nil {
+		return "", fmt.Errorf("ExportSSHPublicKey - %w", err)
+	}
+	if len(keys) == 0 {
+		return "", fmt.Errorf("ExportSSHPublicKey - %w: %s", ErrKeyNotFound, fingerprint)
+	}
+	key := keys[0]
+	if key.Revoked || key.Expired || key.Disabled || key.Invalid {
+		return "", fmt.Errorf("ExportSSHPublicKey - key %s is not usable", fingerprint)
+	}
+
+	// gpg exports the newest authentication subkey of a key, an
+	// exclamation mark selects the subkey
+	pattern := fingerprint
+	usable := false
+	for i, sk := range key.SubKeys {
+		if i > 0 && FingerprintsEqual(sk.Fingerprint, fingerprint) {
+			pattern = fingerprint + "!"
+			usable = strings.Contains(sk.Capabilities, "a") && subKeyUsable(sk)
+			break
+		}
+		usable = usable || strings.Contains(sk.Capabilities, "a") && subKeyUsable(sk)
+	}
+	if !usable {
+		return "", fmt.Errorf("ExportSSHPublicKey - %s has no usable authentication key",
+			fingerprint)
+	}
+
+	dataOut, err := gpgme.NewData()
+	if err != nil {
+		return "", fmt.Errorf("ExportSSHPublicKey - NewData (out) failed: %w", err)
+	}
+	defer dataOut.Close()
+
+	err = myContext.Export(pattern, ExportModeSSH, dataOut)
+	if err != nil {
+		return "", fmt.Errorf("ExportSSHPublicKey - Export failed: %w", err)
+	}
+	keyData, err := readData(dataOut)
+	if err != nil {
+		return "", fmt.Errorf("ExportSSHPublicKey - %w", err)
+	}
+	line = strings.TrimSpace(string(keyData))
+	if line == "" {
+		return "", fmt.Errorf("ExportSSHPublicKey - %w: %s", ErrKeyNotFound, fingerprint)
+	}
+	return line, nil
+}
+
+// ExportKeyMinimal exports the public key with the given fingerprint
+// without third-party signatures and with only the latest self
+// signatures, like `gpg --export-options export-minimal`. The compact
+// binary key is meant for Autocrypt headers, WKD or firmware images.
+// Only the key with exactly this fingerprint is exported. The error
+// matches ErrKeyNotFound with errors.Is, if the key is unknown.
+func ExportKeyMinimal(fingerprint string) ([]byte, error) {
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("ExportKeyMinimal - %w", err)
+	}
+	defer myContext.Release()
+
+	return exportKeyMinimal(myContext, fingerprint)
+}
+
+// exportKeyMinimal implements ExportKeyMinimal using myContext.
+func exportKeyMinimal(myContext *gpgme.Context, fingerprint string) (
+	keyData []byte, err error) {
+
+	fingerprint = NormalizeFingerprint(fingerprint)
+	// a user ID or key ID could match several keys
+	if !isFingerprint(fingerprint) {
+		return nil, fmt.Errorf("ExportKeyMinimal - not a fingerprint: %q", fingerprint)
+	}
+
+	dataOut, err := gpgme.NewData()
+	if err != nil {
+		return nil, fmt.Errorf("ExportKeyMinimal - NewData (out) failed: %w", err)
+	}
+	defer dataOut.Close()
+
+	err = myContext.Export(fingerprint, gpgme.ExportModeMinimal, dataOut)
+	if err != nil {
+		return nil, fmt.Errorf("ExportKeyMinimal - Export failed: %w", err)
+	}
+	keyData, err = readData(dataOut)
+	if err != nil {
+		return nil, fmt.Errorf("ExportKeyMinimal - %w", err)
+	}
+	// gpgme does not report an unknown key as an error
+	if len(keyData) == 0 {
+		return nil, fmt.Errorf("ExportKeyMinimal - %w: %s", ErrKeyNotFound, fingerprint)
+	}
+	return keyData, nil
+}
+
+// subKeyUsable tells whether the subkey sk is neither revoked, expired,
+// disabled nor invalid.
+func subKeyUsable(sk SubKeyType) bool {
+	return !sk.Revoked && !sk.Expired && !sk.Disabled && !sk.Invalid
+}
+
+// EOF
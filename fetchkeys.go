@@ -0,0 +1,105 @@
+/* fetchkeys.go - retrieval of the keys of unknown signers
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// gpg retrieves the key of a signature while verifying it with
+// --auto-key-retrieve: by WKD, if the signature names the mail address
+// of the signer (signed with --sender), otherwise from the keyserver by
+// the issuer fingerprint or key ID.
+
+package gpggohigh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// DefaultFetchTimeout limits the retrieval of signer keys, if no timeout
+// is given.
+const DefaultFetchTimeout = 30 * time.Second
+
+// FetchSignerKeys retrieves the missing keys of the signatures of the
+// signed data, e.g. after VerifyBytes classified a signature as
+// SignatureMissingKey, and imports them. The retrieval is aborted after
+// timeout, DefaultFetchTimeout if it is 0. fetched are the fingerprints
+// of the imported keys, empty if no key was found.
+func FetchSignerKeys(signedData []byte, timeout time.Duration) (fetched []string,
+	err error) {
+
+	fetched, err = fetchSignerKeys(gpgCommand{}, signedData, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("FetchSignerKeys - %w", err)
+	}
+	return fetched, nil
+}
+
+// fetchSignerKeys implements FetchSignerKeys running the command cmd.
+func fetchSignerKeys(cmd gpgCommand, signedData []byte, timeout time.Duration) (
+	fetched []string, err error) {
+
+	if timeout <= 0 {
+		timeout = DefaultFetchTimeout
+	}
+	cmd.timeout = timeout
+	cmd.stdin = bytes.NewReader(signedData)
+	cmd.args = []string{"--verify", "--auto-key-retrieve", "-"}
+	status, err := cmd.run()
+
+	for _, i := range importResultFromStatus(status).Imports {
+		if i.Result == nil && i.Fingerprint != "" {
+			fetched = append(fetched, i.Fingerprint)
+		}
+	}
+	if len(fetched) > 0 {
+		// a bad signature fails the verification, not the import
+		audit(AuditImport, "FetchSignerKeys", nil, func(event *AuditEvent) {
+			event.Keys = fetched
+		})
+	}
+	if len(fetched) > 0 {
+		// a signature may still be bad, which the verification reports
+		return fetched, nil
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	if _, found := findStatus(status, "NO_PUBKEY"); !found {
+		// the keys were available, e.g. imported meanwhile
+		return nil, nil
+	}
+	return nil, fmt.Errorf("signer %w", ErrKeyNotFound)
+}
+
+// hasMissingKey reports, if the key of one of the signatures is not
+// available.
+func hasMissingKey(signatures []gpgme.Signature) bool {
+	for _, sig := range signatures {
+		if ClassifySignature(sig) == SignatureMissingKey {
+			return true
+		}
+	}
+	return false
+}
+
+// EOF
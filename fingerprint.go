@@ -0,0 +1,104 @@
+/* fingerprint.go - fingerprint helpers for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"crypto/subtle"
+	"strings"
+	"unicode"
+)
+
+// NormalizeFingerprint returns the fingerprint as gpgme reports it in
+// KeyType.Fingerprint: upper case without a "0x" prefix, spaces or
+// colons, e.g. for a fingerprint typed in or copied by a user.
+// The result is not checked to be a valid fingerprint.
+func NormalizeFingerprint(fingerprint string) string {
+	fingerprint = strings.TrimSpace(fingerprint)
+	if len(fingerprint) > 2 && (fingerprint[:2] == "0x" || fingerprint[:2] == "0X") {
+		fingerprint = fingerprint[2:]
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == ':' {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, fingerprint)
+}
+
+// FormatFingerprint returns the fingerprint in blocks of four characters
+// for displaying it, like gpg does. A v4 fingerprint has an extra space
+// in the middle:
+//
+//	4AAE 7570 D506 2F3C C8DC  CB9E 2234 0A7B 1981 3D3D
+func FormatFingerprint(fingerprint string) string {
+	fingerprint = NormalizeFingerprint(fingerprint)
+	var b strings.Builder
+	for i := 0; i < len(fingerprint); i += 4 {
+		if i > 0 {
+			b.WriteByte(' ')
+			if i == 20 && len(fingerprint) == 40 {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(fingerprint[i:min(i+4, len(fingerprint))])
+	}
+	return b.String()
+}
+
+// LongKeyID returns the 16 digit key ID of the key with the fingerprint,
+// which is the end of a v4 fingerprint and the start of a v5 one.
+// If the fingerprint is shorter, it is returned normalized.
+func LongKeyID(fingerprint string) string {
+	fingerprint = NormalizeFingerprint(fingerprint)
+	switch {
+	case len(fingerprint) == 64:
+		return fingerprint[:16]
+	case len(fingerprint) > 16:
+		return fingerprint[len(fingerprint)-16:]
+	}
+	return fingerprint
+}
+
+// ShortKeyID returns the 8 digit key ID of the key with the fingerprint.
+// Short key IDs are easy to forge and should only be displayed, not
+// used to select keys.
+func ShortKeyID(fingerprint string) string {
+	id := LongKeyID(fingerprint)
+	if len(id) > 8 {
+		// v5 key IDs are the start of the fingerprint
+		if len(NormalizeFingerprint(fingerprint)) == 64 {
+			return id[:8]
+		}
+		return id[len(id)-8:]
+	}
+	return id
+}
+
+// FingerprintsEqual reports whether the fingerprints are the same after
+// normalizing them. The comparison takes constant time for fingerprints
+// of the same length, so it can be used to check fingerprints given by
+// a remote party.
+func FingerprintsEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(NormalizeFingerprint(a)),
+		[]byte(NormalizeFingerprint(b))) == 1
+}
+
+// EOF
@@ -0,0 +1,105 @@
+/* fingerprint_test.go - tests of the fingerprint helpers
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import "testing"
+
+const (
+	testFingerprint   = "4AAE7570D5062F3CC8DCCB9E22340A7B19813D3D"
+	testFingerprintV5 = "19347BC9872464025F99DF3EC2E0000ED9884892E1F7B3EA4C94009159569B54"
+)
+
+func TestNormalizeFingerprint(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{testFingerprint, testFingerprint},
+		{"4aae7570d5062f3cc8dccb9e22340a7b19813d3d", testFingerprint},
+		{"0x4AAE7570D5062F3CC8DCCB9E22340A7B19813D3D", testFingerprint},
+		{"0X4aae7570d5062f3cc8dccb9e22340a7b19813d3d", testFingerprint},
+		{"4AAE 7570 D506 2F3C C8DC  CB9E 2234 0A7B 1981 3D3D", testFingerprint},
+		{" 4A:AE:75:70:D5:06:2F:3C:C8:DC:CB:9E:22:34:0A:7B:19:81:3D:3D\n", testFingerprint},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeFingerprint(tt.in); got != tt.want {
+			t.Errorf("NormalizeFingerprint(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatFingerprint(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{testFingerprint, "4AAE 7570 D506 2F3C C8DC  CB9E 2234 0A7B 1981 3D3D"},
+		{testFingerprintV5,
+			"1934 7BC9 8724 6402 5F99 DF3E C2E0 000E D988 4892 E1F7 B3EA 4C94 0091 5956 9B54"},
+		{"22340a7b19813d3d", "2234 0A7B 1981 3D3D"},
+		{"ABCDE", "ABCD E"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := FormatFingerprint(tt.in); got != tt.want {
+			t.Errorf("FormatFingerprint(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestKeyIDs(t *testing.T) {
+	tests := []struct {
+		in, long, short string
+	}{
+		{testFingerprint, "22340A7B19813D3D", "19813D3D"},
+		{"0x" + testFingerprint, "22340A7B19813D3D", "19813D3D"},
+		{testFingerprintV5, "19347BC987246402", "19347BC9"},
+		{"22340a7b19813d3d", "22340A7B19813D3D", "19813D3D"},
+		{"19813d3d", "19813D3D", "19813D3D"},
+	}
+	for _, tt := range tests {
+		if got := LongKeyID(tt.in); got != tt.long {
+			t.Errorf("LongKeyID(%q) = %q, want %q", tt.in, got, tt.long)
+		}
+		if got := ShortKeyID(tt.in); got != tt.short {
+			t.Errorf("ShortKeyID(%q) = %q, want %q", tt.in, got, tt.short)
+		}
+	}
+}
+
+func TestFingerprintsEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{testFingerprint, "0x" + FormatFingerprint(testFingerprint), true},
+		{testFingerprint, "4aae7570d5062f3cc8dccb9e22340a7b19813d3d", true},
+		{testFingerprint, testFingerprint[:39] + "E", false},
+		{testFingerprint, testFingerprint[:39], false},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		if got := FingerprintsEqual(tt.a, tt.b); got != tt.want {
+			t.Errorf("FingerprintsEqual(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// EOF
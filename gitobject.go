@@ -0,0 +1,212 @@
+/* gitobject.go - verification of signed git commits and tags
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// git signs the object without the signature: a commit carries it in
+// the `gpgsig` header, whose continuation lines start with a space, a
+// tag appends it to the tag message.
+
+package gpggohigh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// ErrNotSigned is returned for a git object without an OpenPGP signature.
+var ErrNotSigned = errors.New("git object has no OpenPGP signature")
+
+const (
+	gitSigBegin = "-----BEGIN PGP SIGNATURE-----"
+	// git signs SHA-1 and SHA-256 commits with different headers
+	gitSigHeader       = "gpgsig"
+	gitSigHeaderSHA256 = "gpgsig-sha256"
+)
+
+// GitSignatureType is the verification result of a signed git object.
+type GitSignatureType struct {
+	// Kind is "commit" or "tag".
+	Kind string
+	// Payload is the signed object without the signature.
+	Payload []byte
+	// Signature is the ASCII armored detached signature.
+	Signature []byte
+	// Signatures are the verified signatures with their keys. Use
+	// ClassifySignature or a VerifyPolicy to decide whether to accept
+	// them.
+	Signatures []ResolvedSignature
+}
+
+// SplitGitObject splits the raw git commit or tag object raw, e.g. the
+// output of `git cat-file commit HEAD`, into the signed payload and the
+// ASCII armored signature. An object starting with the header of a
+// loose object ("commit <size>\x00") is accepted too. ErrNotSigned is
+// returned, if the object has no OpenPGP signature.
+func SplitGitObject(raw []byte) (kind string, payload, signature []byte, err error) {
+	kind, payload, signature, err = splitGitObject(raw)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("SplitGitObject - %w", err)
+	}
+	return kind, payload, signature, nil
+}
+
+// splitGitObject implements SplitGitObject.
+func splitGitObject(raw []byte) (kind string, payload, signature []byte, err error) {
+	// the header of a loose object
+	if header, rest, found := bytes.Cut(raw, []byte{0}); found &&
+		(bytes.HasPrefix(header, []byte("commit ")) || bytes.HasPrefix(header, []byte("tag "))) {
+		raw = rest
+	}
+
+	switch {
+	case bytes.HasPrefix(raw, []byte("tree ")):
+		kind = "commit"
+		payload, signature = splitGitCommit(raw)
+	case bytes.HasPrefix(raw, []byte("object ")):
+		kind = "tag"
+		payload, signature = splitGitTag(raw)
+	default:
+		return "", nil, nil, errors.New("neither a commit nor a tag object")
+	}
+	if signature == nil {
+		return "", nil, nil, fmt.Errorf("%s: %w", kind, ErrNotSigned)
+	}
+	return kind, payload, signature, nil
+}
+
+// splitGitCommit returns the commit without the signature headers and
+// the signature from the gpgsig header. signature is nil if the commit
+// is not signed with OpenPGP.
+func splitGitCommit(raw []byte) (payload, signature []byte) {
+	var sigSHA256 []byte
+	var target *[]byte
+	inHeader := true
+	for len(raw) > 0 {
+		line := raw
+		if i := bytes.IndexByte(raw, '\n'); i >= 0 {
+			line = raw[:i+1]
+		}
+		raw = raw[len(line):]
+
+		if !inHeader {
+			payload = append(payload, line...)
+			continue
+		}
+		switch name, value, _ := bytes.Cut(line, []byte(" ")); {
+		case len(line) > 0 && line[0] == ' ' && target != nil:
+			// continuation of a signature header
+			*target = append(*target, line[1:]...)
+		case string(line) == "\n":
+			inHeader = false
+			target = nil
+			payload = append(payload, line...)
+		case string(name) == gitSigHeader:
+			target = &signature
+			*target = append([]byte{}, value...)
+		case string(name) == gitSigHeaderSHA256:
+			target = &sigSHA256
+			*target = append([]byte{}, value...)
+		default:
+			target = nil
+			payload = append(payload, line...)
+		}
+	}
+
+	if signature == nil {
+		signature = sigSHA256
+	}
+	if !bytes.HasPrefix(signature, []byte(gitSigBegin)) {
+		// e.g. signed with SSH or X.509
+		return payload, nil
+	}
+	return payload, signature
+}
+
+// splitGitTag returns the tag up to the signature and the signature.
+// signature is nil if the tag is not signed with OpenPGP.
+func splitGitTag(raw []byte) (payload, signature []byte) {
+	// like git the last signature starting a line
+	i := bytes.LastIndex(raw, []byte("\n"+gitSigBegin))
+	if i < 0 {
+		return raw, nil
+	}
+	return raw[:i+1], raw[i+1:]
+}
+
+// VerifyGitObject verifies the signature of the raw git commit or tag
+// object raw, e.g. the output of `git cat-file tag v1.0`, as detached
+// signature over the object without it, like `git verify-commit` and
+// `git verify-tag` do. The signatures are resolved to their keys, see
+// ResolveSigners. A bad signature is not an error but reported in the
+// Status of the signature.
+func VerifyGitObject(raw []byte) (result GitSignatureType, err error) {
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return result, fmt.Errorf("VerifyGitObject - %w", err)
+	}
+	defer myContext.Release()
+
+	return verifyGitObject(myContext, raw)
+}
+
+// verifyGitObject implements VerifyGitObject using myContext.
+func verifyGitObject(myContext *gpgme.Context, raw []byte) (
+	result GitSignatureType, err error) {
+
+	var signatures []gpgme.Signature
+	defer func() {
+		audit(AuditVerify, "VerifyGitObject", err, func(event *AuditEvent) {
+			event.Signers = signatureFingerprints(signatures)
+			event.InputSHA256 = hashBytes(raw)
+		})
+	}()
+
+	result.Kind, result.Payload, result.Signature, err = splitGitObject(raw)
+	if err != nil {
+		return result, fmt.Errorf("VerifyGitObject - %w", err)
+	}
+
+	dataSig, err := gpgme.NewDataBytes(result.Signature)
+	if err != nil {
+		return result, fmt.Errorf("VerifyGitObject - NewData (signature) failed: %w", err)
+	}
+	defer dataSig.Close()
+
+	dataSigned, err := gpgme.NewDataBytes(result.Payload)
+	if err != nil {
+		return result, fmt.Errorf("VerifyGitObject - NewData (data) failed: %w", err)
+	}
+	defer dataSigned.Close()
+
+	_, signatures, err = myContext.Verify(dataSig, dataSigned, nil)
+	if err != nil {
+		return result, fmt.Errorf("VerifyGitObject - Verify failed: %w", err)
+	}
+
+	result.Signatures, err = resolveSigners(myContext, signatures)
+	if err != nil {
+		return result, fmt.Errorf("VerifyGitObject - %w", err)
+	}
+	return result, nil
+}
+
+// EOF
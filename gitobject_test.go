@@ -0,0 +1,108 @@
+/* gitobject_test.go - tests of the git object splitting
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"errors"
+	"testing"
+)
+
+const (
+	testGitSig = "-----BEGIN PGP SIGNATURE-----\n\niHUEABYKAB0WIQ\n=abcd\n" +
+		"-----END PGP SIGNATURE-----\n"
+	testGitCommit = "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n" +
+		"author A U Thor <author@example.com> 1700000000 +0000\n" +
+		"committer A U Thor <author@example.com> 1700000000 +0000\n"
+	testGitTag = "object 4b825dc642cb6eb9a060e54bf8d69288fbee4904\ntype commit\n" +
+		"tag v1.0\ntagger A U Thor <author@example.com> 1700000000 +0000\n\nRelease 1.0\n"
+)
+
+// gitSigHeaderValue returns the signature as value of a commit header,
+// the continuation lines start with a space.
+func gitSigHeaderValue(name string) string {
+	return name + " -----BEGIN PGP SIGNATURE-----\n \n iHUEABYKAB0WIQ\n =abcd\n" +
+		" -----END PGP SIGNATURE-----\n"
+}
+
+func TestSplitGitObject(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		kind      string
+		payload   string
+		signature string
+		wantErr   error // matched with errors.Is, if not nil
+		fails     bool
+	}{
+		{name: "commit", raw: testGitCommit + gitSigHeaderValue("gpgsig") + "\nmessage\n",
+			kind: "commit", payload: testGitCommit + "\nmessage\n", signature: testGitSig},
+		{name: "commit sha256",
+			raw:  testGitCommit + gitSigHeaderValue("gpgsig-sha256") + "\nmessage\n",
+			kind: "commit", payload: testGitCommit + "\nmessage\n", signature: testGitSig},
+		{name: "loose commit",
+			raw:  "commit 123\x00" + testGitCommit + gitSigHeaderValue("gpgsig") + "\nmessage\n",
+			kind: "commit", payload: testGitCommit + "\nmessage\n", signature: testGitSig},
+		{name: "header in message",
+			raw: testGitCommit + gitSigHeaderValue("gpgsig") + "\nmessage\n" +
+				gitSigHeaderValue("gpgsig"),
+			kind: "commit", payload: testGitCommit + "\nmessage\n" + gitSigHeaderValue("gpgsig"),
+			signature: testGitSig},
+		{name: "unsigned commit", raw: testGitCommit + "\nmessage\n", wantErr: ErrNotSigned},
+		{name: "ssh signed commit",
+			raw:     testGitCommit + "gpgsig -----BEGIN SSH SIGNATURE-----\n x\n\nmessage\n",
+			wantErr: ErrNotSigned},
+		{name: "tag", raw: testGitTag + testGitSig, kind: "tag", payload: testGitTag,
+			signature: testGitSig},
+		{name: "loose tag", raw: "tag 99\x00" + testGitTag + testGitSig, kind: "tag",
+			payload: testGitTag, signature: testGitSig},
+		{name: "unsigned tag", raw: testGitTag, wantErr: ErrNotSigned},
+		{name: "blob", raw: "hello\n", fails: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, payload, signature, err := splitGitObject([]byte(tt.raw))
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("splitGitObject error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.fails:
+				if err == nil {
+					t.Fatalf("splitGitObject = %q, want an error", kind)
+				}
+			case err != nil:
+				t.Fatal(err)
+			default:
+				if kind != tt.kind {
+					t.Errorf("kind = %q, want %q", kind, tt.kind)
+				}
+				if string(payload) != tt.payload {
+					t.Errorf("payload = %q, want %q", payload, tt.payload)
+				}
+				if string(signature) != tt.signature {
+					t.Errorf("signature = %q, want %q", signature, tt.signature)
+				}
+			}
+		})
+	}
+}
+
+// EOF
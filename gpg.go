@@ -0,0 +1,391 @@
+/* gpg.go - direct invocation of the gpg engine for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// Some GnuPG features are not (yet) available through gpgme.go.
+// For these the gpg engine configured in gpgme is called directly in
+// batch mode, and its machine readable status output is evaluated.
+
+package gpggohigh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// gpgCommand is one invocation of the gpg engine.
+type gpgCommand struct {
+	args          []string      // the command and its arguments
+	stdin         io.Reader     // data read by gpg, may be nil
+	stdout        io.Writer     // data written by gpg, may be nil
+	passphrase    string        // the passphrase used with loopback pinentry
+	hasPassphrase bool          // passphrase is only used if true
+	homeDir       string        // overrides the home directory of the engine
+	timeout       time.Duration // kills gpg after this time, 0 for no limit
+	retry         *RetryPolicy  // overrides the policy of SetRetryPolicy
+
+	// fdOption is an option of gpg reading fdData from a file
+	// descriptor, e.g. --override-session-key-fd, so secrets do not show
+	// up in the process list.
+	fdOption string
+	fdData   string
+
+	// interact answers the GET_BOOL, GET_LINE and GET_HIDDEN prompts of
+	// gpg, which reads the answers with --command-fd. An error kills gpg
+	// and is returned by run.
+	interact func(prompt gpgStatus) (answer string, err error)
+}
+
+// ErrKeyNotFound is matched by errors.Is for a GpgError reporting a
+// missing public or secret key.
+var ErrKeyNotFound = errors.New("key not found")
+
+// GpgError is returned, if an operation which calls the gpg engine
+// directly fails.
+// errors.Is matches ErrKeyNotFound and ErrBadPassphrase by the code.
+type GpgError struct {
+	Command string          // the gpg command, e.g. "--quick-set-expire"
+	Code    gpgme.ErrorCode // the error code reported by gpg, 0 if unknown
+	Message string          // description of the failure, may be empty
+	Err     error           // the error of running gpg
+}
+
+func (e *GpgError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("gpg %s failed: %v: %s", e.Command, e.Err, e.Message)
+	}
+	return fmt.Sprintf("gpg %s failed: %v", e.Command, e.Err)
+}
+
+func (e *GpgError) Unwrap() error {
+	return e.Err
+}
+
+// Is maps the error code to the errors of this package.
+func (e *GpgError) Is(target error) bool {
+	switch e.Code {
+	case errCodeNoPubkey, errCodeNoSeckey, errCodeNotFound:
+		return target == ErrKeyNotFound
+	case errCodeBadPassphrase:
+		return target == ErrBadPassphrase
+	}
+	return false
+}
+
+// gpgStatus is a line of the gpg status output without the
+// "[GNUPG:] " prefix, split into keyword and arguments.
+type gpgStatus struct {
+	Keyword string
+	Args    []string
+	Text    string // the arguments as written by gpg
+}
+
+// gpgEngine returns the file name and the home directory of the
+// OpenPGP engine as configured in gpgme.
+func gpgEngine() (fileName, homeDir string) {
+	fileName, homeDir = engineInfo(gpgme.ProtocolOpenPGP)
+	if fileName == "" {
+		fileName = gpgme.GetDirInfo("gpg-name")
+	}
+	if fileName == "" {
+		fileName = "gpg"
+	}
+	return fileName, homeDir
+}
+
+// engineInfo returns the file name and the home directory of the engine
+// for the protocol proto as configured in gpgme. Both are empty, if
+// gpgme does not know the engine.
+func engineInfo(proto gpgme.Protocol) (fileName, homeDir string) {
+	info, err := gpgme.GetEngineInfo()
+	if err != nil {
+		return "", ""
+	}
+	for ; info != nil; info = info.Next() {
+		if info.Protocol() == proto {
+			return info.FileName(), info.HomeDir()
+		}
+	}
+	return "", ""
+}
+
+// run executes the gpg command and returns the status lines.
+// The status output is written to file descriptor 3 and the
+// passphrase, if any, is read from file descriptor 4. The descriptors of
+// fdOption and of the answers to the prompts follow.
+// Transient errors are retried with the retry policy, unless the command
+// interacts with gpg, its input can not be read again or gpg has already
+// written output.
+func (c gpgCommand) run() (status []gpgStatus, err error) {
+	policy := currentRetryPolicy(c.retry)
+	if policy == nil || policy.MaxAttempts <= 1 || c.interact != nil {
+		return c.runOnce()
+	}
+	seeker, seekable := c.stdin.(io.Seeker)
+	if c.stdin != nil && !seekable {
+		return c.runOnce()
+	}
+	var start int64
+	if seekable {
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			return c.runOnce()
+		}
+	}
+	var written writeCounter
+	if c.stdout != nil {
+		written.w = c.stdout
+		c.stdout = &written
+	}
+
+	replay := func() bool {
+		if written.n > 0 {
+			return false
+		}
+		if seekable {
+			_, err := seeker.Seek(start, io.SeekStart)
+			return err == nil
+		}
+		return true
+	}
+	err = policy.retry(context.Background(), c.homeDir, func() (err error) {
+		status, err = c.runOnce()
+		return err
+	}, replay)
+	return status, err
+}
+
+// writeCounter counts the bytes written to w.
+type writeCounter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *writeCounter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// runOnce executes the gpg command once, see run.
+func (c gpgCommand) runOnce() (status []gpgStatus, err error) {
+	fileName, homeDir := gpgEngine()
+	if c.homeDir != "" {
+		homeDir = c.homeDir
+	}
+
+	args := []string{"--batch", "--no-tty", "--status-fd", "3"}
+	if homeDir != "" {
+		args = append(args, "--homedir", homeDir)
+	}
+
+	statusR, statusW, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("status pipe failed: %w", err)
+	}
+	defer statusR.Close()
+	extraFiles := []*os.File{statusW}
+
+	var passR, passW *os.File
+	if c.hasPassphrase {
+		passR, passW, err = os.Pipe()
+		if err != nil {
+			statusW.Close()
+			return nil, fmt.Errorf("passphrase pipe failed: %w", err)
+		}
+		defer passR.Close()
+		extraFiles = append(extraFiles, passR)
+		args = append(args, "--pinentry-mode", "loopback", "--passphrase-fd", "4")
+	}
+
+	var fdR, fdW *os.File
+	if c.fdOption != "" {
+		fdR, fdW, err = os.Pipe()
+		if err != nil {
+			statusW.Close()
+			if passW != nil {
+				passW.Close()
+			}
+			return nil, fmt.Errorf("%s pipe failed: %w", c.fdOption, err)
+		}
+		defer fdR.Close()
+		extraFiles = append(extraFiles, fdR)
+		// the extra files start with file descriptor 3
+		args = append(args, c.fdOption, strconv.Itoa(2+len(extraFiles)))
+	}
+
+	var commandR, commandW *os.File
+	if c.interact != nil {
+		commandR, commandW, err = os.Pipe()
+		if err != nil {
+			statusW.Close()
+			if passW != nil {
+				passW.Close()
+			}
+			if fdW != nil {
+				fdW.Close()
+			}
+			return nil, fmt.Errorf("command pipe failed: %w", err)
+		}
+		defer commandR.Close()
+		defer commandW.Close()
+		extraFiles = append(extraFiles, commandR)
+		args = append(args, "--command-fd", strconv.Itoa(2+len(extraFiles)))
+	}
+	args = append(args, c.args...)
+	logDebug("running gpg", "file", fileName, "args", args)
+
+	// cancel kills gpg, if a prompt can not be answered
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if c.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, fileName, args...)
+	cmd.Stdin = c.stdin
+	cmd.Stdout = c.stdout
+	cmd.Stderr = &stderr
+	cmd.ExtraFiles = extraFiles
+
+	err = cmd.Start()
+	statusW.Close() // only the child writes to the status pipe
+	if err != nil {
+		if passW != nil {
+			passW.Close()
+		}
+		if fdW != nil {
+			fdW.Close()
+		}
+		return nil, fmt.Errorf("starting %s failed: %w", fileName, err)
+	}
+
+	if passW != nil {
+		go func() {
+			_, _ = passW.WriteString(c.passphrase + "\n")
+			passW.Close()
+		}()
+	}
+	if fdW != nil {
+		go func() {
+			_, _ = fdW.WriteString(c.fdData + "\n")
+			fdW.Close()
+		}()
+	}
+
+	var interactErr error
+	scanner := bufio.NewScanner(statusR)
+	for scanner.Scan() {
+		line, found := strings.CutPrefix(scanner.Text(), "[GNUPG:] ")
+		if !found {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		_, text, _ := strings.Cut(line, " ")
+		status = append(status, gpgStatus{Keyword: fields[0], Args: fields[1:],
+			Text: text})
+		// only the keyword, the arguments may be secret like SESSION_KEY
+		logTrace("gpg status", "keyword", fields[0])
+
+		if c.interact != nil && interactErr == nil && strings.HasPrefix(fields[0], "GET_") {
+			answer, err := c.interact(status[len(status)-1])
+			if err == nil {
+				_, err = commandW.WriteString(answer + "\n")
+			}
+			if err != nil {
+				interactErr = err
+				cancel()
+			}
+		}
+	}
+
+	err = cmd.Wait()
+	switch {
+	case interactErr != nil:
+		err = interactErr
+	case err != nil && ctx.Err() != nil:
+		// killed after the timeout
+		err = ctx.Err()
+	}
+	if err != nil {
+		logDebug("gpg failed", "error", err, "stderr", stderr.String())
+		return status, &GpgError{
+			Command: c.args[0],
+			Code:    gpgErrorCode(status),
+			Message: gpgErrorMessage(status, stderr.String()),
+			Err:     err,
+		}
+	}
+	return status, nil
+}
+
+// gpgErrorCode returns the error code of the first FAILURE or ERROR
+// status line, or 0 if there is none. The lines contain a location and
+// the gpg-error value, which combines the error source and code.
+func gpgErrorCode(status []gpgStatus) gpgme.ErrorCode {
+	for _, s := range status {
+		if (s.Keyword == "FAILURE" || s.Keyword == "ERROR") && len(s.Args) > 1 {
+			value, err := strconv.ParseUint(s.Args[1], 10, 32)
+			if err == nil {
+				return gpgme.ErrorCode(value & errCodeMask)
+			}
+		}
+	}
+	return 0
+}
+
+// gpgErrorMessage returns a short description of the failure, preferring
+// the FAILURE and ERROR status lines over the last line of stderr.
+func gpgErrorMessage(status []gpgStatus, stderr string) string {
+	for _, s := range status {
+		if (s.Keyword == "FAILURE" || s.Keyword == "ERROR") && len(s.Args) > 0 {
+			return strings.Join(s.Args, " ")
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// findStatus returns the arguments of the first status line with the
+// given keyword and true, or nil and false if there is none.
+func findStatus(status []gpgStatus, keyword string) ([]string, bool) {
+	for _, s := range status {
+		if s.Keyword == keyword {
+			return s.Args, true
+		}
+	}
+	return nil, false
+}
+
+// EOF
@@ -0,0 +1,84 @@
+/* gpg_test.go - tests of the gpg status parsing
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// statusLines returns the status lines as run parses them, each line
+// without the "[GNUPG:] " prefix.
+func statusLines(lines ...string) (status []gpgStatus) {
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		_, text, _ := strings.Cut(line, " ")
+		status = append(status, gpgStatus{Keyword: fields[0], Args: fields[1:],
+			Text: text})
+	}
+	return status
+}
+
+func TestGpgErrorCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		status []gpgStatus
+		want   gpgme.ErrorCode
+	}{
+		{"none", statusLines("NEWSIG"), 0},
+		{"failure", statusLines("FAILURE gpg-exit 33554433"), 1},
+		{"source masked", statusLines("ERROR keyedit.passwd 117440523"), 11},
+		{"first wins", statusLines("ERROR a 17", "FAILURE b 9"), 17},
+		{"not a number", statusLines("FAILURE a b"), 0},
+		{"no code", statusLines("FAILURE a"), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gpgErrorCode(tt.status); got != tt.want {
+				t.Errorf("gpgErrorCode = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGpgErrorMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		status []gpgStatus
+		stderr string
+		want   string
+	}{
+		{"status", statusLines("FAILURE sign 17"), "gpg: failed\n", "sign 17"},
+		{"last stderr line", nil, "gpg: first\ngpg: last\n\n", "gpg: last"},
+		{"empty", nil, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gpgErrorMessage(tt.status, tt.stderr); got != tt.want {
+				t.Errorf("gpgErrorMessage = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// EOF
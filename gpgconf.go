@@ -0,0 +1,76 @@
+/* gpgconf.go - access to the gpgconf tool for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// gpgconfName returns the file name of the gpgconf tool as known by gpgme.
+// If gpgme does not know it, the tool is looked up in the PATH.
+func gpgconfName() string {
+	name := gpgme.GetDirInfo("gpgconf-name")
+	if name == "" {
+		name = "gpgconf"
+	}
+	return name
+}
+
+// runGpgconf runs gpgconf with the given arguments and returns its
+// standard output. The error contains the standard error output of gpgconf.
+// A home directory set with SetHomeDir is passed on to gpgconf.
+func runGpgconf(args ...string) ([]byte, error) {
+	_, homeDir := gpgEngine()
+	return runGpgconfHome(homeDir, args...)
+}
+
+// runGpgconfHome runs gpgconf like runGpgconf for the GnuPG home
+// directory homeDir. An empty homeDir is the default home directory.
+func runGpgconfHome(homeDir string, args ...string) ([]byte, error) {
+	cmdArgs := args
+	if homeDir != "" {
+		cmdArgs = append([]string{"--homedir", homeDir}, args...)
+	}
+
+	logDebug("running gpgconf", "args", cmdArgs)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(gpgconfName(), cmdArgs...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg != "" {
+			return stdout.Bytes(), fmt.Errorf("gpgconf %s failed: %w: %s",
+				strings.Join(args, " "), err, msg)
+		}
+		return stdout.Bytes(), fmt.Errorf("gpgconf %s failed: %w",
+			strings.Join(args, " "), err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// EOF
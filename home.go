@@ -0,0 +1,95 @@
+/* home.go - GnuPG home directory handling for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// SetHomeDir sets the GnuPG home directory used by all following
+// operations instead of the default (usually ~/.gnupg), e.g. to work
+// with an application specific keyring.
+// An empty dir restores the default home directory.
+// The home directory is shared by the OpenPGP (gpg) and the CMS (gpgsm)
+// engine.
+// A Session uses its own home directory if SessionOptions.HomeDir is set.
+func SetHomeDir(dir string) error {
+	for _, proto := range []gpgme.Protocol{gpgme.ProtocolOpenPGP, gpgme.ProtocolCMS} {
+		fileName, _ := engineInfo(proto)
+		err := gpgme.SetEngineInfo(proto, fileName, dir)
+		if err != nil {
+			return fmt.Errorf("SetHomeDir - SetEngineInfo failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// HomeDir returns the GnuPG home directory used by the operations.
+// An empty string means the default home directory of gpg.
+func HomeDir() string {
+	_, homeDir := gpgEngine()
+	return homeDir
+}
+
+// NewEphemeralHome creates a temporary GnuPG home directory, imports the
+// given keys into it and returns a Session bound to it.
+// Close on the session kills the daemons started for the home directory,
+// like gpg-agent, and removes the directory.
+// This allows hermetic tests and stateless services, e.g. verifying
+// signatures against a fixed set of keys.
+func NewEphemeralHome(keys ...[]byte) (session *Session, err error) {
+	homeDir, err := os.MkdirTemp("", "gpggohigh-")
+	if err != nil {
+		return nil, fmt.Errorf("NewEphemeralHome - MkdirTemp failed: %w", err)
+	}
+	removeHome := func() error {
+		// a failed kill should not leave the directory behind
+		killErr := killDaemons(homeDir)
+		return errors.Join(killErr, os.RemoveAll(homeDir))
+	}
+
+	session, err = NewSession(SessionOptions{HomeDir: homeDir})
+	if err != nil {
+		_ = removeHome()
+		return nil, fmt.Errorf("NewEphemeralHome - %w", err)
+	}
+	session.cleanup = removeHome
+
+	for i, key := range keys {
+		_, err = session.ImportKeys(key)
+		if err != nil {
+			_ = session.Close()
+			return nil, fmt.Errorf("NewEphemeralHome - key %d: %w", i, err)
+		}
+	}
+	return session, nil
+}
+
+// killDaemons stops all GnuPG daemons running for the home directory homeDir.
+func killDaemons(homeDir string) error {
+	return controlDaemon(homeDir, "--kill", DaemonAll)
+}
+
+// EOF
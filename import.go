@@ -0,0 +1,159 @@
+/* import.go - high-level key import for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// ImportKeys imports the keys contained in keyData, binary or ASCII
+// armored, into the keyring.
+// The result contains the counters of gpg (imported, unchanged, secret
+// keys, ...) and the status of each considered key.
+func ImportKeys(keyData []byte) (result *gpgme.ImportResult, err error) {
+	err = withRetry(context.Background(), nil, "", func() error {
+		dataIn, err := gpgme.NewDataBytes(keyData)
+		if err != nil {
+			return fmt.Errorf("NewData (in) failed: %w", err)
+		}
+		defer dataIn.Close()
+
+		result, err = importData(dataIn)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ImportKeys - %w", err)
+	}
+	return result, nil
+}
+
+// ImportKeyFile imports the keys contained in the file filename into
+// the keyring. See ImportKeys for the result.
+func ImportKeyFile(filename string) (result *gpgme.ImportResult, err error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("ImportKeyFile - Open failed: %w", err)
+	}
+	defer fh.Close()
+
+	dataIn, err := gpgme.NewDataFile(fh)
+	if err != nil {
+		return nil, fmt.Errorf("ImportKeyFile - NewData (in) failed: %w", err)
+	}
+	defer dataIn.Close()
+
+	result, err = importData(dataIn)
+	if err != nil {
+		return nil, fmt.Errorf("ImportKeyFile - %w", err)
+	}
+	return result, nil
+}
+
+// ImportKeysReader imports the keys read from r into the keyring.
+// See ImportKeys for the result.
+func ImportKeysReader(r io.Reader) (result *gpgme.ImportResult, err error) {
+	dataIn, err := gpgme.NewDataReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("ImportKeysReader - NewData (in) failed: %w", err)
+	}
+	defer dataIn.Close()
+
+	result, err = importData(dataIn)
+	if err != nil {
+		return nil, fmt.Errorf("ImportKeysReader - %w", err)
+	}
+	return result, nil
+}
+
+// importData imports the keys from a gpgme data object with a new
+// default context.
+func importData(dataIn *gpgme.Data) (*gpgme.ImportResult, error) {
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer myContext.Release()
+
+	return importDataWith(myContext, dataIn)
+}
+
+// importDataWith imports the keys from a gpgme data object using myContext.
+func importDataWith(myContext *gpgme.Context, dataIn *gpgme.Data) (
+	*gpgme.ImportResult, error) {
+
+	result, err := myContext.Import(dataIn)
+	audit(AuditImport, "ImportKeys", err, func(event *AuditEvent) {
+		if result != nil {
+			event.Keys = ImportedFingerprints(result)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Import failed: %w", err)
+	}
+	return result, nil
+}
+
+// ImportedFingerprints returns the fingerprints of all keys of an import
+// result which were imported without error, including unchanged keys.
+func ImportedFingerprints(result *gpgme.ImportResult) (fingerprints []string) {
+	if result == nil {
+		return nil
+	}
+	for _, i := range result.Imports {
+		if i.Result == nil {
+			fingerprints = append(fingerprints, i.Fingerprint)
+		}
+	}
+	return fingerprints
+}
+
+// ImportStatusString returns a readable description of the import
+// status flags, e.g. "new,secret". A key without changes is "unchanged".
+func ImportStatusString(s gpgme.ImportStatusFlags) string {
+	var flags []string
+	if s&gpgme.ImportNew != 0 {
+		flags = append(flags, "new")
+	}
+	if s&gpgme.ImportUID != 0 {
+		flags = append(flags, "uid")
+	}
+	if s&gpgme.ImportSIG != 0 {
+		flags = append(flags, "sig")
+	}
+	if s&gpgme.ImportSubKey != 0 {
+		flags = append(flags, "subkey")
+	}
+	if s&gpgme.ImportSecret != 0 {
+		flags = append(flags, "secret")
+	}
+	if len(flags) == 0 {
+		return "unchanged"
+	}
+	return strings.Join(flags, ",")
+}
+
+// EOF
@@ -0,0 +1,374 @@
+/* json.go - JSON output of keys and results for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// The JSON types use the names of gpgme-json where possible and string
+// values instead of the numbers of the gpgme enums, so the output can be
+// used without knowing gpgme.
+
+package gpggohigh
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// KeyJSONType is the JSON representation of a KeyType.
+type KeyJSONType struct {
+	Fingerprint     string           `json:"fingerprint"`
+	KeyID           string           `json:"keyid,omitempty"`
+	Protocol        string           `json:"protocol"`
+	PubkeyAlgo      string           `json:"pubkey_algo,omitempty"`
+	Length          int              `json:"length,omitempty"`
+	Curve           string           `json:"curve,omitempty"`
+	Created         *time.Time       `json:"created,omitempty"`
+	Expires         *time.Time       `json:"expires,omitempty"`
+	OwnerTrust      string           `json:"owner_trust"`
+	CanAuthenticate bool             `json:"can_authenticate"`
+	CanCertify      bool             `json:"can_certify"`
+	CanEncrypt      bool             `json:"can_encrypt"`
+	CanSign         bool             `json:"can_sign"`
+	Disabled        bool             `json:"disabled"`
+	Expired         bool             `json:"expired"`
+	Invalid         bool             `json:"invalid"`
+	Revoked         bool             `json:"revoked"`
+	Secret          bool             `json:"secret"`
+	IsQualified     bool             `json:"is_qualified"`
+	ChainID         string           `json:"chain_id,omitempty"`
+	IssuerName      string           `json:"issuer_name,omitempty"`
+	IssuerSerial    string           `json:"issuer_serial,omitempty"`
+	SubKeys         []SubKeyJSONType `json:"subkeys,omitempty"`
+	UserIDs         []UserIDJSONType `json:"userids,omitempty"`
+}
+
+// SubKeyJSONType is the JSON representation of a SubKeyType.
+type SubKeyJSONType struct {
+	Fingerprint  string     `json:"fingerprint"`
+	KeyID        string     `json:"keyid"`
+	Keygrip      string     `json:"keygrip,omitempty"`
+	CardNumber   string     `json:"card_number,omitempty"`
+	PubkeyAlgo   string     `json:"pubkey_algo,omitempty"`
+	Length       int        `json:"length,omitempty"`
+	Curve        string     `json:"curve,omitempty"`
+	Capabilities string     `json:"capabilities,omitempty"`
+	Created      *time.Time `json:"created,omitempty"`
+	Expires      *time.Time `json:"expires,omitempty"`
+	Revoked      bool       `json:"revoked"`
+	Expired      bool       `json:"expired"`
+	Disabled     bool       `json:"disabled"`
+	Invalid      bool       `json:"invalid"`
+	Secret       bool       `json:"secret"`
+}
+
+// UserIDJSONType is the JSON representation of a KeyUserIDsType.
+type UserIDJSONType struct {
+	UserID     string            `json:"uid"`
+	Name       string            `json:"name,omitempty"`
+	Comment    string            `json:"comment,omitempty"`
+	Address    string            `json:"address,omitempty"`
+	Validity   string            `json:"validity"`
+	Invalid    bool              `json:"invalid"`
+	Revoked    bool              `json:"revoked"`
+	Signatures []KeySigJSONType  `json:"signatures,omitempty"`
+	Tofu       *TofuInfoJSONType `json:"tofu,omitempty"`
+}
+
+// KeySigJSONType is the JSON representation of a
+// KeyUidIssuerSignatureType.
+type KeySigJSONType struct {
+	IssuerKeyID string             `json:"keyid"`
+	UID         string             `json:"uid,omitempty"`
+	Created     *time.Time         `json:"created,omitempty"`
+	Expires     *time.Time         `json:"expires,omitempty"`
+	Revoked     bool               `json:"revoked"`
+	Expired     bool               `json:"expired"`
+	Invalid     bool               `json:"invalid"`
+	Exportable  bool               `json:"exportable"`
+	TrustScope  string             `json:"trust_scope,omitempty"`
+	Notations   []NotationJSONType `json:"notations,omitempty"`
+}
+
+// NotationJSONType is the JSON representation of a NotationType.
+type NotationJSONType struct {
+	Name          string `json:"name"`
+	Value         string `json:"value"`
+	Critical      bool   `json:"critical"`
+	HumanReadable bool   `json:"human_readable"`
+}
+
+// TofuInfoJSONType is the JSON representation of a TofuInfoType.
+type TofuInfoJSONType struct {
+	Validity  int        `json:"validity"`
+	Policy    string     `json:"policy"`
+	SignCount int        `json:"signcount"`
+	EncrCount int        `json:"encrcount"`
+	SignFirst *time.Time `json:"signfirst,omitempty"`
+	SignLast  *time.Time `json:"signlast,omitempty"`
+	EncrFirst *time.Time `json:"encrfirst,omitempty"`
+	EncrLast  *time.Time `json:"encrlast,omitempty"`
+}
+
+// SignatureJSONType is the JSON representation of a gpgme.Signature.
+type SignatureJSONType struct {
+	Fingerprint    string     `json:"fingerprint"`
+	Status         string     `json:"status"`
+	Classification string     `json:"classification"` // see ClassifySignature
+	Summary        []string   `json:"summary"`
+	Timestamp      *time.Time `json:"timestamp,omitempty"`
+	ExpTimestamp   *time.Time `json:"exp_timestamp,omitempty"`
+	WrongKeyUsage  bool       `json:"wrong_key_usage"`
+	ChainModel     bool       `json:"chain_model"`
+	Validity       string     `json:"validity"`
+	ValidityReason string     `json:"validity_reason,omitempty"`
+	PubkeyAlgo     string     `json:"pubkey_algo"`
+	HashAlgo       string     `json:"hash_algo"`
+	Signer         string     `json:"signer,omitempty"` // primary user ID of the signing key
+}
+
+// DecryptResultJSONType is the JSON representation of a
+// gpgme.DecryptResultType. The session key is left out on purpose.
+type DecryptResultJSONType struct {
+	Filename             string                     `json:"file_name,omitempty"`
+	SymkeyAlgo           string                     `json:"symkey_algo,omitempty"`
+	UnsupportedAlgorithm string                     `json:"unsupported_algorithm,omitempty"`
+	WrongKeyUsage        bool                       `json:"wrong_key_usage"`
+	LegacyCipherNoMDC    bool                       `json:"legacy_cipher_nomdc"`
+	IsMIME               bool                       `json:"is_mime"`
+	IsDEVS               bool                       `json:"is_de_vs"`
+	Recipients           []DecryptRecipientJSONType `json:"recipients,omitempty"`
+}
+
+// DecryptRecipientJSONType is the JSON representation of a
+// gpgme.DecryptRecipient.
+type DecryptRecipientJSONType struct {
+	KeyID      string `json:"keyid"`
+	PubkeyAlgo string `json:"pubkey_algo"`
+	Status     string `json:"status"`
+}
+
+// NewKeyJSON converts a key to its JSON representation.
+func NewKeyJSON(key KeyType) (k KeyJSONType) {
+	k = KeyJSONType{
+		Fingerprint:     key.Fingerprint,
+		KeyID:           key.KeyID,
+		Protocol:        protocolName(key.Protocol),
+		PubkeyAlgo:      key.PubkeyAlgo,
+		Length:          key.Length,
+		Curve:           key.Curve,
+		Created:         jsonTime(key.Created),
+		Expires:         jsonTime(key.Expires),
+		OwnerTrust:      GnuPGValidity2String(key.OwnerTrust),
+		CanAuthenticate: key.CanAuthenticate,
+		CanCertify:      key.CanCertify,
+		CanEncrypt:      key.CanEncrypt,
+		CanSign:         key.CanSign,
+		Disabled:        key.Disabled,
+		Expired:         key.Expired,
+		Invalid:         key.Invalid,
+		Revoked:         key.Revoked,
+		Secret:          key.Secret,
+		IsQualified:     key.IsQualified,
+		ChainID:         key.ChainID,
+		IssuerName:      key.IssuerName,
+		IssuerSerial:    key.IssuerSerial,
+	}
+	for _, sk := range key.SubKeys {
+		k.SubKeys = append(k.SubKeys, SubKeyJSONType{
+			Fingerprint:  sk.Fingerprint,
+			KeyID:        sk.KeyID,
+			Keygrip:      sk.Keygrip,
+			CardNumber:   sk.CardNumber,
+			PubkeyAlgo:   sk.PubkeyAlgo,
+			Length:       sk.Length,
+			Curve:        sk.Curve,
+			Capabilities: sk.Capabilities,
+			Created:      jsonTime(sk.Created),
+			Expires:      jsonTime(sk.Expires),
+			Revoked:      sk.Revoked,
+			Expired:      sk.Expired,
+			Disabled:     sk.Disabled,
+			Invalid:      sk.Invalid,
+			Secret:       sk.Secret,
+		})
+	}
+	for _, uid := range key.UserIDs {
+		k.UserIDs = append(k.UserIDs, newUserIDJSON(uid))
+	}
+	return k
+}
+
+// newUserIDJSON converts a user ID to its JSON representation.
+func newUserIDJSON(uid KeyUserIDsType) (u UserIDJSONType) {
+	u = UserIDJSONType{
+		UserID:   uid.UserID,
+		Name:     uid.Name,
+		Comment:  uid.Comment,
+		Address:  uid.Address,
+		Validity: GnuPGValidity2String(uid.Validity),
+		Invalid:  uid.Invalid,
+		Revoked:  uid.Revoked,
+	}
+	for _, sigs := range uid.Signatures {
+		for _, sig := range sigs {
+			s := KeySigJSONType{
+				IssuerKeyID: sig.IssuerKeyID,
+				UID:         sig.UID,
+				Created:     jsonTime(sig.CreationTime),
+				Revoked:     sig.Revoked,
+				Expired:     sig.Expired,
+				Invalid:     sig.Invalid,
+				Exportable:  sig.Exportable,
+				TrustScope:  sig.TrustScope,
+			}
+			if sig.Expires {
+				s.Expires = jsonTime(sig.ExpirationTime)
+			}
+			for _, n := range sig.Notations {
+				s.Notations = append(s.Notations, NotationJSONType(n))
+			}
+			u.Signatures = append(u.Signatures, s)
+		}
+	}
+	if uid.Tofu != nil {
+		u.Tofu = &TofuInfoJSONType{
+			Validity:  uid.Tofu.Validity,
+			Policy:    string(uid.Tofu.Policy),
+			SignCount: uid.Tofu.SignCount,
+			EncrCount: uid.Tofu.EncrCount,
+			SignFirst: jsonTime(uid.Tofu.SignFirst),
+			SignLast:  jsonTime(uid.Tofu.SignLast),
+			EncrFirst: jsonTime(uid.Tofu.EncrFirst),
+			EncrLast:  jsonTime(uid.Tofu.EncrLast),
+		}
+	}
+	return u
+}
+
+// NewSignatureJSON converts a verified signature to its JSON
+// representation. The status is "good" for a good signature and the
+// error text otherwise.
+func NewSignatureJSON(sig gpgme.Signature) SignatureJSONType {
+	return SignatureJSONType{
+		Fingerprint:    sig.Fingerprint,
+		Status:         CondErrStr(sig.Status, "good"),
+		Classification: ClassifySignature(sig).String(),
+		Summary:        SigSumToStrings(sig.Summary),
+		Timestamp:      jsonTime(sig.Timestamp),
+		ExpTimestamp:   jsonTime(sig.ExpTimestamp),
+		WrongKeyUsage:  sig.WrongKeyUsage,
+		ChainModel:     sig.ChainModel,
+		Validity:       GnuPGValidity2String(sig.Validity),
+		ValidityReason: CondErrStr(sig.ValidityReason, ""),
+		PubkeyAlgo:     gpgme.PubkeyAlgoName(sig.PubkeyAlgo),
+		HashAlgo:       gpgme.HashAlgoName(sig.HashAlgo),
+	}
+}
+
+// NewResolvedSignatureJSON converts a signature with its signing key, see
+// ResolveSigners, to its JSON representation, which names the signer.
+func NewResolvedSignatureJSON(sig ResolvedSignature) SignatureJSONType {
+	s := NewSignatureJSON(sig.Signature)
+	s.Signer = sig.UserID
+	return s
+}
+
+// NewDecryptResultJSON converts a decryption result to its JSON
+// representation.
+func NewDecryptResultJSON(result gpgme.DecryptResultType) (r DecryptResultJSONType) {
+	r = DecryptResultJSONType{
+		Filename:             result.Filename,
+		SymkeyAlgo:           result.SymkeyAlgo,
+		UnsupportedAlgorithm: result.UnsupportedAlgorithm,
+		WrongKeyUsage:        result.WrongKeyUsage,
+		LegacyCipherNoMDC:    result.LegacyCipherNoMDC,
+		IsMIME:               result.IsMIME,
+		IsDEVS:               result.IsDEVS,
+	}
+	for _, rcp := range result.Recipients {
+		r.Recipients = append(r.Recipients, DecryptRecipientJSONType{
+			KeyID:      rcp.KeyID,
+			PubkeyAlgo: gpgme.PubkeyAlgoName(rcp.PubkeyAlgo),
+			Status:     CondErrStr(rcp.Status, "ok"),
+		})
+	}
+	return r
+}
+
+// KeysToJSON returns the keys as JSON array, see KeyJSONType.
+func KeysToJSON(keys []KeyType) ([]byte, error) {
+	out := make([]KeyJSONType, len(keys))
+	for i, k := range keys {
+		out[i] = NewKeyJSON(k)
+	}
+	return json.Marshal(out)
+}
+
+// VerificationToJSON returns the result of a verification as JSON object
+// with the embedded file name and the signatures, see SignatureJSONType.
+func VerificationToJSON(filename string, signatures []gpgme.Signature) ([]byte, error) {
+	return json.Marshal(struct {
+		Filename   string              `json:"file_name,omitempty"`
+		Signatures []SignatureJSONType `json:"signatures"`
+	}{filename, newSignaturesJSON(signatures)})
+}
+
+// DecryptionToJSON returns the result of a decryption and the
+// verification of the contained signatures as JSON object, see
+// DecryptResultJSONType.
+func DecryptionToJSON(result gpgme.DecryptResultType,
+	signatures []gpgme.Signature) ([]byte, error) {
+	return json.Marshal(struct {
+		Decryption DecryptResultJSONType `json:"decryption"`
+		Signatures []SignatureJSONType   `json:"signatures"`
+	}{NewDecryptResultJSON(result), newSignaturesJSON(signatures)})
+}
+
+// newSignaturesJSON converts signatures to their JSON representation,
+// never nil, so the JSON array is empty instead of null.
+func newSignaturesJSON(signatures []gpgme.Signature) []SignatureJSONType {
+	out := make([]SignatureJSONType, len(signatures))
+	for i, sig := range signatures {
+		out[i] = NewSignatureJSON(sig)
+	}
+	return out
+}
+
+// protocolName returns the name of a protocol as used by gpgme.
+func protocolName(p gpgme.Protocol) string {
+	switch p {
+	case gpgme.ProtocolOpenPGP:
+		return "OpenPGP"
+	case gpgme.ProtocolCMS:
+		return "CMS"
+	}
+	return "unknown"
+}
+
+// jsonTime returns a pointer to t, nil for the zero time, so it is
+// omitted in the JSON output.
+func jsonTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// EOF
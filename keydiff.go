@@ -0,0 +1,144 @@
+/* keydiff.go - differences between two key listings
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// KeyDiffType is the difference between two key listings, see DiffKeys.
+type KeyDiffType struct {
+	Added   []KeyType       // the keys only in the second listing
+	Removed []KeyType       // the keys only in the first listing
+	Changed []KeyChangeType // the keys in both listings which differ
+}
+
+// CertificationType is a signature on a user ID by another key.
+type CertificationType struct {
+	UserID      string
+	IssuerKeyID string
+	Created     time.Time
+	Revocation  bool // the signature revokes an earlier certification
+}
+
+// DiffKeys compares the key listings a and b, e.g. of a keyring before
+// and after a refresh, and reports the added and removed keys and the
+// changes of the keys in both, sorted by fingerprint. The keys should be
+// listed with their signatures, e.g. with KeyList, to find new
+// certifications.
+func DiffKeys(a, b []KeyType) (diff KeyDiffType) {
+	before := make(map[string]KeyType, len(a))
+	for _, key := range a {
+		before[key.Fingerprint] = key
+	}
+	after := make(map[string]KeyType, len(b))
+	for _, key := range b {
+		after[key.Fingerprint] = key
+	}
+
+	for fpr, key := range before {
+		if _, found := after[fpr]; !found {
+			diff.Removed = append(diff.Removed, key)
+		}
+	}
+	for fpr, key := range after {
+		old, found := before[fpr]
+		if !found {
+			diff.Added = append(diff.Added, key)
+			continue
+		}
+		if change, changed := keyChange(old, key); changed {
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+
+	byFingerprint := func(x, y KeyType) int { return cmp.Compare(x.Fingerprint, y.Fingerprint) }
+	slices.SortFunc(diff.Added, byFingerprint)
+	slices.SortFunc(diff.Removed, byFingerprint)
+	slices.SortFunc(diff.Changed, func(x, y KeyChangeType) int {
+		return cmp.Compare(x.Fingerprint, y.Fingerprint)
+	})
+	return diff
+}
+
+// DiffKeyrings compares the public keys of the GnuPG home directories
+// homeA and homeB like DiffKeys. An empty home directory is the one of
+// SetHomeDir.
+func DiffKeyrings(homeA, homeB string) (diff KeyDiffType, err error) {
+	a, err := homeKeyList(homeA)
+	if err != nil {
+		return diff, fmt.Errorf("DiffKeyrings - %s: %w", homeA, err)
+	}
+	b, err := homeKeyList(homeB)
+	if err != nil {
+		return diff, fmt.Errorf("DiffKeyrings - %s: %w", homeB, err)
+	}
+	return DiffKeys(a, b), nil
+}
+
+// homeKeyList lists all public keys of the home directory homeDir with
+// their signatures.
+func homeKeyList(homeDir string) ([]KeyType, error) {
+	myContext, err := newContext(SessionOptions{HomeDir: homeDir})
+	if err != nil {
+		return nil, err
+	}
+	defer myContext.Release()
+
+	return keyList(context.Background(), myContext, "")
+}
+
+// newCertifications returns the signatures of other keys than keyID on
+// the user ID uid, which are not on oldUID.
+func newCertifications(keyID string, oldUID, uid KeyUserIDsType) (
+	certifications []CertificationType) {
+
+	issuers := make([]string, 0, len(uid.Signatures))
+	for issuer := range uid.Signatures {
+		if issuer != keyID {
+			issuers = append(issuers, issuer)
+		}
+	}
+	slices.Sort(issuers)
+	for _, issuer := range issuers {
+		for _, sig := range uid.Signatures[issuer] {
+			known := slices.ContainsFunc(oldUID.Signatures[issuer],
+				func(old KeyUidIssuerSignatureType) bool {
+					return old.Revoked == sig.Revoked && old.CreationTime.Equal(sig.CreationTime)
+				})
+			if !known {
+				certifications = append(certifications, CertificationType{
+					UserID:      uid.UserID,
+					IssuerKeyID: issuer,
+					Created:     sig.CreationTime,
+					Revocation:  sig.Revoked,
+				})
+			}
+		}
+	}
+	return certifications
+}
+
+// EOF
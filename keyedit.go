@@ -0,0 +1,217 @@
+/* keyedit.go - interactive key editing with gpg --edit-key
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// gpgme_op_interact is not available through gpgme.go. It runs
+// gpg --edit-key, which asks with the GET_LINE, GET_BOOL and GET_HIDDEN
+// status lines for the commands and answers and reads them with
+// --command-fd. The same is done here, the prompts are identified by
+// their keyword, e.g. "keyedit.prompt" for the next command.
+
+package gpggohigh
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KeyEditFunc answers a prompt of gpg --edit-key. keyword is the
+// status keyword, "GET_LINE", "GET_BOOL" or "GET_HIDDEN", and prompt
+// identifies the question, e.g. "keyedit.prompt". Boolean prompts are
+// answered with "y" or "n". An error cancels the editing, changes not
+// saved before are discarded.
+type KeyEditFunc func(keyword, prompt string) (answer string, err error)
+
+// KeyEditStep is the answer to one prompt of gpg --edit-key.
+type KeyEditStep struct {
+	Prompt string // the prompt answered, e.g. "keyedit.prompt"
+	Answer string // the command or answer, e.g. "save"
+	// Optional steps are skipped, if gpg asks another prompt, e.g. for
+	// confirmations gpg only asks in some cases.
+	Optional bool
+}
+
+// DisableKeySteps returns the steps disabling a key, so it is not used
+// for encryption. Only the local trust database is changed.
+func DisableKeySteps() []KeyEditStep {
+	return []KeyEditStep{
+		{Prompt: "keyedit.prompt", Answer: "disable"},
+		{Prompt: "keyedit.prompt", Answer: "save"},
+	}
+}
+
+// EnableKeySteps returns the steps enabling a disabled key again.
+func EnableKeySteps() []KeyEditStep {
+	return []KeyEditStep{
+		{Prompt: "keyedit.prompt", Answer: "enable"},
+		{Prompt: "keyedit.prompt", Answer: "save"},
+	}
+}
+
+// SetPrimaryUserIDSteps returns the steps flagging the user ID with the
+// given number as primary user ID, see UserIDNumber. The secret key is
+// required.
+func SetPrimaryUserIDSteps(number int) []KeyEditStep {
+	return []KeyEditStep{
+		{Prompt: "keyedit.prompt", Answer: "uid " + strconv.Itoa(number)},
+		{Prompt: "keyedit.prompt", Answer: "primary"},
+		{Prompt: "keyedit.prompt", Answer: "save"},
+	}
+}
+
+// AddPhotoIDSteps returns the steps adding the JPEG image in the file
+// jpegFile as photo ID. gpg asks to confirm images larger than 6 KiB,
+// they are accepted. The secret key is required.
+func AddPhotoIDSteps(jpegFile string) []KeyEditStep {
+	return []KeyEditStep{
+		{Prompt: "keyedit.prompt", Answer: "addphoto"},
+		{Prompt: "photoid.jpeg.add", Answer: jpegFile},
+		{Prompt: "photoid.jpeg.size", Answer: "y", Optional: true},
+		{Prompt: "keyedit.prompt", Answer: "save"},
+	}
+}
+
+// EditKey edits the key with the given fingerprint by answering the
+// prompts of gpg --edit-key with steps, e.g. DisableKeySteps(). Each
+// prompt must match the next step which is not skipped, an unexpected
+// prompt cancels the editing without saving. The last step should be
+// "save" or "quit".
+func EditKey(fingerprint string, steps []KeyEditStep) error {
+	err := editKey(gpgCommand{}, fingerprint, stepsFunc(steps))
+	if err != nil {
+		return fmt.Errorf("EditKey - %w", err)
+	}
+	return nil
+}
+
+// EditKeyFunc edits the key with the given fingerprint by answering the
+// prompts of gpg --edit-key with fn, for edits which need to react to
+// the prompts. The commands are described in the gpg manual.
+func EditKeyFunc(fingerprint string, fn KeyEditFunc) error {
+	err := editKey(gpgCommand{}, fingerprint, fn)
+	if err != nil {
+		return fmt.Errorf("EditKeyFunc - %w", err)
+	}
+	return nil
+}
+
+// editKey implements EditKey and EditKeyFunc running cmd with the
+// arguments set.
+func editKey(cmd gpgCommand, fingerprint string, fn KeyEditFunc) error {
+	fingerprint = NormalizeFingerprint(fingerprint)
+	if !isFingerprint(fingerprint) {
+		return fmt.Errorf("not a fingerprint: %q", fingerprint)
+	}
+
+	cmd.args = []string{"--edit-key", fingerprint}
+	cmd.interact = func(prompt gpgStatus) (string, error) {
+		name := ""
+		if len(prompt.Args) > 0 {
+			name = prompt.Args[0]
+		}
+		logDebug("gpg prompt", "keyword", prompt.Keyword, "prompt", name)
+		return fn(prompt.Keyword, name)
+	}
+	status, err := cmd.run()
+	if _, found := findStatus(status, "KEY_CONSIDERED"); !found && !errors.Is(err, ErrKeyNotFound) {
+		// gpg reports an unknown key only on stderr
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrKeyNotFound, fingerprint, err)
+		}
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, fingerprint)
+	}
+	return err
+}
+
+// stepsFunc returns the KeyEditFunc answering the prompts with steps.
+// A prompt after the last step is an error.
+func stepsFunc(steps []KeyEditStep) KeyEditFunc {
+	next := 0
+	return func(keyword, prompt string) (string, error) {
+		for next < len(steps) && steps[next].Optional && steps[next].Prompt != prompt {
+			next++
+		}
+		if next == len(steps) {
+			return "", fmt.Errorf("unexpected prompt %s after the last step", prompt)
+		}
+		step := steps[next]
+		if step.Prompt != prompt {
+			return "", fmt.Errorf("unexpected prompt %s at step %d, expected %s",
+				prompt, next+1, step.Prompt)
+		}
+		next++
+		return step.Answer, nil
+	}
+}
+
+// UserIDNumber returns the number of the user ID uid of the key with the
+// given fingerprint as used by gpg --edit-key, e.g. for
+// SetPrimaryUserIDSteps. The user IDs and photo IDs are counted from 1
+// in the order of the keyblock. uid must match the user ID exactly.
+func UserIDNumber(fingerprint, uid string) (int, error) {
+	number, err := userIDNumber(gpgCommand{}, fingerprint, uid)
+	if err != nil {
+		return 0, fmt.Errorf("UserIDNumber - %w", err)
+	}
+	return number, nil
+}
+
+// userIDNumber implements UserIDNumber running cmd with the arguments
+// set.
+func userIDNumber(cmd gpgCommand, fingerprint, uid string) (int, error) {
+	fingerprint = NormalizeFingerprint(fingerprint)
+	if !isFingerprint(fingerprint) {
+		return 0, fmt.Errorf("not a fingerprint: %q", fingerprint)
+	}
+
+	var out bytes.Buffer
+	cmd.args = []string{"--with-colons", "--fixed-list-mode", "--list-keys", "--", fingerprint}
+	cmd.stdout = &out
+	if _, err := cmd.run(); err != nil {
+		return 0, err
+	}
+
+	number, keys := 0, 0
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		switch fields[0] {
+		case "pub":
+			keys++
+		case "uid", "uat":
+			if keys != 1 {
+				continue
+			}
+			number++
+			if fields[0] == "uid" && len(fields) > 9 && unescapeColon(fields[9]) == uid {
+				return number, nil
+			}
+		}
+	}
+	if keys == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrKeyNotFound, fingerprint)
+	}
+	return 0, fmt.Errorf("key %s has no user ID %q", fingerprint, uid)
+}
+
+// EOF
@@ -0,0 +1,242 @@
+/* keygen.go - key generation for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Key algorithms as understood by gpg --quick-gen-key.
+const (
+	KeyAlgoEd25519 = "ed25519"
+	KeyAlgoRSA4096 = "rsa4096"
+)
+
+// KeyCaps is a set of key capabilities.
+type KeyCaps uint
+
+const (
+	KeyCapSign KeyCaps = 1 << iota
+	KeyCapEncrypt
+	KeyCapAuthenticate
+	KeyCapCertify
+)
+
+// String returns the capabilities in the usage format of gpg,
+// e.g. "sign,encr".
+func (c KeyCaps) String() string {
+	var usage []string
+	if c&KeyCapSign != 0 {
+		usage = append(usage, "sign")
+	}
+	if c&KeyCapEncrypt != 0 {
+		usage = append(usage, "encr")
+	}
+	if c&KeyCapAuthenticate != 0 {
+		usage = append(usage, "auth")
+	}
+	if c&KeyCapCertify != 0 {
+		usage = append(usage, "cert")
+	}
+	return strings.Join(usage, ",")
+}
+
+// KeyGenOptions are the options for GenerateKey.
+// Apart from the Passphrase, which is required unless NoProtection is
+// set, the zero value creates an ed25519 primary key for signing and
+// certification with a cv25519 encryption subkey, using the default
+// expiration of gpg.
+type KeyGenOptions struct {
+	// Algorithm of the primary key and the subkeys, KeyAlgoEd25519 if empty.
+	Algorithm string
+	// Expiry is the validity period of the key. If 0, the default of gpg
+	// is used, if negative, the key does not expire.
+	Expiry time.Duration
+	// Passphrase protects the secret key. It must not be empty, unless
+	// NoProtection is set.
+	Passphrase string
+	// NoProtection creates a secret key without passphrase, e.g. for
+	// tests or services without a user. Passphrase must be empty then.
+	NoProtection bool
+	// SubkeyCapabilities defines the subkeys, one subkey is created for
+	// each capability. If 0, KeyCapEncrypt is used.
+	SubkeyCapabilities KeyCaps
+	// NoSubkeys creates a primary key only, with all capabilities
+	// possible for the algorithm.
+	NoSubkeys bool
+}
+
+// GenerateKey creates a new key with the user ID userID, e.g.
+// "Alice <alice@example.com>", and returns it.
+func GenerateKey(userID string, opts KeyGenOptions) (key KeyType, err error) {
+
+	if strings.TrimSpace(userID) == "" {
+		return key, fmt.Errorf("GenerateKey - empty user ID")
+	}
+	switch {
+	case opts.Passphrase == "" && !opts.NoProtection:
+		return key, fmt.Errorf("GenerateKey - no passphrase given")
+	case opts.Passphrase != "" && opts.NoProtection:
+		return key, fmt.Errorf("GenerateKey - passphrase given with NoProtection")
+	}
+	algo := opts.Algorithm
+	if algo == "" {
+		algo = KeyAlgoEd25519
+	}
+	expire := expiryArg(opts.Expiry)
+
+	primaryUsage := "sign,cert"
+	if opts.NoSubkeys {
+		primaryUsage = "default"
+	}
+	cmd := gpgCommand{
+		args: []string{"--quick-gen-key", "--", userID, algo, primaryUsage,
+			expire},
+		passphrase:    opts.Passphrase,
+		hasPassphrase: true,
+	}
+	status, err := cmd.run()
+	if err != nil {
+		return key, fmt.Errorf("GenerateKey - %w", err)
+	}
+	args, ok := findStatus(status, "KEY_CREATED")
+	if !ok || len(args) < 2 {
+		return key, fmt.Errorf("GenerateKey - no fingerprint of the created key")
+	}
+	fingerprint := args[1]
+
+	if !opts.NoSubkeys {
+		caps := opts.SubkeyCapabilities
+		if caps == 0 {
+			caps = KeyCapEncrypt
+		}
+		for _, c := range []KeyCaps{KeyCapEncrypt, KeyCapSign, KeyCapAuthenticate} {
+			if caps&c == 0 {
+				continue
+			}
+			cmd := gpgCommand{passphrase: opts.Passphrase, hasPassphrase: true}
+			_, err := addSubkey(cmd, fingerprint, subkeyAlgo(algo, c), c, expire)
+			if err != nil {
+				return key, fmt.Errorf("GenerateKey - adding %s subkey failed: %w", c, err)
+			}
+		}
+	}
+
+	keys, err := KeyList(fingerprint)
+	if err != nil {
+		return key, fmt.Errorf("GenerateKey - %w", err)
+	}
+	if len(keys) == 0 {
+		return key, fmt.Errorf("GenerateKey - created key %s not found", fingerprint)
+	}
+	return keys[0], nil
+}
+
+// AddSubkey adds a subkey with the capabilities to the key with the
+// given fingerprint and returns the fingerprint of the subkey.
+// algo is the algorithm like for KeyGenOptions, if empty the default of
+// gpg for the capabilities is used. The encryption subkey of an ed25519
+// key uses cv25519.
+// expiry is the expiration time of the subkey, the zero time means the
+// subkey does not expire.
+// The secret key is required.
+func AddSubkey(fingerprint, algo string, capabilities KeyCaps, expiry time.Time) (
+	subkeyFingerprint string, err error) {
+
+	expire, err := expiryTimeArg(expiry)
+	if err == nil {
+		subkeyFingerprint, err = addSubkey(gpgCommand{}, fingerprint, algo,
+			capabilities, expire)
+	}
+	if err != nil {
+		return "", fmt.Errorf("AddSubkey - %w", err)
+	}
+	return subkeyFingerprint, nil
+}
+
+// addSubkey implements AddSubkey running cmd with the arguments set.
+// expire is the expiration in the format of gpg.
+func addSubkey(cmd gpgCommand, fingerprint, algo string, capabilities KeyCaps,
+	expire string) (subkeyFingerprint string, err error) {
+
+	if !isFingerprint(fingerprint) {
+		return "", fmt.Errorf("not a fingerprint: %q", fingerprint)
+	}
+	if capabilities == 0 {
+		return "", fmt.Errorf("no capabilities given")
+	}
+	if algo == "" {
+		algo = "default"
+	} else {
+		algo = subkeyAlgo(algo, capabilities)
+	}
+
+	cmd.args = []string{"--quick-add-key", fingerprint, algo,
+		capabilities.String(), expire}
+	status, err := cmd.run()
+	if err != nil {
+		return "", err
+	}
+	args, ok := findStatus(status, "KEY_CREATED")
+	if !ok || len(args) < 2 {
+		return "", fmt.Errorf("no fingerprint of the created subkey")
+	}
+	return args[1], nil
+}
+
+// subkeyAlgo returns the algorithm of a subkey with the capability c for
+// a primary key with the algorithm algo. For ed25519 keys the encryption
+// subkey has to use cv25519.
+func subkeyAlgo(algo string, c KeyCaps) string {
+	if algo == KeyAlgoEd25519 && c == KeyCapEncrypt {
+		return "cv25519"
+	}
+	return algo
+}
+
+// expiryArg converts a validity period to the expire argument of the
+// gpg quick commands.
+func expiryArg(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "default"
+	case d < 0:
+		return "never"
+	}
+	return fmt.Sprintf("seconds=%d", int64(d.Seconds()))
+}
+
+// expiryTimeArg returns the gpg expiration argument for the expiration
+// time expiry, the zero time means no expiration.
+func expiryTimeArg(expiry time.Time) (string, error) {
+	if expiry.IsZero() {
+		return "never", nil
+	}
+	d := time.Until(expiry)
+	if d <= 0 {
+		return "", fmt.Errorf("expiry %s is in the past", expiry.Format(time.RFC3339))
+	}
+	return expiryArg(d), nil
+}
+
+// EOF
@@ -0,0 +1,404 @@
+/* keymanage.go - keyring management for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// DeleteKey removes the key with the given fingerprint from the keyring.
+// If the key has a secret key, it is only deleted together with the
+// secret key, which requires allowSecret.
+// force deletes without asking for a confirmation, which gpg may
+// otherwise request for secret keys.
+// A full fingerprint is required, so no key is deleted by accident.
+func DeleteKey(fingerprint string, allowSecret, force bool) error {
+	err := deleteKey(gpgCommand{}, fingerprint, allowSecret, force)
+	if err != nil {
+		return fmt.Errorf("DeleteKey - %w", err)
+	}
+	return nil
+}
+
+// deleteKey implements DeleteKey running cmd with the arguments set.
+func deleteKey(cmd gpgCommand, fingerprint string, allowSecret, force bool) error {
+	if !isFingerprint(fingerprint) {
+		return fmt.Errorf("not a fingerprint: %q", fingerprint)
+	}
+
+	op := "--delete-keys"
+	if allowSecret {
+		op = "--delete-secret-and-public-key"
+	}
+	args := []string{op, fingerprint}
+	if force {
+		args = []string{op, "--yes", fingerprint}
+	}
+
+	cmd.args = args
+	_, err := cmd.run()
+	return err
+}
+
+// SetKeyExpiry sets the expiration time of the key with the given
+// fingerprint to expiry. The zero time removes the expiration.
+// If subkeyFingerprints are given, the expiration of these subkeys is
+// set instead of the one of the primary key, "*" selects all subkeys.
+// Errors for unknown keys match ErrKeyNotFound with errors.Is.
+func SetKeyExpiry(fingerprint string, expiry time.Time, subkeyFingerprints ...string) error {
+	err := setKeyExpiry(gpgCommand{}, fingerprint, expiry, subkeyFingerprints)
+	if err != nil {
+		return fmt.Errorf("SetKeyExpiry - %w", err)
+	}
+	return nil
+}
+
+// setKeyExpiry implements SetKeyExpiry running cmd with the arguments set.
+func setKeyExpiry(cmd gpgCommand, fingerprint string, expiry time.Time,
+	subkeyFingerprints []string) error {
+
+	if !isFingerprint(fingerprint) {
+		return fmt.Errorf("not a fingerprint: %q", fingerprint)
+	}
+	expire, err := expiryTimeArg(expiry)
+	if err != nil {
+		return err
+	}
+
+	cmd.args = append([]string{"--quick-set-expire", fingerprint, expire},
+		subkeyFingerprints...)
+	_, err = cmd.run()
+	return err
+}
+
+// DisableKey disables the key with the given fingerprint, e.g. when an
+// employee leaves, so it is no longer used for encryption, but it stays
+// in the keyring for verifying signatures. Key listings report the key
+// with KeyType.Disabled set. Only the local trust database is changed,
+// exports of the key are not affected. Errors for unknown keys match
+// ErrKeyNotFound with errors.Is.
+func DisableKey(fingerprint string) error {
+	err := editKey(gpgCommand{}, fingerprint, stepsFunc(DisableKeySteps()))
+	if err != nil {
+		return fmt.Errorf("DisableKey - %w", err)
+	}
+	return nil
+}
+
+// EnableKey enables the key with the given fingerprint again, which was
+// disabled with DisableKey.
+func EnableKey(fingerprint string) error {
+	err := editKey(gpgCommand{}, fingerprint, stepsFunc(EnableKeySteps()))
+	if err != nil {
+		return fmt.Errorf("EnableKey - %w", err)
+	}
+	return nil
+}
+
+// AddUserID adds the user ID uid, e.g. "Name <mail@example.org>", to
+// the key with the given fingerprint. The secret key is required.
+func AddUserID(fingerprint, uid string) error {
+	err := editUserID(gpgCommand{}, "--quick-add-uid", fingerprint, uid)
+	if err != nil {
+		return fmt.Errorf("AddUserID - %w", err)
+	}
+	return nil
+}
+
+// RevokeUserID revokes the user ID uid of the key with the given
+// fingerprint. uid must match the user ID exactly. The last valid user
+// ID of a key can not be revoked.
+func RevokeUserID(fingerprint, uid string) error {
+	err := editUserID(gpgCommand{}, "--quick-revoke-uid", fingerprint, uid)
+	if err != nil {
+		return fmt.Errorf("RevokeUserID - %w", err)
+	}
+	return nil
+}
+
+// editUserID runs cmd with the gpg command op for the user ID uid of
+// the key with the given fingerprint.
+func editUserID(cmd gpgCommand, op, fingerprint, uid string) error {
+	if !isFingerprint(fingerprint) {
+		return fmt.Errorf("not a fingerprint: %q", fingerprint)
+	}
+	if strings.TrimSpace(uid) == "" {
+		return fmt.Errorf("empty user ID")
+	}
+
+	cmd.args = []string{op, fingerprint, uid}
+	_, err := cmd.run()
+	return err
+}
+
+// CertifyOptions configures CertifyKey.
+type CertifyOptions struct {
+	// Local creates a non-exportable signature, which is not published
+	// when the key is exported.
+	Local bool
+	// Expiry is the validity period of the signature. If 0, the
+	// signature does not expire.
+	Expiry time.Duration
+	// Force signs the user IDs again, even if they are already signed
+	// by the signer.
+	Force bool
+}
+
+// CertifyKey signs the user IDs uids of the key targetFPR with the key
+// signerFPR, usually after the owner of the target key was verified.
+// If uids is empty, all user IDs are signed. The user IDs must match
+// exactly.
+func CertifyKey(signerFPR, targetFPR string, uids []string, opts CertifyOptions) error {
+	err := certifyKey(SessionOptions{}, signerFPR, targetFPR, uids, opts)
+	if err != nil {
+		return fmt.Errorf("CertifyKey - %w", err)
+	}
+	return nil
+}
+
+// certifyKey implements CertifyKey with a context configured by
+// contextOpts. A new context is used, because gpgme.go can not clear
+// the signers added to a context.
+func certifyKey(contextOpts SessionOptions, signerFPR, targetFPR string,
+	uids []string, opts CertifyOptions) error {
+
+	myContext, err := newContext(contextOpts)
+	if err != nil {
+		return err
+	}
+	defer myContext.Release()
+
+	signer, err := myContext.GetKey(signerFPR, true)
+	if err != nil {
+		return fmt.Errorf("GetKey (signer) failed: %w", err)
+	}
+	defer signer.Release()
+	target, err := myContext.GetKey(targetFPR, false)
+	if err != nil {
+		return fmt.Errorf("GetKey (target) failed: %w", err)
+	}
+	defer target.Release()
+
+	err = myContext.SignersAdd(signer)
+	if err != nil {
+		return fmt.Errorf("SignersAdd failed: %w", err)
+	}
+
+	var flags gpgme.KeySignFlag
+	if opts.Local {
+		flags |= gpgme.KeySignLocal
+	}
+	if opts.Force {
+		flags |= gpgme.KeySignForce
+	}
+	// gpgme.go adds the expiry to the current time, so 0 would not mean
+	// the default and no expiry is requested by the flag
+	if opts.Expiry <= 0 {
+		flags |= gpgme.KeySignNoExpire
+	}
+	if len(uids) > 1 {
+		flags |= gpgme.KeySignLFSep
+	}
+
+	err = myContext.KeySign(*target, strings.Join(uids, "\n"), opts.Expiry, flags)
+	if err != nil {
+		return fmt.Errorf("KeySign failed: %w", err)
+	}
+	return nil
+}
+
+// SetOwnerTrust sets the owner trust of the key with the given
+// fingerprint, i.e. how far its certifications of other keys are
+// trusted. gpgme.ValidityUltimate should be used for own keys only.
+// gpgme.ValidityUnknown and gpgme.ValidityUndefined reset the trust to
+// undefined.
+func SetOwnerTrust(fingerprint string, trust gpgme.Validity) error {
+	err := setOwnerTrust(gpgCommand{}, fingerprint, trust)
+	if err != nil {
+		return fmt.Errorf("SetOwnerTrust - %w", err)
+	}
+	return nil
+}
+
+// setOwnerTrust implements SetOwnerTrust running cmd with the arguments
+// and the input set.
+func setOwnerTrust(cmd gpgCommand, fingerprint string, trust gpgme.Validity) error {
+	if !isFingerprint(fingerprint) {
+		return fmt.Errorf("not a fingerprint: %q", fingerprint)
+	}
+
+	value, err := ownerTrustValue(trust)
+	if err != nil {
+		return err
+	}
+	return importOwnerTrust(cmd, []OwnerTrustEntry{{Fingerprint: fingerprint, Value: value}})
+}
+
+// OwnerTrustEntry is the owner trust of a key in the gpg trust database.
+type OwnerTrustEntry struct {
+	Fingerprint string
+	Trust       gpgme.Validity
+	// Value is the value in the trust database, which also holds flags
+	// like disabled. If it is not zero, it is imported instead of Trust.
+	Value int
+}
+
+// ExportOwnerTrust returns the owner trust of all keys which have one
+// set, like `gpg --export-ownertrust`, e.g. to replicate the trust
+// decisions on another machine with ImportOwnerTrust.
+func ExportOwnerTrust() (entries []OwnerTrustEntry, err error) {
+	entries, err = exportOwnerTrust(gpgCommand{})
+	if err != nil {
+		return nil, fmt.Errorf("ExportOwnerTrust - %w", err)
+	}
+	return entries, nil
+}
+
+// exportOwnerTrust implements ExportOwnerTrust running cmd with the
+// arguments set.
+func exportOwnerTrust(cmd gpgCommand) (entries []OwnerTrustEntry, err error) {
+	var out bytes.Buffer
+	cmd.args = []string{"--export-ownertrust"}
+	cmd.stdout = &out
+	if _, err := cmd.run(); err != nil {
+		return nil, err
+	}
+
+	// lines like "<fingerprint>:<value>:", comments start with #
+	for _, line := range strings.Split(out.String(), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ":")
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid ownertrust line %q", line)
+		}
+		value, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid ownertrust line %q", line)
+		}
+		entries = append(entries, OwnerTrustEntry{
+			Fingerprint: fields[0],
+			Trust:       ownerTrustValidity(value),
+			Value:       value,
+		})
+	}
+	return entries, nil
+}
+
+// ImportOwnerTrust sets the owner trust of the keys of the entries, like
+// `gpg --import-ownertrust`. The owner trust of other keys is kept.
+// The keys need not be in the keyring yet.
+func ImportOwnerTrust(entries []OwnerTrustEntry) error {
+	err := importOwnerTrust(gpgCommand{}, entries)
+	if err != nil {
+		return fmt.Errorf("ImportOwnerTrust - %w", err)
+	}
+	return nil
+}
+
+// importOwnerTrust implements ImportOwnerTrust running cmd with the
+// arguments and the input set.
+func importOwnerTrust(cmd gpgCommand, entries []OwnerTrustEntry) error {
+	if len(entries) == 0 {
+		return fmt.Errorf("no ownertrust entries given")
+	}
+
+	var in strings.Builder
+	for _, e := range entries {
+		if !isFingerprint(e.Fingerprint) {
+			return fmt.Errorf("not a fingerprint: %q", e.Fingerprint)
+		}
+		value := e.Value
+		if value == 0 {
+			var err error
+			value, err = ownerTrustValue(e.Trust)
+			if err != nil {
+				return fmt.Errorf("key %s: %w", e.Fingerprint, err)
+			}
+		}
+		fmt.Fprintf(&in, "%s:%d:\n",
+			NormalizeFingerprint(e.Fingerprint), value)
+	}
+
+	cmd.args = []string{"--import-ownertrust"}
+	cmd.stdin = strings.NewReader(in.String())
+	_, err := cmd.run()
+	return err
+}
+
+// ownerTrustValue returns the value of the gpg trust database for trust.
+func ownerTrustValue(trust gpgme.Validity) (int, error) {
+	switch trust {
+	case gpgme.ValidityUnknown, gpgme.ValidityUndefined:
+		return 2, nil
+	case gpgme.ValidityNever:
+		return 3, nil
+	case gpgme.ValidityMarginal:
+		return 4, nil
+	case gpgme.ValidityFull:
+		return 5, nil
+	case gpgme.ValidityUltimate:
+		return 6, nil
+	}
+	return 0, fmt.Errorf("unknown trust value %d", trust)
+}
+
+// ownerTrustValidity returns the trust of a value of the gpg trust
+// database, the flags in the upper bits are ignored.
+func ownerTrustValidity(value int) gpgme.Validity {
+	switch value & 0x0f {
+	case 2:
+		return gpgme.ValidityUndefined
+	case 3:
+		return gpgme.ValidityNever
+	case 4:
+		return gpgme.ValidityMarginal
+	case 5:
+		return gpgme.ValidityFull
+	case 6:
+		return gpgme.ValidityUltimate
+	}
+	return gpgme.ValidityUnknown
+}
+
+// isFingerprint reports whether s is a full hexadecimal fingerprint of a
+// v4 (40 digits) or v5 (64 digits) key.
+func isFingerprint(s string) bool {
+	s = strings.TrimPrefix(s, "0x")
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// EOF
@@ -0,0 +1,184 @@
+/* keypasswd.go - changing the passphrase of secret keys
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// gpg --passwd asks for the old and the new passphrase with the same
+// prompt, and not at all for the old one of an unprotected key, so the
+// answers can not be given in advance. The PASSWD command of gpg-agent
+// inquires them as PASSPHRASE and NEW_PASSPHRASE, which
+// gpg-connect-agent answers from variables. The passphrases are
+// percent-encoded, so they may contain any character.
+
+package gpggohigh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ChangeKeyPassphrase changes the passphrase of the secret key with the
+// given fingerprint and of its secret subkeys from oldPassphrase to
+// newPassphrase with loopback pinentry, so no pinentry is shown.
+// oldPassphrase is not used for keys which are not protected. Keys on
+// smartcards are not changed. The keys are changed one after the other,
+// if one fails, e.g. a subkey with another passphrase, the keys before
+// already have the new passphrase. The error matches ErrBadPassphrase
+// with errors.Is, if oldPassphrase is wrong, and ErrKeyNotFound, if there
+// is no secret key for fingerprint.
+func ChangeKeyPassphrase(fingerprint, oldPassphrase, newPassphrase string) error {
+	err := changeKeyPassphrase("", fingerprint, oldPassphrase, newPassphrase)
+	if err != nil {
+		return fmt.Errorf("ChangeKeyPassphrase - %w", err)
+	}
+	return nil
+}
+
+// changeKeyPassphrase implements ChangeKeyPassphrase for the home
+// directory homeDir, empty for the one of SetHomeDir.
+func changeKeyPassphrase(homeDir, fingerprint, oldPassphrase, newPassphrase string) error {
+	fingerprint = NormalizeFingerprint(fingerprint)
+	if !isFingerprint(fingerprint) {
+		return fmt.Errorf("not a fingerprint: %q", fingerprint)
+	}
+	if homeDir == "" {
+		_, homeDir = gpgEngine()
+	}
+
+	keys, err := colonKeys(homeDir, fingerprint, true)
+	if err != nil {
+		return err
+	}
+	var keygrips []string
+	for _, k := range keys {
+		if k.Keygrip != "" && k.Token == "+" {
+			keygrips = append(keygrips, k.Keygrip)
+		}
+	}
+	if len(keygrips) == 0 {
+		return fmt.Errorf("%w: no secret key for %s", ErrKeyNotFound, fingerprint)
+	}
+
+	script := []string{
+		"OPTION pinentry-mode=loopback",
+		"/subst",
+		"/let old ${unpercent " + percentEncode(oldPassphrase) + "}",
+		"/let new ${unpercent " + percentEncode(newPassphrase) + "}",
+		"/definq PASSPHRASE old",
+		"/definq NEW_PASSPHRASE new",
+	}
+	for _, keygrip := range keygrips {
+		script = append(script, "PASSWD "+keygrip)
+	}
+	_, err = agentCommand(homeDir, strings.Join(script, "\n"))
+	return err
+}
+
+// percentEncode encodes all bytes of s but letters and digits as %XX.
+func percentEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// ExportSecretKeyWithPassphrase exports the secret key with the given
+// fingerprint protected by transferPassphrase instead of its passphrase,
+// e.g. to hand the key over without revealing the own passphrase.
+// passphrase is the current passphrase of the key. The key in the
+// keyring keeps its passphrase, it is changed in a copy in a temporary
+// home directory, which is removed afterwards. If armored is true, the
+// output is ASCII armored.
+func ExportSecretKeyWithPassphrase(fingerprint, passphrase, transferPassphrase string,
+	armored bool) ([]byte, error) {
+
+	keyData, err := exportSecretKeyWithPassphrase(gpgCommand{}, fingerprint,
+		passphrase, transferPassphrase, armored)
+	if err != nil {
+		return nil, fmt.Errorf("ExportSecretKeyWithPassphrase - %w", err)
+	}
+	return keyData, nil
+}
+
+// exportSecretKeyWithPassphrase implements ExportSecretKeyWithPassphrase
+// running the command cmd for the keyring of the key.
+func exportSecretKeyWithPassphrase(cmd gpgCommand, fingerprint, passphrase,
+	transferPassphrase string, armored bool) (keyData []byte, err error) {
+
+	fingerprint = NormalizeFingerprint(fingerprint)
+	if !isFingerprint(fingerprint) {
+		return nil, fmt.Errorf("not a fingerprint: %q", fingerprint)
+	}
+
+	var exported bytes.Buffer
+	cmd.args = []string{"--export-secret-keys", "--", fingerprint}
+	cmd.stdout = &exported
+	cmd.passphrase, cmd.hasPassphrase = passphrase, true
+	if _, err := cmd.run(); err != nil {
+		return nil, err
+	}
+	if exported.Len() == 0 {
+		return nil, fmt.Errorf("%w: no secret key for %s", ErrKeyNotFound, fingerprint)
+	}
+
+	tempHome, err := os.MkdirTemp("", "gpggohigh-")
+	if err != nil {
+		return nil, fmt.Errorf("MkdirTemp failed: %w", err)
+	}
+	defer func() {
+		// a failed kill should not leave the directory behind
+		cleanErr := errors.Join(killDaemons(tempHome), os.RemoveAll(tempHome))
+		if err == nil && cleanErr != nil {
+			keyData, err = nil, fmt.Errorf("removing %s failed: %w", tempHome, cleanErr)
+		}
+	}()
+
+	temp := gpgCommand{homeDir: tempHome, passphrase: passphrase, hasPassphrase: true,
+		args: []string{"--import"}, stdin: &exported}
+	if _, err := temp.run(); err != nil {
+		return nil, fmt.Errorf("import into %s failed: %w", tempHome, err)
+	}
+	err = changeKeyPassphrase(tempHome, fingerprint, passphrase, transferPassphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	temp.args = []string{"--export-secret-keys"}
+	if armored {
+		temp.args = append(temp.args, "--armor")
+	}
+	temp.args = append(temp.args, "--", fingerprint)
+	temp.stdin, temp.stdout = nil, &out
+	temp.passphrase = transferPassphrase
+	if _, err := temp.run(); err != nil {
+		return nil, fmt.Errorf("export from %s failed: %w", tempHome, err)
+	}
+	return out.Bytes(), nil
+}
+
+// EOF
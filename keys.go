@@ -25,8 +25,13 @@ package gpggohigh
 
 import "C"
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/kulbartsch/gpgme"
@@ -70,20 +75,52 @@ type KeyType struct {
 	// Release
 	Revoked bool
 	Secret  bool
-	// SubKeys *SubKey
+	SubKeys []SubKeyType // the primary key first
 	UserIDs []KeyUserIDsType
+
+	// facts of the primary key
+	KeyID      string    // the long key ID
+	PubkeyAlgo string    // the algorithm, e.g. "RSA" or "EdDSA"
+	Length     int       // the key size in bits
+	Curve      string    // the curve of ECC keys, e.g. "ed25519"
+	Created    time.Time // the creation time
+	Expires    time.Time // the expiration time, zero if the key does not expire
+}
+
+// SubKeyType is a structure for each subkey of a key, the first one is
+// the primary key.
+type SubKeyType struct {
+	Fingerprint string
+	KeyID       string
+	Keygrip     string // identifies the secret key in gpg-agent
+	CardNumber  string // serial number of the smartcard holding the secret key
+	PubkeyAlgo  string
+	Length      int
+	Curve       string
+	// Capabilities are the usages of the subkey as listed by gpg:
+	// "e" encrypt, "s" sign, "c" certify and "a" authenticate.
+	Capabilities string
+	Created      time.Time
+	Expires      time.Time // zero if the subkey does not expire
+	Revoked      bool
+	Expired      bool
+	Disabled     bool
+	Invalid      bool
+	Secret       bool
 }
 
 // KeyUserIDs is a structure for each user ID (UID) of a key.
 type KeyUserIDsType struct {
 	UserID        string
 	Name          string
+	Comment       string
 	Invalid       bool
 	Revoked       bool
 	Validity      gpgme.Validity
 	Address       string
 	HasSignatures bool
 	Signatures    KeyUidSignaturesType
+	Tofu          *TofuInfoType // nil unless listed with KeyListWithTofu
 }
 
 // KeyUidIssuerSignatureType is a structure for each signature of
@@ -103,7 +140,7 @@ type KeyUidIssuerSignatureType struct {
 	Comment        string
 	TrustScope     string
 	HasNotations   bool
-	// Notations []NotationType
+	Notations      []NotationType
 }
 
 // KeyUidSignaturesType is a map for each issuers KeyID with
@@ -111,34 +148,425 @@ type KeyUidIssuerSignatureType struct {
 type KeyUidSignaturesType map[string][]KeyUidIssuerSignatureType
 
 // KeyList returns a list of keys that match the lookFor string.
+// The keys are listed with their key signatures and details, see
+// KeyFilter.WithDetails, which is slow for large keyrings; see KeyListFast.
 func KeyList(lookFor string) (keys []KeyType, err error) {
+	return KeyListCtx(context.Background(), lookFor)
+}
 
-	ctx, err := gpgme.New()
+// KeyListCtx returns a list of keys like KeyList. The listing stops
+// when ctx is done, the keys listed so far are returned with the error.
+func KeyListCtx(ctx context.Context, lookFor string) (keys []KeyType, err error) {
+
+	myContext, err := newContext(SessionOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("KeyList -Create context failed - %w", err)
 	}
-	defer ctx.Release()
+	defer myContext.Release()
+
+	return keyList(ctx, myContext, lookFor)
+}
 
-	err = ctx.SetKeyListMode(gpgme.KeyListModeLocal | gpgme.KeyListModeSigs |
-		gpgme.KeyListModeSigNotations)
+// KeyListFast returns a list of keys like KeyList, but without their key
+// signatures and details, which makes listing large keyrings much faster.
+// The user IDs have no Signatures, LoadKeySignatures and LoadKeyDetails
+// load them for a key when needed.
+func KeyListFast(lookFor string) (keys []KeyType, err error) {
+	myContext, err := newContext(SessionOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("KeyList -SetKeyListMode failed - %w", err)
+		return nil, fmt.Errorf("KeyList -Create context failed - %w", err)
 	}
+	defer myContext.Release()
 
-	if err := ctx.KeyListStart(lookFor, false); err != nil {
-		return nil, fmt.Errorf("KeyList -SetKeyListStart failed - %w", err)
+	return keyListFiltered(context.Background(), myContext, lookFor,
+		KeyFilter{WithoutSignatures: true})
+}
+
+// LoadKeySignatures sets the key signatures on the user IDs of key,
+// which was listed without them, e.g. by KeyListFast. A key listed with
+// its signatures is not changed.
+func LoadKeySignatures(key *KeyType) (err error) {
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return fmt.Errorf("LoadKeySignatures - %w", err)
 	}
-	defer func() { _ = ctx.KeyListEnd() }()
+	defer myContext.Release()
+
+	return loadKeySignatures(myContext, key)
+}
 
-	for ctx.KeyListNext() {
-		keys = append(keys, fillKey(ctx.Key))
+// loadKeySignatures implements LoadKeySignatures using myContext.
+func loadKeySignatures(myContext *gpgme.Context, key *KeyType) error {
+	if key.KeyListMode&gpgme.KeyListModeSigs != 0 {
+		return nil
+	}
+	var found *KeyType
+	err := keyListEach(context.Background(), myContext, key.Fingerprint, KeyFilter{},
+		func(k KeyType) bool {
+			if k.Fingerprint != key.Fingerprint {
+				return true
+			}
+			found = &k
+			return false
+		})
+	if err != nil {
+		return fmt.Errorf("LoadKeySignatures - %w", err)
 	}
-	if ctx.KeyError != nil {
-		return keys, fmt.Errorf("KeyList -KeyListNext failed - %w", ctx.KeyError)
+	if found == nil {
+		return fmt.Errorf("LoadKeySignatures - %w: %s", ErrKeyNotFound, key.Fingerprint)
+	}
+
+	// the other fields of the user IDs, e.g. Tofu, are kept
+	for i := range key.UserIDs {
+		uid := &key.UserIDs[i]
+		for _, f := range found.UserIDs {
+			if f.UserID == uid.UserID {
+				uid.HasSignatures, uid.Signatures = f.HasSignatures, f.Signatures
+				break
+			}
+		}
+	}
+	key.KeyListMode = found.KeyListMode
+	return nil
+}
+
+// KeyFilter selects the keys returned by KeyListFiltered. The zero value
+// selects all keys, each set field restricts the selection, except
+// WithoutSignatures and WithDetails.
+type KeyFilter struct {
+	SecretOnly bool // only keys with a secret key
+	CanEncrypt bool // only keys usable for encryption
+	CanSign    bool // only keys usable for signing
+	NotExpired bool // no expired keys
+	NotRevoked bool // no revoked keys
+	// ExpiringWithin, if not zero, selects only keys which expire within
+	// this duration from now. Already expired keys are included, unless
+	// NotExpired is set.
+	ExpiringWithin time.Duration
+	// WithoutSignatures lists the keys without their key signatures like
+	// KeyListFast.
+	WithoutSignatures bool
+	// WithDetails lists the keys with the algorithm, size and curve of
+	// their keys and the keygrips and capabilities of their subkeys.
+	// gpgme.go does not provide them, so gpg lists all matching keys
+	// once more before the first key is returned; LoadKeyDetails loads
+	// them for a single key.
+	WithDetails bool
+}
+
+// match reports whether the key k is selected by the filter.
+// SecretOnly is handled by the key listing.
+func (f KeyFilter) match(k *gpgme.Key, now time.Time) bool {
+	switch {
+	case f.CanEncrypt && !k.CanEncrypt(),
+		f.CanSign && !k.CanSign(),
+		f.NotExpired && k.Expired(),
+		f.NotRevoked && k.Revoked():
+		return false
+	}
+	if f.ExpiringWithin != 0 {
+		primary := k.SubKeys()
+		if primary == nil {
+			return false
+		}
+		expires := primary.Expires()
+		if expires.IsZero() || expires.After(now.Add(f.ExpiringWithin)) {
+			return false
+		}
+	}
+	return true
+}
+
+// KeyListFiltered returns the keys matching the lookFor string like
+// KeyList, but only those selected by filter. The filter is applied
+// while listing, the other keys are not converted.
+func KeyListFiltered(lookFor string, filter KeyFilter) (keys []KeyType, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("KeyList -Create context failed - %w", err)
+	}
+	defer myContext.Release()
+
+	return keyListFiltered(context.Background(), myContext, lookFor, filter)
+}
+
+// ListCardKeys returns the secret keys with at least one subkey stored
+// on a smartcard or token like a YubiKey. The subkeys on a card have
+// their CardNumber set.
+func ListCardKeys() (keys []KeyType, err error) {
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("ListCardKeys - %w", err)
+	}
+	defer myContext.Release()
+
+	keys, err = listCardKeys(myContext)
+	if err != nil {
+		return nil, fmt.Errorf("ListCardKeys - %w", err)
 	}
 	return keys, nil
 }
 
+// listCardKeys implements ListCardKeys using myContext.
+func listCardKeys(myContext *gpgme.Context) (keys []KeyType, err error) {
+	err = keyListEach(context.Background(), myContext, "", KeyFilter{SecretOnly: true},
+		func(key KeyType) bool {
+			if slices.ContainsFunc(key.SubKeys, func(sk SubKeyType) bool {
+				return sk.CardNumber != ""
+			}) {
+				keys = append(keys, key)
+			}
+			return true
+		})
+	return keys, err
+}
+
+// keyList implements KeyList using myContext.
+func keyList(ctx context.Context, myContext *gpgme.Context, lookFor string) (
+	keys []KeyType, err error) {
+	return keyListFiltered(ctx, myContext, lookFor, KeyFilter{WithDetails: true})
+}
+
+// keyListFiltered implements KeyListFiltered using myContext.
+func keyListFiltered(ctx context.Context, myContext *gpgme.Context, lookFor string,
+	filter KeyFilter) (keys []KeyType, err error) {
+
+	err = keyListEach(ctx, myContext, lookFor, filter, func(key KeyType) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys, err
+}
+
+// KeyListEach calls fn for each key matching the lookFor string, like
+// KeyList returns them but without their details, see
+// KeyFilter.WithDetails, without collecting all keys in memory.
+// The listing stops early, if fn returns false.
+func KeyListEach(lookFor string, fn func(KeyType) bool) (err error) {
+	return KeyListEachCtx(context.Background(), lookFor, KeyFilter{}, fn)
+}
+
+// KeyListEachCtx calls fn for each key matching the lookFor string and
+// selected by filter like KeyListEach. The listing stops when ctx is
+// done, the error is returned then.
+func KeyListEachCtx(ctx context.Context, lookFor string, filter KeyFilter,
+	fn func(KeyType) bool) (err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return fmt.Errorf("KeyList -Create context failed - %w", err)
+	}
+	defer myContext.Release()
+
+	return keyListEach(ctx, myContext, lookFor, filter, fn)
+}
+
+// keyListEach implements KeyListEachCtx using myContext.
+func keyListEach(ctx context.Context, myContext *gpgme.Context, lookFor string,
+	filter KeyFilter, fn func(KeyType) bool) (err error) {
+
+	mode := gpgme.KeyListModeLocal
+	// X.509 certificates have no key signatures
+	if myContext.Protocol() == gpgme.ProtocolOpenPGP && !filter.WithoutSignatures {
+		mode |= gpgme.KeyListModeSigs | gpgme.KeyListModeSigNotations
+	}
+	err = myContext.SetKeyListMode(mode)
+	if err != nil {
+		return fmt.Errorf("KeyList -SetKeyListMode failed - %w", err)
+	}
+
+	if err := myContext.KeyListStart(lookFor, filter.SecretOnly); err != nil {
+		return fmt.Errorf("KeyList -SetKeyListStart failed - %w", err)
+	}
+	defer func() { _ = myContext.KeyListEnd() }()
+
+	var facts map[string]colonKey
+	if filter.WithDetails && myContext.Protocol() == gpgme.ProtocolOpenPGP {
+		facts, err = colonKeys(contextHomeDir(myContext), lookFor, filter.SecretOnly)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return fmt.Errorf("KeyList -listing the details failed - %w", err)
+		}
+	}
+
+	now := time.Now()
+	for myContext.KeyListNext() {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("KeyList -canceled - %w", err)
+		}
+		if !filter.match(myContext.Key, now) {
+			continue
+		}
+		key := fillKey(myContext.Key)
+		setKeyDetails(&key, facts)
+		if !fn(key) {
+			return nil
+		}
+	}
+	if myContext.KeyError != nil {
+		return fmt.Errorf("KeyList -KeyListNext failed - %w", myContext.KeyError)
+	}
+	return nil
+}
+
+// LoadKeyDetails sets the details of key, which was listed without them,
+// see KeyFilter.WithDetails.
+func LoadKeyDetails(key *KeyType) (err error) {
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return fmt.Errorf("LoadKeyDetails - %w", err)
+	}
+	defer myContext.Release()
+
+	return loadKeyDetails(myContext, key)
+}
+
+// loadKeyDetails implements LoadKeyDetails using myContext.
+func loadKeyDetails(myContext *gpgme.Context, key *KeyType) error {
+	// X.509 certificates have no colon listing by gpg
+	if myContext.Protocol() != gpgme.ProtocolOpenPGP {
+		return nil
+	}
+	facts, err := colonKeys(contextHomeDir(myContext), key.Fingerprint, false)
+	if err != nil {
+		return fmt.Errorf("LoadKeyDetails - %w", err)
+	}
+	if _, ok := facts[key.Fingerprint]; !ok {
+		return fmt.Errorf("LoadKeyDetails - %w: %s", ErrKeyNotFound, key.Fingerprint)
+	}
+	setKeyDetails(key, facts)
+	return nil
+}
+
+// setKeyDetails sets the details of key and its subkeys from the facts
+// of colonKeys.
+func setKeyDetails(key *KeyType, facts map[string]colonKey) {
+	if f, ok := facts[key.Fingerprint]; ok {
+		key.PubkeyAlgo, key.Length, key.Curve = f.PubkeyAlgo, f.Length, f.Curve
+	}
+	for i := range key.SubKeys {
+		if f, ok := facts[key.SubKeys[i].Fingerprint]; ok {
+			sk := &key.SubKeys[i]
+			sk.PubkeyAlgo, sk.Length, sk.Curve = f.PubkeyAlgo, f.Length, f.Curve
+			sk.Keygrip = f.Keygrip
+			sk.Capabilities = f.Capabilities
+		}
+	}
+}
+
+// findKeys returns the keys matching pattern, looked up with the
+// configuration (protocol, home directory) of myContext.
+// If secretOnly is true, only keys with a secret key are returned.
+func findKeys(myContext *gpgme.Context, pattern string, secretOnly bool) (
+	keys []*gpgme.Key, err error) {
+
+	defer func() {
+		logDebug("key lookup", "pattern", pattern, "secret", secretOnly,
+			"found", len(keys), "error", err)
+	}()
+
+	if err := myContext.KeyListStart(pattern, secretOnly); err != nil {
+		return nil, err
+	}
+	defer func() { _ = myContext.KeyListEnd() }()
+
+	for myContext.KeyListNext() {
+		keys = append(keys, myContext.Key)
+	}
+	if myContext.KeyError != nil {
+		return keys, myContext.KeyError
+	}
+	return keys, nil
+}
+
+// colonKey holds the facts of a (sub)key from the gpg colon listing,
+// which gpgme.go does not provide.
+type colonKey struct {
+	PubkeyAlgo   string
+	Length       int
+	Curve        string
+	Keygrip      string
+	Capabilities string // of the key itself, e.g. "sc"
+	// Token tells where a secret key is: "+" on disk, "#" not
+	// available, otherwise the serial number of the card holding it.
+	Token string
+}
+
+// colonKeys returns the facts of the primary keys and subkeys matching
+// pattern by their fingerprint, as listed by gpg with the home directory
+// homeDir. The error matches ErrKeyNotFound, if no key matches.
+func colonKeys(homeDir, pattern string, secretOnly bool) (map[string]colonKey, error) {
+	list := "--list-keys"
+	if secretOnly {
+		list = "--list-secret-keys"
+	}
+	var out bytes.Buffer
+	cmd := gpgCommand{
+		args:    []string{"--with-colons", "--fixed-list-mode", "--with-keygrip", list},
+		stdout:  &out,
+		homeDir: homeDir,
+	}
+	if pattern != "" {
+		cmd.args = append(cmd.args, "--", pattern)
+	}
+	if _, err := cmd.run(); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]colonKey)
+	var pending *colonKey // the key record waiting for its fpr record
+	var current string    // the fingerprint of the last key
+	for _, line := range strings.Split(out.String(), "\n") {
+		fields := strings.Split(line, ":")
+		switch fields[0] {
+		case "pub", "sec", "sub", "ssb":
+			pending, current = nil, ""
+			if len(fields) < 17 {
+				continue
+			}
+			length, _ := strconv.Atoi(fields[2])
+			algo, _ := strconv.Atoi(fields[3])
+			pending = &colonKey{
+				PubkeyAlgo: gpgme.PubkeyAlgoName(gpgme.PubkeyAlgo(algo)),
+				Length:     length,
+				Curve:      fields[16],
+				// the upper case letters are the capabilities of the
+				// whole key and only set on the primary key
+				Capabilities: strings.Map(func(r rune) rune {
+					if r >= 'A' && r <= 'Z' {
+						return -1
+					}
+					return r
+				}, fields[11]),
+				Token: fields[14],
+			}
+		case "fpr":
+			if pending != nil && len(fields) > 9 {
+				current = fields[9]
+				keys[current] = *pending
+				pending = nil
+			}
+		case "grp":
+			if k, ok := keys[current]; ok && len(fields) > 9 {
+				k.Keygrip = fields[9]
+				keys[current] = k
+			}
+		}
+	}
+	return keys, nil
+}
+
+// contextHomeDir returns the home directory of the OpenPGP engine of
+// myContext, empty for the default one.
+func contextHomeDir(myContext *gpgme.Context) string {
+	for info := myContext.EngineInfo(); info != nil; info = info.Next() {
+		if info.Protocol() == gpgme.ProtocolOpenPGP {
+			return info.HomeDir()
+		}
+	}
+	return ""
+}
+
 //// Key Information
 
 func fillKey(k *gpgme.Key) (key KeyType) {
@@ -162,7 +590,12 @@ func fillKey(k *gpgme.Key) (key KeyType) {
 	// key.// Release  = kRelease()
 	key.Revoked = k.Revoked()
 	key.Secret = k.Secret()
-	// key.// SubKeys *SubKey  //TODO: implement SubKey
+	if primary := k.SubKeys(); primary != nil {
+		key.KeyID = primary.KeyID()
+		key.Created = primary.Created()
+		key.Expires = primary.Expires()
+	}
+	key.SubKeys = fillSubKeys(k.SubKeys())
 
 	//key.UserIDs []KeyUserIDsType
 	if key.HasUserIDs {
@@ -174,12 +607,31 @@ func fillKey(k *gpgme.Key) (key KeyType) {
 	return key
 }
 
+func fillSubKeys(sk *gpgme.SubKey) (subKeys []SubKeyType) {
+	for ; sk != nil; sk = sk.Next() {
+		subKeys = append(subKeys, SubKeyType{
+			Fingerprint: sk.Fingerprint(),
+			KeyID:       sk.KeyID(),
+			CardNumber:  sk.CardNumber(),
+			Created:     sk.Created(),
+			Expires:     sk.Expires(),
+			Revoked:     sk.Revoked(),
+			Expired:     sk.Expired(),
+			Disabled:    sk.Disabled(),
+			Invalid:     sk.Invalid(),
+			Secret:      sk.Secret(),
+		})
+	}
+	return subKeys
+}
+
 func fillUserIDs(uid *gpgme.UserID) (uids []KeyUserIDsType) {
 
 	for uid != nil {
 		var oneUid KeyUserIDsType
 		oneUid.UserID = uid.UID()
 		oneUid.Name = uid.Name()
+		oneUid.Comment = uid.Comment()
 		oneUid.Invalid = uid.Invalid()
 		oneUid.Revoked = uid.Revoked()
 		oneUid.Validity = uid.Validity()
@@ -223,7 +675,9 @@ func fillUidSignatures(uid *gpgme.UserID) (sigs KeyUidSignaturesType) {
 		oneSig.Comment = sig.Comment()
 		oneSig.TrustScope = sig.TrustScope()
 		oneSig.HasNotations = sig.HasNotation()
-		// TODO: add notations ...
+		if oneSig.HasNotations {
+			oneSig.Notations = fillNotations(sig.Notations())
+		}
 
 		sigs[keyID] = append(sigs[keyID], oneSig)
 	}
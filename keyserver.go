@@ -0,0 +1,201 @@
+/* keyserver.go - keyserver operations for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// KeyserverSearch searches the keyserver configured for gpg (dirmngr)
+// for keys matching pattern, e.g. a mail address or fingerprint.
+// The keys are not imported, see KeyserverReceive.
+// Keyserver failures are transient errors, see RetryPolicy.
+func KeyserverSearch(pattern string) (keys []KeyType, err error) {
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("KeyserverSearch - %w", err)
+	}
+	defer myContext.Release()
+
+	err = withRetry(context.Background(), nil, "", func() (err error) {
+		keys, err = keyserverSearch(myContext, pattern)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("KeyserverSearch - %w", err)
+	}
+	return keys, nil
+}
+
+// keyserverSearch implements KeyserverSearch using myContext.
+func keyserverSearch(myContext *gpgme.Context, pattern string) (keys []KeyType, err error) {
+	if strings.TrimSpace(pattern) == "" {
+		return nil, fmt.Errorf("empty search pattern")
+	}
+
+	// other operations using the context must not search the keyserver
+	oldMode := myContext.KeyListMode()
+	err = myContext.SetKeyListMode(gpgme.KeyListModeExtern)
+	if err != nil {
+		return nil, fmt.Errorf("SetKeyListMode failed: %w", err)
+	}
+	defer func() { _ = myContext.SetKeyListMode(oldMode) }()
+
+	found, err := findKeys(myContext, pattern, false)
+	for _, k := range found {
+		keys = append(keys, fillKey(k))
+	}
+	if err != nil {
+		return keys, fmt.Errorf("key listing failed: %w", err)
+	}
+	return keys, nil
+}
+
+// KeyserverReceive imports the keys with the given fingerprints from the
+// keyserver configured for gpg. The result is the same as for ImportKeys.
+func KeyserverReceive(fingerprints []string) (result *gpgme.ImportResult, err error) {
+	result, err = keyserverReceive(gpgCommand{}, fingerprints)
+	if err != nil {
+		return result, fmt.Errorf("KeyserverReceive - %w", err)
+	}
+	return result, nil
+}
+
+// keyserverReceive implements KeyserverReceive running cmd with the
+// arguments set.
+func keyserverReceive(cmd gpgCommand, fingerprints []string) (
+	*gpgme.ImportResult, error) {
+
+	if len(fingerprints) == 0 {
+		return nil, fmt.Errorf("no fingerprints given")
+	}
+	cmd.args = append([]string{"--recv-keys", "--"}, fingerprints...)
+	return runImport(cmd, "KeyserverReceive")
+}
+
+// runImport runs the command cmd, which imports keys into the keyring,
+// and audits the import as done by the function fn.
+func runImport(cmd gpgCommand, fn string) (*gpgme.ImportResult, error) {
+	status, err := cmd.run()
+	result := importResultFromStatus(status)
+	audit(AuditImport, fn, err, func(event *AuditEvent) {
+		event.Keys = ImportedFingerprints(result)
+	})
+	return result, err
+}
+
+// KeyserverSend sends the keys with the given fingerprints to the
+// keyserver configured for gpg.
+func KeyserverSend(fingerprints []string) error {
+	err := keyserverSend(gpgCommand{}, fingerprints)
+	if err != nil {
+		return fmt.Errorf("KeyserverSend - %w", err)
+	}
+	return nil
+}
+
+// keyserverSend implements KeyserverSend running cmd with the arguments set.
+func keyserverSend(cmd gpgCommand, fingerprints []string) error {
+	if len(fingerprints) == 0 {
+		return fmt.Errorf("no fingerprints given")
+	}
+	for _, fpr := range fingerprints {
+		// a user ID would send any matching key
+		if !isFingerprint(fpr) {
+			return fmt.Errorf("not a fingerprint: %q", fpr)
+		}
+	}
+	cmd.args = append([]string{"--send-keys", "--"}, fingerprints...)
+	_, err := cmd.run()
+	return err
+}
+
+// importResultFromStatus builds an import result from the IMPORT_OK,
+// IMPORT_PROBLEM and IMPORT_RES status lines of gpg.
+func importResultFromStatus(status []gpgStatus) *gpgme.ImportResult {
+	result := &gpgme.ImportResult{}
+	for _, s := range status {
+		switch s.Keyword {
+		case "IMPORT_OK":
+			if len(s.Args) < 2 {
+				continue
+			}
+			// the flags of gpg and gpgme are the same
+			flags, _ := strconv.Atoi(s.Args[0])
+			result.Imports = append(result.Imports, gpgme.ImportStatus{
+				Fingerprint: s.Args[1], Status: gpgme.ImportStatusFlags(flags)})
+		case "IMPORT_PROBLEM":
+			i := gpgme.ImportStatus{Result: errors.New(importProblemString(s.Args))}
+			if len(s.Args) > 1 {
+				i.Fingerprint = s.Args[1]
+			}
+			result.Imports = append(result.Imports, i)
+		case "IMPORT_RES":
+			n := make([]int, 14)
+			for i := range n {
+				if i < len(s.Args) {
+					n[i], _ = strconv.Atoi(s.Args[i])
+				}
+			}
+			result.Considered = n[0]
+			result.NoUserID = n[1]
+			result.Imported = n[2]
+			result.ImportedRSA = n[3]
+			result.Unchanged = n[4]
+			result.NewUserIDs = n[5]
+			result.NewSubKeys = n[6]
+			result.NewSignatures = n[7]
+			result.NewRevocations = n[8]
+			result.SecretRead = n[9]
+			result.SecretImported = n[10]
+			result.SecretUnchanged = n[11]
+			result.NotImported = n[13]
+		}
+	}
+	return result
+}
+
+// importProblemString returns the description of the reason code of an
+// IMPORT_PROBLEM status line.
+func importProblemString(args []string) string {
+	if len(args) == 0 {
+		return "import problem"
+	}
+	switch args[0] {
+	case "1":
+		return "invalid certificate"
+	case "2":
+		return "issuer certificate missing"
+	case "3":
+		return "certificate chain too long"
+	case "4":
+		return "error storing certificate"
+	}
+	return "no specific reason given"
+}
+
+// EOF
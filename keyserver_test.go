@@ -0,0 +1,110 @@
+/* keyserver_test.go - tests of the keyserver status parsing
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"testing"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+func TestImportResultFromStatus(t *testing.T) {
+	const fpr = "4AAE7570D5062F3CC8DCCB9E22340A7B19813D3D"
+	result := importResultFromStatus(statusLines(
+		"IMPORT_OK 1 "+fpr,
+		"IMPORT_PROBLEM 1 919C2B7343B8319CD68201BACC698E1B92E86C86",
+		"IMPORT_PROBLEM",
+		"IMPORT_OK 1",
+		"IMPORT_RES 3 0 1 0 1 2 3 4 5 6 7 8 0 1",
+	))
+
+	if len(result.Imports) != 3 {
+		t.Fatalf("got %d imports, want 3", len(result.Imports))
+	}
+	if i := result.Imports[0]; i.Fingerprint != fpr || i.Status != gpgme.ImportStatusFlags(1) ||
+		i.Result != nil {
+		t.Errorf("import 0 = %+v", i)
+	}
+	if i := result.Imports[1]; i.Fingerprint != "919C2B7343B8319CD68201BACC698E1B92E86C86" ||
+		i.Result == nil || i.Result.Error() != "invalid certificate" {
+		t.Errorf("import 1 = %+v", i)
+	}
+	if i := result.Imports[2]; i.Fingerprint != "" || i.Result == nil ||
+		i.Result.Error() != "import problem" {
+		t.Errorf("import 2 = %+v", i)
+	}
+
+	counters := []struct {
+		name      string
+		got, want int
+	}{
+		{"Considered", result.Considered, 3},
+		{"NoUserID", result.NoUserID, 0},
+		{"Imported", result.Imported, 1},
+		{"ImportedRSA", result.ImportedRSA, 0},
+		{"Unchanged", result.Unchanged, 1},
+		{"NewUserIDs", result.NewUserIDs, 2},
+		{"NewSubKeys", result.NewSubKeys, 3},
+		{"NewSignatures", result.NewSignatures, 4},
+		{"NewRevocations", result.NewRevocations, 5},
+		{"SecretRead", result.SecretRead, 6},
+		{"SecretImported", result.SecretImported, 7},
+		{"SecretUnchanged", result.SecretUnchanged, 8},
+		{"NotImported", result.NotImported, 1},
+	}
+	for _, c := range counters {
+		if c.got != c.want {
+			t.Errorf("%s = %d, want %d", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestImportResultFromStatusShort(t *testing.T) {
+	// older versions of gpg write fewer counters
+	result := importResultFromStatus(statusLines("IMPORT_RES 1 0 1"))
+	if result.Considered != 1 || result.Imported != 1 || result.NotImported != 0 {
+		t.Errorf("result = %+v", result)
+	}
+	if len(importResultFromStatus(nil).Imports) != 0 {
+		t.Error("imports without status lines")
+	}
+}
+
+func TestImportProblemString(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "import problem"},
+		{[]string{"0"}, "no specific reason given"},
+		{[]string{"1", "FPR"}, "invalid certificate"},
+		{[]string{"2"}, "issuer certificate missing"},
+		{[]string{"3"}, "certificate chain too long"},
+		{[]string{"4"}, "error storing certificate"},
+	}
+	for _, tt := range tests {
+		if got := importProblemString(tt.args); got != tt.want {
+			t.Errorf("importProblemString(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+// EOF
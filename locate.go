@@ -0,0 +1,104 @@
+/* locate.go - key discovery by mail address for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sources of a key found by LocateKeyByEmail, named like the mechanisms
+// of the gpg option --auto-key-locate.
+const (
+	KeySourceLocal     = "local"     // the key was already in the keyring
+	KeySourceWKD       = "wkd"       // Web Key Directory of the mail domain
+	KeySourceDANE      = "dane"      // OPENPGPKEY DNS record
+	KeySourceKeyserver = "keyserver" // keyserver configured for gpg
+)
+
+// locateMechanisms are the external mechanisms tried in order.
+var locateMechanisms = []string{KeySourceWKD, KeySourceDANE, KeySourceKeyserver}
+
+// LocateKeyByEmail returns the key for the mail address email and where
+// it was found. If the keyring has no key for the address, the key is
+// looked up like with the gpg option --auto-key-locate by the Web Key
+// Directory, DANE and the keyserver, in this order, and imported.
+// The error matches ErrKeyNotFound with errors.Is, if no mechanism
+// found a key.
+func LocateKeyByEmail(email string) (key KeyType, source string, err error) {
+	key, source, err = locateKeyByEmail(gpgCommand{}, email)
+	if err != nil {
+		return key, "", fmt.Errorf("LocateKeyByEmail - %w", err)
+	}
+	return key, source, nil
+}
+
+// locateKeyByEmail implements LocateKeyByEmail running cmd with the
+// arguments set.
+func locateKeyByEmail(cmd gpgCommand, email string) (key KeyType, source string,
+	err error) {
+
+	email = strings.TrimSpace(email)
+	if !strings.Contains(email, "@") {
+		return key, "", fmt.Errorf("not a mail address: %q", email)
+	}
+	// only keys with exactly this mail address
+	pattern := "<" + email + ">"
+
+	myContext, err := newContext(SessionOptions{HomeDir: cmd.homeDir})
+	if err != nil {
+		return key, "", err
+	}
+	defer myContext.Release()
+
+	keys, err := keyList(context.Background(), myContext, pattern)
+	if err != nil {
+		return key, "", err
+	}
+	if len(keys) > 0 {
+		return keys[0], KeySourceLocal, nil
+	}
+
+	var errs []error
+	for _, mechanism := range locateMechanisms {
+		cmd.args = []string{"--auto-key-locate", "clear,nodefault," + mechanism,
+			"--locate-external-keys", "--", email}
+		if _, err := runImport(cmd, "LocateKeyByEmail"); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", mechanism, err))
+			continue
+		}
+		keys, err = keyList(context.Background(), myContext, pattern)
+		if err != nil {
+			return key, "", err
+		}
+		if len(keys) > 0 {
+			return keys[0], mechanism, nil
+		}
+	}
+	if len(errs) == 0 {
+		return key, "", fmt.Errorf("%w for %s", ErrKeyNotFound, email)
+	}
+	return key, "", fmt.Errorf("%w for %s: %w", ErrKeyNotFound, email, errors.Join(errs...))
+}
+
+// EOF
@@ -0,0 +1,65 @@
+/* log.go - diagnostic logging for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// The package is silent by default. With SetLogger it reports the gpgme
+// contexts, key lookups and engine calls at the debug level and the
+// status lines of gpg at the trace level. Passphrases, session keys and
+// data are never logged.
+
+package gpggohigh
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// LevelTrace is the slog level of the most detailed messages, e.g. each
+// status line of gpg.
+const LevelTrace = slog.LevelDebug - 4
+
+var logger atomic.Pointer[slog.Logger]
+
+// SetLogger sets the logger for the diagnostic messages of the package,
+// nil disables them.
+func SetLogger(l *slog.Logger) {
+	logger.Store(l)
+}
+
+// logDebug logs msg with the attributes args at the debug level.
+func logDebug(msg string, args ...any) {
+	logAt(slog.LevelDebug, msg, args...)
+}
+
+// logTrace logs msg with the attributes args at the trace level.
+func logTrace(msg string, args ...any) {
+	logAt(LevelTrace, msg, args...)
+}
+
+// logAt logs msg at level, if a logger is set.
+func logAt(level slog.Level, msg string, args ...any) {
+	l := logger.Load()
+	if l == nil {
+		return
+	}
+	l.Log(context.Background(), level, msg, args...)
+}
+
+// EOF
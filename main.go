@@ -21,9 +21,11 @@
 package gpggohigh
 
 import (
+	"bufio"
+	"bytes"
 	"crypto/rand"
 	"fmt"
-	"log"
+	"io"
 	"math/big"
 	"os"
 	"runtime/debug"
@@ -128,6 +130,62 @@ func IdentifyFile(filename string) (GDType gpgme.DataType, err error) {
 	return dataIn.Identify(), nil
 }
 
+// IdentifyResultType describes data identified by IdentifyBytes and
+// IdentifyFileDetails.
+type IdentifyResultType struct {
+	DataType gpgme.DataType // see DataTypeMapString
+	Armored  bool           // the data starts with an ASCII armored block
+	Armor    ArmorInfo      // the armored block, if Armored
+}
+
+// IdentifyBytes identifies the data like IdentifyFile and describes its
+// ASCII armor: the block type, the headers and whether the checksum is
+// valid.
+func IdentifyBytes(data []byte) (result IdentifyResultType, err error) {
+	dataIn, err := gpgme.NewDataBytes(data)
+	if err != nil {
+		return result, fmt.Errorf("IdentifyBytes - NewData (in) failed: %w", err)
+	}
+	defer dataIn.Close()
+
+	result.DataType = dataIn.Identify()
+	result.Armored, result.Armor = identifyArmor(bytes.NewReader(data))
+	return result, nil
+}
+
+// IdentifyFileDetails identifies the file filename like IdentifyBytes.
+func IdentifyFileDetails(filename string) (result IdentifyResultType, err error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return result, fmt.Errorf("IdentifyFile - Open failed: %w", err)
+	}
+	defer fh.Close()
+	dataIn, err := gpgme.NewDataFile(fh)
+	if err != nil {
+		return result, fmt.Errorf("IdentifyFile - NewData (in) failed: %w", err)
+	}
+	defer dataIn.Close()
+
+	result.DataType = dataIn.Identify()
+	if _, err = fh.Seek(0, io.SeekStart); err != nil {
+		return result, fmt.Errorf("IdentifyFile - Seek failed: %w", err)
+	}
+	result.Armored, result.Armor = identifyArmor(fh)
+	return result, nil
+}
+
+// identifyArmor describes the ASCII armor of the data read from r, if it
+// starts with one.
+func identifyArmor(r io.Reader) (armored bool, info ArmorInfo) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(64)
+	if !bytes.HasPrefix(bytes.TrimSpace(head), []byte(armorBegin)) {
+		return false, info
+	}
+	info, err := inspectArmor(br)
+	return err == nil, info
+}
+
 var DataTypeMapString = map[gpgme.DataType]string{
 	gpgme.TypeInvalid:      "invalid",
 	gpgme.TypeUnknown:      "unknown",
@@ -148,21 +206,22 @@ var DataTypeMapString = map[gpgme.DataType]string{
 // RandomString generates a string of chars and nums with length n.
 // If n is less than 1, an empty string is returned.
 // The character set is [a-z,A-Z,0-9] => 62 characters.
-func RandomString(n int) string {
+// An error is returned, if the system random number generator fails.
+func RandomString(n int) (string, error) {
 	if n < 1 {
-		return ""
+		return "", nil
 	}
 	var charSet = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
 	s := make([]rune, n)
 	l := big.NewInt(int64(len(charSet)))
 	for i := range s {
 		r, err := rand.Int(rand.Reader, l)
-		if err != nil { // Can't happen by definition of crypto/rand, just paranoia
-			log.Fatalf("Can't generate random string: %v\n", err)
+		if err != nil {
+			return "", fmt.Errorf("RandomString - %w", err)
 		}
 		s[i] = charSet[int(r.Int64())]
 	}
-	return string(s)
+	return string(s), nil
 }
 
 // Bool2str returns "true" if b is true, otherwise "false".
@@ -0,0 +1,122 @@
+/* manifest.go - parallel verification of detached signatures
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// ManifestEntry is a file and its detached signature.
+type ManifestEntry struct {
+	Data string // the signed file
+	Sig  string // the file with the detached signature, binary or ASCII armored
+}
+
+// ManifestResult is the verification result of one ManifestEntry.
+type ManifestResult struct {
+	ManifestEntry
+	Signatures []gpgme.Signature
+	// Classification is the best classification of the signatures, 0 if
+	// there are none.
+	Classification SignatureClassification
+	// Err is nil if the file has a good signature, i.e. one classified
+	// as SignatureValid or SignatureUnknownKey.
+	Err error
+}
+
+// VerifyManifest verifies the detached signatures of the entries
+// concurrently by workers, each using its own gpgme context for all its
+// entries, e.g. to check the packages of a software repository. The
+// contexts are taken from the pool of SetDefaultPool, if one is set. If
+// workers is less than 1, one worker per CPU is used. The results are in
+// the order of entries. If an entry failed, err reports the number of
+// failed entries, the details are in the results.
+func VerifyManifest(entries []ManifestEntry, workers int) (results []ManifestResult,
+	err error) {
+	return VerifyManifestCtx(context.Background(), entries, workers)
+}
+
+// VerifyManifestCtx verifies signatures like VerifyManifest. When ctx is
+// done, the remaining entries are reported with the error of ctx.
+func VerifyManifestCtx(ctx context.Context, entries []ManifestEntry, workers int) (
+	results []ManifestResult, err error) {
+	return verifyManifest(ctx, defaultPool.Load(), SessionOptions{}, entries, workers)
+}
+
+// verifyManifest implements VerifyManifestCtx with the gpgme contexts of
+// pool, or of a pool configured by opts if pool is nil, see runBatch.
+func verifyManifest(ctx context.Context, pool *ContextPool, opts SessionOptions,
+	entries []ManifestEntry, workers int) (results []ManifestResult, err error) {
+
+	results = make([]ManifestResult, len(entries))
+	for i, e := range entries {
+		results[i].ManifestEntry = e
+	}
+
+	runBatch(ctx, pool, opts, workers, len(entries),
+		func(myContext *gpgme.Context, i int) {
+			r := &results[i]
+			r.Signatures, r.Err = verifyFileDetached(myContext, r.Sig, r.Data)
+			if r.Err == nil {
+				r.Classification, r.Err = manifestClassification(r.Signatures)
+			}
+		},
+		func(i int, err error) {
+			results[i].Err = err
+		})
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("VerifyManifest - %d of %d entries failed",
+			failed, len(entries))
+	}
+	return results, nil
+}
+
+// manifestClassification returns the best classification of the
+// signatures and an error, if none of them is good.
+func manifestClassification(signatures []gpgme.Signature) (
+	best SignatureClassification, err error) {
+
+	for _, sig := range signatures {
+		// the classifications are ordered from good to bad
+		if c := ClassifySignature(sig); best == 0 || c < best {
+			best = c
+		}
+	}
+	switch best {
+	case SignatureValid, SignatureUnknownKey:
+		return best, nil
+	case 0:
+		return best, fmt.Errorf("no signature found")
+	}
+	return best, fmt.Errorf("signature %s", best)
+}
+
+// EOF
@@ -0,0 +1,175 @@
+/* metadata.go - preservation of file permissions and modification times
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// gpg stores the time of the encryption and the file name in the
+// literal data packet, not the permissions or the modification time of
+// the source file. gpgme.go does not report the time, so to restore it
+// the data is decrypted by gpg itself, and the time is read from its
+// PLAINTEXT status together with the decryption and verification
+// result.
+
+package gpggohigh
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// errNoSecretKey is the status of a recipient of data decrypted by
+// decryptLiteral without a secret key, with the text gpgme reports.
+var errNoSecretKey = errors.New("No secret key")
+
+// copyMetadata copies the permissions and the modification time of the
+// file source to the file destination.
+func copyMetadata(source, destination string) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(destination, info.Mode().Perm()); err != nil {
+		return err
+	}
+	// the zero access time keeps it unchanged
+	return os.Chtimes(destination, time.Time{}, info.ModTime())
+}
+
+// decryptLiteral decrypts the data read from r to w running the command
+// cmd, verifies the contained signatures and returns the results like
+// decryptStream, and the time stored in the literal data packet. It is
+// the zero time, if the data has none. fn names the operation in errors.
+func decryptLiteral(cmd gpgCommand, fn string, r io.Reader, w io.Writer) (
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, literal time.Time, err error) {
+
+	defer func() {
+		audit(AuditDecrypt, fn, err, func(event *AuditEvent) {
+			event.Recipients = decryptRecipients(decryptionResult)
+			event.Signers = signatureFingerprints(signatures)
+		})
+	}()
+
+	cmd.args = []string{"--decrypt"}
+	cmd.stdin = r
+	cmd.stdout = w
+	status, err := cmd.run()
+	if err != nil {
+		err = fmt.Errorf("%s - %w", fn, err)
+		return
+	}
+	decryptionResult = decryptResultFromStatus(status)
+	signatures = signaturesFromStatus(status)
+	filename = decryptionResult.Filename
+	if _, found := findStatus(status, "BEGIN_DECRYPTION"); !found {
+		warning = fn + " - DecryptVerify: no encrypted data"
+	}
+	if args, found := findStatus(status, "PLAINTEXT"); found && len(args) > 1 {
+		literal = statusTime(args[1])
+	}
+	return
+}
+
+// decryptResultFromStatus builds the decryption result from the status
+// lines of gpg like gpgme does.
+func decryptResultFromStatus(status []gpgStatus) (result gpgme.DecryptResultType) {
+	for _, s := range status {
+		switch s.Keyword {
+		case "ENC_TO":
+			// <long keyid> <keytype> <keylength>
+			if len(s.Args) > 1 {
+				algo, _ := strconv.Atoi(s.Args[1])
+				result.Recipients = append(result.Recipients, gpgme.DecryptRecipient{
+					KeyID: s.Args[0], PubkeyAlgo: gpgme.PubkeyAlgo(algo)})
+			}
+		case "NO_SECKEY":
+			for i, r := range result.Recipients {
+				if len(s.Args) > 0 && r.KeyID == s.Args[0] {
+					result.Recipients[i].Status = errNoSecretKey
+				}
+			}
+		case "DECRYPTION_INFO":
+			// <mdc method> <sym algo> [<aead algo>]
+			if len(s.Args) > 1 {
+				aead := "0"
+				if len(s.Args) > 2 {
+					aead = s.Args[2]
+				}
+				result.SymkeyAlgo = symkeyAlgoName(s.Args[1], aead)
+			}
+		case "DECRYPTION_COMPLIANCE_MODE":
+			// 23 is the de-vs compliance
+			result.IsDEVS = slices.Contains(s.Args, "23")
+		case "PLAINTEXT":
+			// <format> <timestamp> <filename>
+			if len(s.Args) > 0 {
+				result.IsMIME = s.Args[0] == "6d"
+			}
+			if len(s.Args) > 2 {
+				result.Filename = unescapeStatus(s.Args[2])
+			}
+		}
+	}
+	return result
+}
+
+// symkeyAlgoName returns the name of the symmetric cipher and mode like
+// gpgme, e.g. "AES256.OCB", for the algorithm numbers of DECRYPTION_INFO.
+func symkeyAlgoName(cipherAlgo, aeadAlgo string) string {
+	ciphers := map[string]string{"1": "IDEA", "2": "3DES", "3": "CAST5",
+		"4": "BLOWFISH", "7": "AES", "8": "AES192", "9": "AES256",
+		"10": "TWOFISH", "11": "CAMELLIA128", "12": "CAMELLIA192",
+		"13": "CAMELLIA256"}
+	modes := map[string]string{"0": "CFB", "1": "EAX", "2": "OCB"}
+	cipher, mode := ciphers[cipherAlgo], modes[aeadAlgo]
+	if cipher == "" {
+		cipher = "?"
+	}
+	if mode == "" {
+		mode = "?"
+	}
+	return cipher + "." + mode
+}
+
+// setMetadata sets the metadata of the decrypted file destination, before
+// it is renamed to its final name, as requested by opts. sources are the
+// files the encrypted data was read from, the metadata is copied from
+// the first one. literal is the time of the literal data packet, used
+// with RestoreTimestamp.
+func setMetadata(sources []string, destination string, opts DecryptOptions,
+	literal time.Time) error {
+
+	if opts.PreserveMetadata && len(sources) > 0 {
+		if err := copyMetadata(sources[0], destination); err != nil {
+			return fmt.Errorf("copying the metadata failed: %w", err)
+		}
+	}
+	if !opts.RestoreTimestamp || literal.IsZero() {
+		return nil
+	}
+	return os.Chtimes(destination, time.Time{}, literal)
+}
+
+// EOF
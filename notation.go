@@ -0,0 +1,207 @@
+/* notation.go - signature notation data for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// gpgme.go provides the notations of key signatures only, so signing
+// with notations and reading the notations of data signatures is done
+// by calling gpg.
+
+package gpggohigh
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// NotationType is a notation, a name/value pair attached to a signature.
+// Names without `@` are reserved for IETF use, others have the form
+// name@domain.
+type NotationType struct {
+	Name          string
+	Value         string
+	Critical      bool // the signature is invalid, if the notation is not understood
+	HumanReadable bool // the value is text
+}
+
+// SignatureNotationsType are the notations of one data signature.
+type SignatureNotationsType struct {
+	Fingerprint string // of the signing key, the key ID if the key is unknown
+	Notations   []NotationType
+	PolicyURL   string // the signature policy URL, if any
+}
+
+// fillNotations converts the notations of gpgme to NotationType.
+func fillNotations(n *gpgme.Notation) (notations []NotationType) {
+	for ; n != nil; n = n.Next() {
+		notations = append(notations, NotationType{
+			Name:          n.Name(),
+			Value:         n.Value(),
+			Critical:      n.Critical(),
+			HumanReadable: n.HumanReadable(),
+		})
+	}
+	return notations
+}
+
+// SignBytesNotations signs a memory buffer like SignBytesMode and
+// attaches the notations to the signature.
+// Only human readable notations are supported by gpg.
+func SignBytesNotations(plainText []byte, signWith string, mode gpgme.SigMode,
+	armored bool, notations []NotationType) (cipherText []byte, err error) {
+
+	cipherText, err = signBytesNotations(gpgCommand{}, plainText, signWith, mode,
+		armored, notations)
+	if err != nil {
+		return nil, fmt.Errorf("SignBytesNotations - %w", err)
+	}
+	return cipherText, nil
+}
+
+// signBytesNotations implements SignBytesNotations running the command
+// cmd.
+func signBytesNotations(cmd gpgCommand, plainText []byte, signWith string,
+	mode gpgme.SigMode, armored bool, notations []NotationType) (
+	cipherText []byte, err error) {
+
+	extra, err := notationArgs(notations)
+	if err != nil {
+		return nil, err
+	}
+	return signBytesArgs(cmd, "SignBytesNotations", plainText, signWith, mode,
+		armored, extra)
+}
+
+// signOperation returns the gpg command for the signature mode.
+func signOperation(mode gpgme.SigMode) (string, error) {
+	switch mode {
+	case gpgme.SigModeNormal:
+		return "--sign", nil
+	case gpgme.SigModeDetach:
+		return "--detach-sign", nil
+	case gpgme.SigModeClear:
+		return "--clearsign", nil
+	}
+	return "", fmt.Errorf("unknown signature mode %d", mode)
+}
+
+// notationArgs returns the gpg arguments attaching the notations to a
+// signature.
+func notationArgs(notations []NotationType) (args []string, err error) {
+	for _, n := range notations {
+		if !n.HumanReadable {
+			return nil, fmt.Errorf("notation %s: only human readable notations are supported", n.Name)
+		}
+		if n.Name == "" || strings.ContainsAny(n.Name, "= ") {
+			return nil, fmt.Errorf("invalid notation name %q", n.Name)
+		}
+		arg := n.Name + "=" + n.Value
+		if n.Critical {
+			arg = "!" + arg
+		}
+		args = append(args, "--set-notation", arg)
+	}
+	return args, nil
+}
+
+// VerifyBytesNotations verifies the signed data like VerifyBytes and
+// returns the notations of each signature. Signatures without notations
+// are included with empty notations.
+// Detached signatures are not supported.
+func VerifyBytesNotations(cipherText []byte) (sigNotations []SignatureNotationsType,
+	err error) {
+
+	sigNotations, err = verifyBytesNotations(gpgCommand{}, cipherText)
+	if err != nil {
+		return sigNotations, fmt.Errorf("VerifyBytes - %w", err)
+	}
+	return sigNotations, nil
+}
+
+// verifyBytesNotations implements VerifyBytesNotations running cmd with
+// the arguments and the input set.
+func verifyBytesNotations(cmd gpgCommand, cipherText []byte) (
+	sigNotations []SignatureNotationsType, err error) {
+
+	cmd.args = []string{"--verify", "--output", "-", "-"}
+	cmd.stdin = bytes.NewReader(cipherText)
+	cmd.stdout = io.Discard
+	status, err := cmd.run()
+	audit(AuditVerify, "VerifyBytesNotations", err, func(event *AuditEvent) {
+		event.Signers = signatureFingerprints(signaturesFromStatus(status))
+		event.InputSHA256 = hashBytes(cipherText)
+	})
+	return notationsFromStatus(status), err
+}
+
+// notationsFromStatus collects the notations of the status lines of a
+// signature verification. Each signature starts with NEWSIG.
+func notationsFromStatus(status []gpgStatus) (sigNotations []SignatureNotationsType) {
+	var current *SignatureNotationsType
+	var notation *NotationType
+	for _, s := range status {
+		switch s.Keyword {
+		case "NEWSIG":
+			sigNotations = append(sigNotations, SignatureNotationsType{})
+			current = &sigNotations[len(sigNotations)-1]
+			notation = nil
+		case "ERRSIG", "VALIDSIG":
+			if current != nil && len(s.Args) > 0 {
+				// VALIDSIG has the fingerprint, ERRSIG the key ID only
+				current.Fingerprint = s.Args[0]
+			}
+		case "NOTATION_NAME":
+			if current != nil && len(s.Args) > 0 {
+				current.Notations = append(current.Notations,
+					NotationType{Name: s.Args[0], HumanReadable: true})
+				notation = &current.Notations[len(current.Notations)-1]
+			}
+		case "NOTATION_FLAGS":
+			if notation != nil && len(s.Args) > 1 {
+				notation.Critical = s.Args[0] == "1"
+				notation.HumanReadable = s.Args[1] == "1"
+			}
+		case "NOTATION_DATA":
+			// long values are split over several lines
+			if notation != nil {
+				notation.Value += unescapeStatus(s.Text)
+			}
+		case "POLICY_URL":
+			if current != nil {
+				current.PolicyURL = unescapeStatus(s.Text)
+			}
+		}
+	}
+	return sigNotations
+}
+
+// unescapeStatus decodes the percent escaping of status line arguments.
+func unescapeStatus(s string) string {
+	u, err := url.PathUnescape(s)
+	if err != nil {
+		return s
+	}
+	return u
+}
+
+// EOF
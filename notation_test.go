@@ -0,0 +1,100 @@
+/* notation_test.go - tests of the notation parsing
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNotationsFromStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status []gpgStatus
+		want   []SignatureNotationsType
+	}{
+		{"none", statusLines("PLAINTEXT 62 0"), nil},
+		{"signature without notations",
+			statusLines("NEWSIG", "GOODSIG 22340A7B19813D3D Alice",
+				"VALIDSIG 4AAE7570D5062F3CC8DCCB9E22340A7B19813D3D 2025-01-01"),
+			[]SignatureNotationsType{{Fingerprint: "4AAE7570D5062F3CC8DCCB9E22340A7B19813D3D"}}},
+		{"notations and policy",
+			statusLines("NEWSIG",
+				"NOTATION_NAME ticket@example.org",
+				"NOTATION_FLAGS 1 1",
+				"NOTATION_DATA 1234%20and%25",
+				"NOTATION_DATA more",
+				"NOTATION_NAME blob@example.org",
+				"NOTATION_FLAGS 0 0",
+				"NOTATION_DATA %00%01",
+				"POLICY_URL https://example.org/policy%3Fv=1",
+				"VALIDSIG 4AAE7570D5062F3CC8DCCB9E22340A7B19813D3D 2025-01-01"),
+			[]SignatureNotationsType{{
+				Fingerprint: "4AAE7570D5062F3CC8DCCB9E22340A7B19813D3D",
+				Notations: []NotationType{
+					{Name: "ticket@example.org", Value: "1234 and%more", Critical: true,
+						HumanReadable: true},
+					{Name: "blob@example.org", Value: "\x00\x01"},
+				},
+				PolicyURL: "https://example.org/policy?v=1",
+			}}},
+		{"two signatures",
+			statusLines("NEWSIG", "NOTATION_NAME a@example.org", "NOTATION_DATA 1",
+				"ERRSIG 22340A7B19813D3D 22 10 00 1735689600 9",
+				"NEWSIG", "NOTATION_DATA ignored", "NOTATION_NAME b@example.org",
+				"NOTATION_DATA 2"),
+			[]SignatureNotationsType{
+				{Fingerprint: "22340A7B19813D3D", Notations: []NotationType{
+					{Name: "a@example.org", Value: "1", HumanReadable: true}}},
+				{Notations: []NotationType{
+					{Name: "b@example.org", Value: "2", HumanReadable: true}}},
+			}},
+		{"before NEWSIG", statusLines("NOTATION_NAME a@example.org", "NOTATION_DATA 1"),
+			nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := notationsFromStatus(tt.status)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("notationsFromStatus =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnescapeStatus(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"a%20b%25c", "a b%c"},
+		{"%E2%82%AC", "€"},
+		{"bad%zz", "bad%zz"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := unescapeStatus(tt.in); got != tt.want {
+			t.Errorf("unescapeStatus(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// EOF
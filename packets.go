@@ -0,0 +1,181 @@
+/* packets.go - inspect OpenPGP data without decrypting for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// The packets are listed with `gpg --list-only --list-packets`, which
+// reads the unencrypted packets only and asks for no passphrase.
+
+package gpggohigh
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// PacketRecipient is a public key recipient of encrypted data.
+type PacketRecipient struct {
+	// KeyID is the key ID of the encryption subkey, 0000000000000000 for
+	// a hidden recipient.
+	KeyID string
+	// Fingerprint is the fingerprint of the primary key, empty if the key
+	// is not in the keyring.
+	Fingerprint string
+	PubkeyAlgo  gpgme.PubkeyAlgo
+}
+
+// PacketInfo describes OpenPGP data as far as it is known without
+// decrypting it.
+type PacketInfo struct {
+	Armored    bool              // the data is ASCII armored
+	Encrypted  bool              // the data is encrypted
+	Symmetric  bool              // the data can be decrypted with a passphrase
+	Recipients []PacketRecipient // the public key recipients
+	// Signed tells, whether the data is signed. The signature of signed
+	// and encrypted data is inside the encryption, so it is only found
+	// by the decryption.
+	Signed bool
+}
+
+// KeyIDs returns the key IDs of the public key recipients.
+func (p PacketInfo) KeyIDs() (keyIDs []string) {
+	for _, r := range p.Recipients {
+		keyIDs = append(keyIDs, r.KeyID)
+	}
+	return keyIDs
+}
+
+// ListPacketRecipients returns the recipients of the encrypted file
+// filename and whether it is signed, symmetric encrypted or armored,
+// without decrypting it. E.g. to check who can decrypt the file before
+// calling ModRecipients.
+func ListPacketRecipients(filename string) (info PacketInfo, err error) {
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return info, fmt.Errorf("ListPacketRecipients - %w", err)
+	}
+	defer myContext.Release()
+
+	info, err = listPacketRecipients(myContext, gpgCommand{}, filename)
+	if err != nil {
+		return info, fmt.Errorf("ListPacketRecipients - %w", err)
+	}
+	return info, nil
+}
+
+// listPacketRecipients implements ListPacketRecipients using myContext
+// for the key lookup and the command cmd to read the file.
+func listPacketRecipients(myContext *gpgme.Context, cmd gpgCommand, filename string) (
+	info PacketInfo, err error) {
+
+	fh, err := os.Open(filename)
+	if err != nil {
+		return info, fmt.Errorf("Open failed: %w", err)
+	}
+	defer fh.Close()
+
+	return packetRecipients(myContext, cmd, fh)
+}
+
+// ListPacketRecipientsBytes returns the recipients of the encrypted data
+// cipherText like ListPacketRecipients.
+func ListPacketRecipientsBytes(cipherText []byte) (info PacketInfo, err error) {
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return info, fmt.Errorf("ListPacketRecipients - %w", err)
+	}
+	defer myContext.Release()
+
+	info, err = packetRecipients(myContext, gpgCommand{}, bytes.NewReader(cipherText))
+	if err != nil {
+		return info, fmt.Errorf("ListPacketRecipients - %w", err)
+	}
+	return info, nil
+}
+
+// packetRecipients lists the packets of the data read from r and looks up
+// the recipient keys with myContext.
+func packetRecipients(myContext *gpgme.Context, cmd gpgCommand, r io.Reader) (
+	info PacketInfo, err error) {
+
+	info, err = listPackets(cmd, r)
+	if err != nil {
+		return info, err
+	}
+	for i, rcpt := range info.Recipients {
+		if strings.Trim(rcpt.KeyID, "0") == "" {
+			continue // hidden recipient
+		}
+		keys, err := findKeys(myContext, rcpt.KeyID, false)
+		if err == nil && len(keys) == 1 {
+			info.Recipients[i].Fingerprint = keys[0].Fingerprint()
+		}
+	}
+	return info, nil
+}
+
+// listPackets returns the packet information of the data read from r.
+func listPackets(cmd gpgCommand, r io.Reader) (info PacketInfo, err error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(64)
+	info.Armored = bytes.HasPrefix(bytes.TrimSpace(head), []byte("-----BEGIN PGP "))
+
+	var out bytes.Buffer
+	cmd.args = []string{"--list-only", "--list-packets"}
+	cmd.stdin = br
+	cmd.stdout = &out
+	status, err := cmd.run()
+	if err != nil {
+		return info, err
+	}
+
+	for _, s := range status {
+		if s.Keyword == "ENC_TO" && len(s.Args) > 0 {
+			rcpt := PacketRecipient{KeyID: s.Args[0]}
+			if len(s.Args) > 1 {
+				algo, _ := strconv.Atoi(s.Args[1])
+				rcpt.PubkeyAlgo = gpgme.PubkeyAlgo(algo)
+			}
+			info.Recipients = append(info.Recipients, rcpt)
+		}
+	}
+	for _, line := range strings.Split(out.String(), "\n") {
+		switch {
+		case strings.HasPrefix(line, ":pubkey enc packet:"),
+			strings.HasPrefix(line, ":encrypted data packet:"),
+			strings.HasPrefix(line, ":aead encrypted packet:"):
+			info.Encrypted = true
+		case strings.HasPrefix(line, ":symkey enc packet:"):
+			info.Encrypted = true
+			info.Symmetric = true
+		case strings.HasPrefix(line, ":onepass_sig packet:"),
+			strings.HasPrefix(line, ":signature packet:"):
+			info.Signed = true
+		}
+	}
+	return info, nil
+}
+
+// EOF
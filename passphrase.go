@@ -0,0 +1,122 @@
+/* passphrase.go - passphrase handling for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// ErrBadPassphrase is returned by a PassphraseFunc created with
+// StaticPassphrase, if the passphrase was rejected.
+var ErrBadPassphrase = errors.New("bad passphrase")
+
+// PassphraseFunc returns the passphrase instead of asking the user
+// via pinentry.
+// uidHint describes the key the passphrase is requested for, and is
+// empty for symmetric encryption.
+// prevWasBad is true, if the previously returned passphrase was wrong.
+// Returning an error cancels the operation.
+type PassphraseFunc func(uidHint string, prevWasBad bool) (string, error)
+
+// StaticPassphrase returns a PassphraseFunc which always returns
+// passphrase. If the passphrase was wrong, ErrBadPassphrase is returned
+// to avoid an endless loop.
+func StaticPassphrase(passphrase string) PassphraseFunc {
+	return func(uidHint string, prevWasBad bool) (string, error) {
+		if prevWasBad {
+			return "", ErrBadPassphrase
+		}
+		return passphrase, nil
+	}
+}
+
+// setPassphraseFunc switches the context to loopback pinentry and
+// registers passphrase as callback. If passphrase is nil, the context
+// is not changed.
+func setPassphraseFunc(ctx *gpgme.Context, passphrase PassphraseFunc) error {
+	if passphrase == nil {
+		return nil
+	}
+	err := ctx.SetPinEntryMode(gpgme.PinEntryLoopback)
+	if err != nil {
+		return err
+	}
+	return ctx.SetCallback(func(uidHint string, prevWasBad bool, f *os.File) error {
+		p, err := passphrase(uidHint, prevWasBad)
+		if err != nil {
+			return err
+		}
+		_, err = f.WriteString(p + "\n")
+		return err
+	})
+}
+
+// PresetPassphrase stores the passphrase of the secret key with the
+// keygrip in the cache of gpg-agent, like gpg-preset-passphrase, so the
+// following operations do not ask for it, e.g. to unlock a signing key
+// at the start of a service. The keygrips of a key are listed in
+// KeyType.SubKeys.
+// With ttl 0 the passphrase is cached until the agent ends or the cache
+// is flushed. Other values for ttl are passed on, but gpg-agent up to
+// 2.4 rejects them as not implemented.
+// gpg-agent only accepts it with the option allow-preset-passphrase in
+// gpg-agent.conf, see ConfigGpgAgent.
+func PresetPassphrase(keygrip, passphrase string, ttl time.Duration) error {
+	if err := presetPassphrase("", keygrip, passphrase, ttl); err != nil {
+		return fmt.Errorf("PresetPassphrase - %w", err)
+	}
+	return nil
+}
+
+// presetPassphrase implements PresetPassphrase for the agent of homeDir,
+// empty for the one of SetHomeDir.
+func presetPassphrase(homeDir, keygrip, passphrase string, ttl time.Duration) error {
+	if len(keygrip) != 40 || strings.Trim(strings.ToUpper(keygrip), "0123456789ABCDEF") != "" {
+		return fmt.Errorf("not a keygrip: %q", keygrip)
+	}
+	timeout := -1 // no expiry
+	if ttl > 0 {
+		timeout = max(int(ttl/time.Second), 1)
+	}
+	_, err := agentCommand(homeDir, "PRESET_PASSPHRASE "+keygrip+" "+
+		strconv.Itoa(timeout)+" "+strings.ToUpper(hex.EncodeToString([]byte(passphrase))))
+	return err
+}
+
+// ForgetPassphrase removes the passphrase of the secret key with the
+// keygrip from the cache of gpg-agent, e.g. one set by PresetPassphrase.
+func ForgetPassphrase(keygrip string) error {
+	_, err := agentCommand("", "CLEAR_PASSPHRASE --mode=normal "+keygrip)
+	if err != nil {
+		return fmt.Errorf("ForgetPassphrase - %w", err)
+	}
+	return nil
+}
+
+// EOF
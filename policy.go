@@ -0,0 +1,270 @@
+/* policy.go - signature verification policies for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// ErrSignatureRejected is returned if the signatures do not satisfy a
+// VerifyPolicy. The VerifyDecision contains the reasons.
+var ErrSignatureRejected = errors.New("signature rejected by policy")
+
+// VerifyPolicy describes which signatures are acceptable.
+// A signature is acceptable, if it is good and meets all set
+// requirements. The data is accepted, if there is at least one
+// acceptable signature and every required signer made one.
+type VerifyPolicy struct {
+	// RequiredSigners are the fingerprints of the keys which all must
+	// have made an acceptable signature. The fingerprint of the primary
+	// key or of the signing subkey may be given.
+	RequiredSigners []string
+	// MinValidity is the minimum validity of the signing key, e.g.
+	// gpgme.ValidityFull. The zero value (gpgme.ValidityUnknown)
+	// accepts any validity.
+	MinValidity gpgme.Validity
+	// RejectExpiredKeys rejects signatures made by keys which are
+	// expired by now.
+	RejectExpiredKeys bool
+	// MaxSignatureAge rejects signatures older than this. Zero means
+	// no limit.
+	MaxSignatureAge time.Duration
+	// ResolveSigners looks up the signing keys, see ResolveSigners, and
+	// sets them on the decision.
+	ResolveSigners bool
+	// AutoFetchKeys retrieves the keys of signatures by unknown keys, see
+	// FetchSignerKeys, and verifies again. FetchTimeout limits the
+	// retrieval, DefaultFetchTimeout is used if it is 0. Only
+	// VerifyBytesWithPolicy fetches keys.
+	AutoFetchKeys bool
+	FetchTimeout  time.Duration
+}
+
+// VerifyDecision is the result of checking signatures against a
+// VerifyPolicy.
+type VerifyDecision struct {
+	Accepted   bool              // the policy is satisfied
+	Reasons    []string          // why signatures were not acceptable
+	Signatures []gpgme.Signature // all signatures found
+	// Signers are the signatures with their keys, if the policy has
+	// ResolveSigners set and the keys could be looked up.
+	Signers []ResolvedSignature
+	// FetchedKeys are the fingerprints of the keys retrieved because of
+	// AutoFetchKeys.
+	FetchedKeys []string
+}
+
+// VerifyBytesWithPolicy verifies the signed data like VerifyBytes and
+// checks the signatures against policy.
+// If the policy is not satisfied, the error is ErrSignatureRejected and
+// no plain text is returned, the decision tells the reasons.
+func VerifyBytesWithPolicy(cipherText []byte, policy VerifyPolicy) (
+	plainText []byte, decision VerifyDecision, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		err = fmt.Errorf("VerifyBytes - %w", err)
+		return
+	}
+	defer myContext.Release()
+
+	return verifyBytesWithPolicy(myContext, cipherText, policy)
+}
+
+// verifyBytesWithPolicy implements VerifyBytesWithPolicy using myContext.
+func verifyBytesWithPolicy(myContext *gpgme.Context, cipherText []byte,
+	policy VerifyPolicy) (plainText []byte, decision VerifyDecision, err error) {
+
+	plainText, signatures, _, err := verifyBytes(context.Background(), myContext, cipherText)
+	// verifyBytes reports reading until the end of the data as io.EOF
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, decision, err
+	}
+
+	var fetched []string
+	var fetchErr error
+	if policy.AutoFetchKeys && hasMissingKey(signatures) {
+		cmd := gpgCommand{homeDir: contextHomeDir(myContext)}
+		fetched, fetchErr = fetchSignerKeys(cmd, cipherText, policy.FetchTimeout)
+		if len(fetched) > 0 {
+			plainText, signatures, _, err = verifyBytes(context.Background(),
+				myContext, cipherText)
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, decision, err
+			}
+		}
+	}
+
+	decision = checkPolicy(myContext, policy, signatures)
+	decision.FetchedKeys = fetched
+	if fetchErr != nil {
+		decision.Reasons = append(decision.Reasons,
+			fmt.Sprintf("fetching the signer keys failed: %v", fetchErr))
+	}
+	if !decision.Accepted {
+		return nil, decision, fmt.Errorf("VerifyBytes - %w", ErrSignatureRejected)
+	}
+	return plainText, decision, nil
+}
+
+// DecryptFileWithPolicy decrypts a file like DecryptFile and checks the
+// signatures of the data against policy.
+// If the policy is not satisfied, the decrypted file is removed and the
+// error is ErrSignatureRejected, the decision tells the reasons.
+func DecryptFileWithPolicy(cypherFilename, clearFilename string, policy VerifyPolicy) (
+	decryptionResult gpgme.DecryptResultType, filename string,
+	decision VerifyDecision, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		err = fmt.Errorf("DecryptFile - %w", err)
+		return
+	}
+	defer myContext.Release()
+
+	return decryptFileWithPolicy(myContext, cypherFilename, clearFilename, policy)
+}
+
+// decryptFileWithPolicy implements DecryptFileWithPolicy using myContext.
+func decryptFileWithPolicy(myContext *gpgme.Context, cypherFilename,
+	clearFilename string, policy VerifyPolicy) (
+	decryptionResult gpgme.DecryptResultType, filename string,
+	decision VerifyDecision, err error) {
+
+	destination, err := decryptDestination(cypherFilename, clearFilename)
+	if err != nil {
+		err = fmt.Errorf("DecryptFile - %w", err)
+		return
+	}
+
+	decryptionResult, filename, signatures, _, err := decryptFile(myContext,
+		cypherFilename, destination)
+	if err != nil {
+		return
+	}
+
+	decision = checkPolicy(myContext, policy, signatures)
+	if !decision.Accepted {
+		if rmErr := os.Remove(destination); rmErr != nil {
+			err = fmt.Errorf("DecryptFile - %w, removing %s failed: %w",
+				ErrSignatureRejected, destination, rmErr)
+			return
+		}
+		err = fmt.Errorf("DecryptFile - %w", ErrSignatureRejected)
+	}
+	return
+}
+
+// checkPolicy checks the signatures against policy. The signing keys
+// are looked up with myContext.
+func checkPolicy(myContext *gpgme.Context, policy VerifyPolicy,
+	signatures []gpgme.Signature) (decision VerifyDecision) {
+
+	decision.Signatures = signatures
+	if policy.ResolveSigners {
+		// a failed lookup leaves the signers unknown, the decision does
+		// not depend on them
+		decision.Signers, _ = resolveSigners(myContext, signatures)
+	}
+	if len(signatures) == 0 {
+		decision.Reasons = append(decision.Reasons, "no signature found")
+		return decision
+	}
+
+	signedBy := make(map[string]bool)
+	acceptable := 0
+	for _, sig := range signatures {
+		reason := signatureProblem(policy, sig)
+		if reason != "" {
+			decision.Reasons = append(decision.Reasons,
+				fmt.Sprintf("signature by %s: %s", sig.Fingerprint, reason))
+			continue
+		}
+		acceptable++
+		for _, fpr := range signingKeyFingerprints(myContext, sig.Fingerprint) {
+			signedBy[strings.ToUpper(fpr)] = true
+		}
+	}
+
+	if acceptable == 0 {
+		decision.Reasons = append(decision.Reasons, "no acceptable signature")
+	}
+	missing := false
+	for _, fpr := range policy.RequiredSigners {
+		if !signedBy[NormalizeFingerprint(fpr)] {
+			decision.Reasons = append(decision.Reasons,
+				fmt.Sprintf("no acceptable signature by required signer %s", fpr))
+			missing = true
+		}
+	}
+	decision.Accepted = acceptable > 0 && !missing
+	return decision
+}
+
+// signatureProblem returns why the signature does not satisfy the
+// policy, or an empty string if it does.
+func signatureProblem(policy VerifyPolicy, sig gpgme.Signature) string {
+	switch {
+	case sig.Status != nil:
+		return fmt.Sprintf("bad signature: %v", sig.Status)
+	case sig.Summary&gpgme.SigSumKeyRevoked != 0:
+		return "key revoked"
+	case sig.Summary&gpgme.SigSumSigExpired != 0:
+		return "signature expired"
+	case policy.RejectExpiredKeys && sig.Summary&gpgme.SigSumKeyExpired != 0:
+		return "key expired"
+	case sig.Validity < policy.MinValidity:
+		return fmt.Sprintf("validity %s below %s",
+			GnuPGValidity2String(sig.Validity),
+			GnuPGValidity2String(policy.MinValidity))
+	case policy.MaxSignatureAge > 0 && time.Since(sig.Timestamp) > policy.MaxSignatureAge:
+		return fmt.Sprintf("signature made %s is too old",
+			sig.Timestamp.Format(time.RFC3339))
+	}
+	return ""
+}
+
+// signingKeyFingerprints returns the fingerprints of the primary key and
+// all subkeys of the key which made a signature with the (sub)key
+// fingerprint. If the key is unknown, only fingerprint is returned.
+func signingKeyFingerprints(myContext *gpgme.Context, fingerprint string) []string {
+	key, err := myContext.GetKey(fingerprint, false)
+	if err != nil || key == nil {
+		return []string{fingerprint}
+	}
+	defer key.Release()
+
+	fprs := []string{fingerprint}
+	for sk := key.SubKeys(); sk != nil; sk = sk.Next() {
+		fprs = append(fprs, sk.Fingerprint())
+	}
+	return fprs
+}
+
+// EOF
@@ -0,0 +1,206 @@
+/* pool.go - pool of gpgme contexts for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// A Session serializes its operations, because a gpgme context must not
+// be used concurrently. A ContextPool holds several sessions with the
+// same options, so a server can run that many operations in parallel
+// without setting up a new context for each.
+
+package gpggohigh
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// ErrPoolClosed is returned by the operations of a closed ContextPool.
+var ErrPoolClosed = errors.New("context pool is closed")
+
+// ContextPool is a pool of sessions, which share the same options and
+// thus the protocol. Use one pool per protocol. The sessions are created
+// on demand, at most size of them.
+// A ContextPool may be used by several goroutines.
+type ContextPool struct {
+	opts  SessionOptions
+	slots chan struct{} // one entry per created session
+	idle  chan *Session
+	done  chan struct{} // closed by Close
+
+	// mu orders release and Close, so no session is put into idle after
+	// Close has drained it.
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewContextPool creates a pool of at most size sessions with the options
+// opts. If size is less than 1, the number of CPUs is used.
+func NewContextPool(opts SessionOptions, size int) (*ContextPool, error) {
+	if size < 1 {
+		size = runtime.GOMAXPROCS(0)
+	}
+	pool := &ContextPool{
+		opts:  opts,
+		slots: make(chan struct{}, size),
+		idle:  make(chan *Session, size),
+		done:  make(chan struct{}),
+	}
+
+	// create the first session, so wrong options are reported here
+	s, err := pool.acquire()
+	if err != nil {
+		return nil, fmt.Errorf("NewContextPool - %w", err)
+	}
+	pool.release(s)
+	return pool, nil
+}
+
+// Size returns the maximal number of sessions of the pool.
+func (p *ContextPool) Size() int {
+	return cap(p.slots)
+}
+
+// Do runs fn with a session of the pool, waiting for one to become free
+// if all are in use. The session must not be used after fn returns.
+func (p *ContextPool) Do(fn func(s *Session) error) error {
+	s, err := p.acquire()
+	if err != nil {
+		return err
+	}
+	defer p.release(s)
+	return fn(s)
+}
+
+// acquire returns an idle session or creates a new one, if the pool is
+// not full yet.
+func (p *ContextPool) acquire() (*Session, error) {
+	select {
+	case <-p.done:
+		return nil, ErrPoolClosed
+	case s := <-p.idle:
+		return s, nil
+	default:
+	}
+
+	select {
+	case <-p.done:
+		return nil, ErrPoolClosed
+	case s := <-p.idle:
+		return s, nil
+	case p.slots <- struct{}{}:
+		s, err := NewSession(p.opts)
+		if err != nil {
+			<-p.slots
+			return nil, err
+		}
+		return s, nil
+	}
+}
+
+// release returns the session s to the pool, or closes it, if the pool
+// is closed.
+func (p *ContextPool) release(s *Session) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		_ = s.Close()
+		<-p.slots
+		return
+	}
+	p.idle <- s // never blocks, idle has room for all sessions
+}
+
+// Close closes the idle sessions of the pool, the sessions in use are
+// closed when they are released. Operations started afterwards fail with
+// ErrPoolClosed.
+func (p *ContextPool) Close() (err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	close(p.done)
+	for len(p.idle) > 0 {
+		s := <-p.idle
+		err = errors.Join(err, s.Close())
+		<-p.slots
+	}
+	if err != nil {
+		return fmt.Errorf("ContextPool.Close - %w", err)
+	}
+	return nil
+}
+
+var defaultPool atomic.Pointer[ContextPool]
+
+// SetDefaultPool makes the package functions DecryptBytes and VerifyBytes
+// run with a session of pool instead of creating a context for each
+// call, which pays off for many concurrent calls. VerifyManifest uses the
+// sessions of pool for its workers. The options of the pool apply like
+// for the Session methods, e.g. its HomeDir, RequireCompliance and Retry.
+// nil restores the default.
+func SetDefaultPool(pool *ContextPool) {
+	defaultPool.Store(pool)
+}
+
+// EncryptBytes encrypts a memory buffer with a session of the pool like
+// Session.EncryptBytes.
+func (p *ContextPool) EncryptBytes(plainText []byte, recipients []string, sign bool) (
+	cipherText []byte, err error) {
+
+	err = p.Do(func(s *Session) error {
+		cipherText, err = s.EncryptBytes(plainText, recipients, sign)
+		return err
+	})
+	return cipherText, err
+}
+
+// DecryptBytes decrypts a memory buffer with a session of the pool like
+// Session.DecryptBytes.
+func (p *ContextPool) DecryptBytes(cipherText []byte) (plainText []byte,
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+
+	err = p.Do(func(s *Session) error {
+		plainText, decryptionResult, filename, signatures, warning, err =
+			s.DecryptBytes(cipherText)
+		return err
+	})
+	return
+}
+
+// VerifyBytes verifies signed data with a session of the pool like
+// Session.VerifyBytes.
+func (p *ContextPool) VerifyBytes(cipherText []byte) (plainText []byte,
+	signatures []gpgme.Signature, filename string, err error) {
+
+	err = p.Do(func(s *Session) error {
+		plainText, signatures, filename, err = s.VerifyBytes(cipherText)
+		return err
+	})
+	return
+}
+
+// EOF
@@ -0,0 +1,118 @@
+/* pool_test.go - tests of the pool of gpgme contexts
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/gnupg-com/gpggohigh"
+	"github.com/gnupg-com/gpggohigh/testsupport"
+)
+
+// newPool returns a pool with the secret key of Alice, which is closed
+// at the end of the test.
+func newPool(tb testing.TB, size int) *gpggohigh.ContextPool {
+	tb.Helper()
+	home := testsupport.Home(tb, nil, []testsupport.TestKey{testsupport.Alice})
+	pool, err := gpggohigh.NewContextPool(home.Options(), size)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		if err := pool.Close(); err != nil {
+			tb.Error(err)
+		}
+	})
+	return pool
+}
+
+func TestContextPoolCloseWhileInUse(t *testing.T) {
+	pool := newPool(t, 4)
+
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := pool.Do(func(s *gpggohigh.Session) error { return nil })
+			if err != nil && !errors.Is(err, gpggohigh.ErrPoolClosed) {
+				t.Error(err)
+			}
+		}()
+	}
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	err := pool.Do(func(s *gpggohigh.Session) error { return nil })
+	if !errors.Is(err, gpggohigh.ErrPoolClosed) {
+		t.Fatalf("Do after Close: got %v, want ErrPoolClosed", err)
+	}
+}
+
+func BenchmarkContextPoolDecryptBytes(b *testing.B) {
+	pool := newPool(b, 0)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _, _, _, _, err := pool.DecryptBytes(testsupport.Encrypted)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkContextPoolVerifyBytes(b *testing.B) {
+	pool := newPool(b, 0)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _, _, err := pool.VerifyBytes(testsupport.Signed)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkSessionVerifyBytes is the baseline of a single session, which
+// serializes the operations.
+func BenchmarkSessionVerifyBytes(b *testing.B) {
+	home := testsupport.Home(b, nil, []testsupport.TestKey{testsupport.Alice})
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _, _, err := home.VerifyBytes(testsupport.Signed)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// EOF
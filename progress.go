@@ -0,0 +1,111 @@
+/* progress.go - progress reporting for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// gpgme.go does not bind the progress callback of gpgme, so the progress
+// is measured on the data read by gpgme.
+
+package gpggohigh
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// ProgressFunc is called while data is processed with the number of
+// bytes processed so far and the total number of bytes, or -1 if the
+// total is not known.
+type ProgressFunc func(processed, total int64)
+
+// progressReader is an io.Reader which reports the bytes read.
+type progressReader struct {
+	r         io.Reader
+	total     int64
+	processed int64
+	progress  ProgressFunc
+}
+
+// NewProgressReader returns a reader which reads from r and calls
+// progress after each read. total is the expected size or -1 if unknown.
+// It can be used to get the progress of EncryptStream and DecryptStream.
+func NewProgressReader(r io.Reader, total int64, progress ProgressFunc) io.Reader {
+	if progress == nil {
+		return r
+	}
+	return &progressReader{r: r, total: total, progress: progress}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.processed += int64(n)
+		p.progress(p.processed, p.total)
+	}
+	return n, err
+}
+
+// fileProgressReader returns a reader for the opened file fh which
+// reports the progress with the size of the file as total.
+func fileProgressReader(fh *os.File, progress ProgressFunc) io.Reader {
+	if progress == nil {
+		return fh
+	}
+	total := int64(-1)
+	if info, err := fh.Stat(); err == nil && info.Mode().IsRegular() {
+		total = info.Size()
+	}
+	return NewProgressReader(fh, total, progress)
+}
+
+// EncryptFileProgress encrypts a file like EncryptFileCtx and reports
+// the progress of reading the source file to progress.
+func EncryptFileProgress(ctx context.Context, sourceFilename, destinationFilename string,
+	recipients []string, sign bool, progress ProgressFunc) (err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return fmt.Errorf("EncryptFile - %w", err)
+	}
+	defer myContext.Release()
+
+	return encryptFileCtx(ctx, myContext, sourceFilename, destinationFilename,
+		recipients, sign, progress)
+}
+
+// DecryptFileProgress decrypts a file like DecryptFileCtx and reports
+// the progress of reading the encrypted file to progress.
+func DecryptFileProgress(ctx context.Context, cypherFilename, clearFilename string,
+	progress ProgressFunc) (decryptionResult gpgme.DecryptResultType,
+	filename string, signatures []gpgme.Signature, warning string, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		err = fmt.Errorf("DecryptFile - %w", err)
+		return
+	}
+	defer myContext.Release()
+
+	return decryptFileCtx(ctx, myContext, cypherFilename, clearFilename, progress)
+}
+
+// EOF
@@ -0,0 +1,311 @@
+/* recipients.go - recipient resolution for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// Errors returned by EncryptFileStrict for recipients which do not
+// select exactly one usable key.
+var (
+	ErrRecipientNotFound  = errors.New("no usable key found for recipient")
+	ErrAmbiguousRecipient = errors.New("recipient matches several keys")
+)
+
+// RecipientProblem describes why a recipient needs attention.
+type RecipientProblem int
+
+const (
+	RecipientNotFound  RecipientProblem = iota + 1 // no usable key matches
+	RecipientAmbiguous                             // several usable keys match
+	RecipientExpired                               // a matching key is expired
+	RecipientRevoked                               // a matching key is revoked
+	RecipientUnusable                              // a matching key is disabled, invalid or can not encrypt
+	RecipientDenied                                // a matching key is on the deny-list of a RecipientPolicy
+)
+
+// RecipientProblemString maps a RecipientProblem to a readable text.
+var RecipientProblemString = map[RecipientProblem]string{
+	RecipientNotFound:  "not found",
+	RecipientAmbiguous: "ambiguous",
+	RecipientExpired:   "expired",
+	RecipientRevoked:   "revoked",
+	RecipientUnusable:  "unusable",
+	RecipientDenied:    "denied",
+}
+
+// RecipientWarning is a problem found while resolving a recipient.
+type RecipientWarning struct {
+	Recipient   string           // the text selecting the recipient
+	Problem     RecipientProblem // what is wrong
+	Fingerprint string           // the concerned key, empty for RecipientNotFound
+}
+
+// String returns a readable description of the warning.
+func (w RecipientWarning) String() string {
+	if w.Fingerprint == "" {
+		return fmt.Sprintf("recipient %q: %s", w.Recipient, RecipientProblemString[w.Problem])
+	}
+	return fmt.Sprintf("recipient %q: %s key %s", w.Recipient,
+		RecipientProblemString[w.Problem], w.Fingerprint)
+}
+
+// ResolveRecipients looks up the keys selected by the recipients texts,
+// like EncryptFile does, but reports what may be unexpected:
+// recipients without a usable key, recipients matching several usable
+// keys and matching keys which are expired, revoked or otherwise not
+// usable for encryption. Keys which are not usable are not returned.
+func ResolveRecipients(recipients []string) (keys []*gpgme.Key,
+	warnings []RecipientWarning, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("ResolveRecipients - %w", err)
+	}
+	defer myContext.Release()
+
+	keys, warnings, err = resolveRecipients(myContext, recipients)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ResolveRecipients - %w", err)
+	}
+	return keys, warnings, nil
+}
+
+// resolveRecipients implements ResolveRecipients using myContext.
+func resolveRecipients(myContext *gpgme.Context, recipients []string) (
+	keys []*gpgme.Key, warnings []RecipientWarning, err error) {
+
+	for _, r := range recipients {
+		found, err := findKeys(myContext, r, false)
+		if err != nil {
+			return nil, nil, fmt.Errorf("FindKeys failed: %w", err)
+		}
+
+		var usable []*gpgme.Key
+		for _, k := range found {
+			problem := recipientKeyProblem(k)
+			if problem != 0 {
+				warnings = append(warnings, RecipientWarning{
+					Recipient: r, Problem: problem, Fingerprint: k.Fingerprint()})
+				continue
+			}
+			usable = append(usable, k)
+		}
+
+		switch {
+		case len(usable) == 0:
+			warnings = append(warnings, RecipientWarning{Recipient: r,
+				Problem: RecipientNotFound})
+		case len(usable) > 1:
+			for _, k := range usable {
+				warnings = append(warnings, RecipientWarning{Recipient: r,
+					Problem: RecipientAmbiguous, Fingerprint: k.Fingerprint()})
+			}
+		}
+		keys = append(keys, usable...)
+	}
+	return keys, warnings, nil
+}
+
+// recipientKeyProblem returns why the key can not be used for
+// encryption, or 0 if it can.
+func recipientKeyProblem(k *gpgme.Key) RecipientProblem {
+	switch {
+	case k.Revoked():
+		return RecipientRevoked
+	case k.Expired():
+		return RecipientExpired
+	case k.Disabled() || k.Invalid() || !k.CanEncrypt():
+		return RecipientUnusable
+	}
+	return 0
+}
+
+// ErrRecipientDenied is matched by the RecipientPolicyError returned if
+// a recipient key is rejected by a RecipientPolicy.
+var ErrRecipientDenied = errors.New("recipient key denied by policy")
+
+// RecipientPolicy describes which keys must not be used as recipients,
+// e.g. keys of former employees or keys known to be compromised.
+type RecipientPolicy struct {
+	// DenyFingerprints are the fingerprints of banned keys. The
+	// fingerprint of the primary key or of any subkey may be given.
+	DenyFingerprints []string
+	// DenyRevoked rejects revoked recipient keys.
+	DenyRevoked bool
+	// DenyExpired rejects expired recipient keys.
+	DenyExpired bool
+}
+
+// RecipientPolicyError lists the recipient keys rejected by a
+// RecipientPolicy. It matches ErrRecipientDenied with errors.Is.
+type RecipientPolicyError struct {
+	Denied []RecipientWarning // the offending keys
+}
+
+// Error returns a readable description of the rejected keys.
+func (e *RecipientPolicyError) Error() string {
+	texts := make([]string, len(e.Denied))
+	for i, d := range e.Denied {
+		texts[i] = d.String()
+	}
+	return fmt.Sprintf("%s: %s", ErrRecipientDenied, strings.Join(texts, ", "))
+}
+
+// Unwrap returns ErrRecipientDenied.
+func (e *RecipientPolicyError) Unwrap() error {
+	return ErrRecipientDenied
+}
+
+// checkRecipientPolicy looks up the keys selected by recipients like the
+// encryption does and returns a *RecipientPolicyError, if any of them is
+// rejected by policy.
+func checkRecipientPolicy(myContext *gpgme.Context, recipients []string,
+	policy RecipientPolicy) error {
+
+	denied := make(map[string]bool)
+	for _, fpr := range policy.DenyFingerprints {
+		denied[NormalizeFingerprint(fpr)] = true
+	}
+
+	var problems []RecipientWarning
+	for _, r := range recipients {
+		found, err := findKeys(myContext, r, false)
+		if err != nil {
+			return fmt.Errorf("FindKeys failed: %w", err)
+		}
+		for _, k := range found {
+			var problem RecipientProblem
+			switch {
+			case isDeniedKey(k, denied):
+				problem = RecipientDenied
+			case policy.DenyRevoked && k.Revoked():
+				problem = RecipientRevoked
+			case policy.DenyExpired && k.Expired():
+				problem = RecipientExpired
+			default:
+				continue
+			}
+			problems = append(problems, RecipientWarning{Recipient: r,
+				Problem: problem, Fingerprint: k.Fingerprint()})
+		}
+	}
+	if len(problems) > 0 {
+		return &RecipientPolicyError{Denied: problems}
+	}
+	return nil
+}
+
+// isDeniedKey reports whether the primary key or a subkey of k has one of
+// the upper case fingerprints in denied.
+func isDeniedKey(k *gpgme.Key, denied map[string]bool) bool {
+	if denied[strings.ToUpper(k.Fingerprint())] {
+		return true
+	}
+	for sk := k.SubKeys(); sk != nil; sk = sk.Next() {
+		if denied[strings.ToUpper(sk.Fingerprint())] {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultKey returns the own key of the user: the default-key set in
+// gpg.conf, or the fingerprint of the first secret key in the keyring,
+// which gpg also uses if no default key is set.
+func DefaultKey() (string, error) {
+	key, err := defaultKey("")
+	if err != nil {
+		return "", fmt.Errorf("DefaultKey - %w", err)
+	}
+	return key, nil
+}
+
+// defaultKey implements DefaultKey for the GnuPG home directory homeDir,
+// empty for the one of SetHomeDir.
+func defaultKey(homeDir string) (string, error) {
+	if homeDir == "" {
+		_, homeDir = gpgEngine()
+	}
+	out, err := runGpgconfHome(homeDir, "--list-options", "gpg")
+	if err == nil {
+		for _, line := range strings.Split(string(out), "\n") {
+			fields := strings.Split(line, ":")
+			// the value of string options is prefixed with a quote
+			if fields[0] == "default-key" && len(fields) > 9 && len(fields[9]) > 1 {
+				return unescapeStatus(fields[9][1:]), nil
+			}
+		}
+	}
+
+	myContext, err := newContext(SessionOptions{HomeDir: homeDir})
+	if err != nil {
+		return "", err
+	}
+	defer myContext.Release()
+
+	keys, err := findKeys(myContext, "", true)
+	if err != nil {
+		return "", fmt.Errorf("FindKeys failed: %w", err)
+	}
+	if len(keys) == 0 {
+		return "", fmt.Errorf("no secret key: %w", ErrKeyNotFound)
+	}
+	return keys[0].Fingerprint(), nil
+}
+
+// AppendSelf returns recipients with the own key of the user added, see
+// DefaultKey, so the encrypted data can also be decrypted by the user.
+func AppendSelf(recipients []string) ([]string, error) {
+	key, err := DefaultKey()
+	if err != nil {
+		return nil, fmt.Errorf("AppendSelf - %w", err)
+	}
+	return append(slices.Clip(recipients), key), nil
+}
+
+// strictRecipients resolves the recipients and fails, if a recipient
+// does not select exactly one usable key.
+func strictRecipients(myContext *gpgme.Context, recipients []string) (
+	[]*gpgme.Key, error) {
+
+	keys, warnings, err := resolveRecipients(myContext, recipients)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range warnings {
+		switch w.Problem {
+		case RecipientNotFound:
+			return nil, fmt.Errorf("%w: %s", ErrRecipientNotFound, w.Recipient)
+		case RecipientAmbiguous:
+			return nil, fmt.Errorf("%w: %s", ErrAmbiguousRecipient, w.Recipient)
+		}
+	}
+	return appendAdditionalKeys(myContext, keys)
+}
+
+// EOF
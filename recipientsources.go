@@ -0,0 +1,135 @@
+/* recipientsources.go - recipients given as keys, fingerprints or public keys
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// gpg only encrypts to keys in its keyring. Public keys supplied with
+// the request are imported into an ephemeral home directory, see
+// NewEphemeralHome, together with the other recipient keys exported
+// from the keyring, and the encryption is done there.
+
+package gpggohigh
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// Recipient is a recipient of EncryptBytesTo. Exactly one of the fields
+// must be set.
+type Recipient struct {
+	Key         *gpgme.Key // a key of the keyring, e.g. from ResolveRecipients
+	Fingerprint string     // the fingerprint of a key of the keyring
+	PublicKey   []byte     // public keys, binary or ASCII armored
+}
+
+// EncryptBytesTo encrypts a memory buffer like EncryptBytes to recipients
+// given as keys, fingerprints or public keys. If public keys are given,
+// they are imported into a temporary home directory together with the
+// other recipient keys and the additional decryption keys, and the data
+// is encrypted there, so stateless services can encrypt to keys supplied
+// in a request without changing the keyring. The temporary home
+// directory is removed afterwards. The supplied keys are trusted like
+// the ones of EncryptBytes. sign is not supported with public keys,
+// because the temporary keyring has no secret key.
+func EncryptBytesTo(plainText []byte, recipients []Recipient, sign, armored bool) (
+	cipherText []byte, err error) {
+
+	myContext, err := newContext(SessionOptions{Armor: armored})
+	if err != nil {
+		return nil, fmt.Errorf("EncryptBytesTo - %w", err)
+	}
+	defer myContext.Release()
+
+	return encryptBytesTo(myContext, plainText, recipients, sign, armored)
+}
+
+// encryptBytesTo implements EncryptBytesTo, the keys and fingerprints
+// are looked up using myContext.
+func encryptBytesTo(myContext *gpgme.Context, plainText []byte, recipients []Recipient,
+	sign, armored bool) (cipherText []byte, err error) {
+
+	var fingerprints []string // of the keys in the keyring
+	var publicKeys [][]byte
+	for i, r := range recipients {
+		switch {
+		case r.Key != nil && r.Fingerprint == "" && len(r.PublicKey) == 0:
+			fingerprints = append(fingerprints, r.Key.Fingerprint())
+		case r.Key == nil && r.Fingerprint != "" && len(r.PublicKey) == 0:
+			fpr := NormalizeFingerprint(r.Fingerprint)
+			if !isFingerprint(fpr) {
+				return nil, fmt.Errorf("EncryptBytesTo - recipient %d: not a fingerprint: %q",
+					i, r.Fingerprint)
+			}
+			fingerprints = append(fingerprints, fpr)
+		case r.Key == nil && r.Fingerprint == "" && len(r.PublicKey) > 0:
+			publicKeys = append(publicKeys, r.PublicKey)
+		default:
+			return nil, fmt.Errorf("EncryptBytesTo - recipient %d: "+
+				"exactly one of Key, Fingerprint and PublicKey must be set", i)
+		}
+	}
+	if len(fingerprints) == 0 && len(publicKeys) == 0 {
+		return nil, fmt.Errorf("EncryptBytesTo - no recipients given")
+	}
+	if len(publicKeys) == 0 {
+		return encryptBytes(context.Background(), myContext, plainText, fingerprints, sign)
+	}
+	if sign {
+		return nil, fmt.Errorf("EncryptBytesTo - signing is not supported with public keys")
+	}
+
+	home, err := NewEphemeralHome()
+	if err != nil {
+		return nil, fmt.Errorf("EncryptBytesTo - %w", err)
+	}
+	defer func() {
+		if closeErr := home.Close(); closeErr != nil && err == nil {
+			cipherText, err = nil, fmt.Errorf("EncryptBytesTo - %w", closeErr)
+		}
+	}()
+	home.ctx.SetArmor(armored)
+
+	// the keys of the keyring are needed in the temporary one as well
+	for _, fpr := range slices.Concat(fingerprints, AdditionalDecryptionKeys()) {
+		keyData, err := exportKeyMinimal(myContext, fpr)
+		if err != nil {
+			return nil, fmt.Errorf("EncryptBytesTo - %w", err)
+		}
+		if _, err := home.ImportKeys(keyData); err != nil {
+			return nil, fmt.Errorf("EncryptBytesTo - %w", err)
+		}
+	}
+	for i, keyData := range publicKeys {
+		result, err := home.ImportKeys(keyData)
+		if err != nil {
+			return nil, fmt.Errorf("EncryptBytesTo - public key %d: %w", i, err)
+		}
+		imported := ImportedFingerprints(result)
+		if len(imported) == 0 {
+			return nil, fmt.Errorf("EncryptBytesTo - public key %d: no key imported", i)
+		}
+		fingerprints = append(fingerprints, imported...)
+	}
+	return encryptBytes(context.Background(), home.ctx, plainText, fingerprints, false)
+}
+
+// EOF
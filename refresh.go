@@ -0,0 +1,245 @@
+/* refresh.go - keyring synchronization for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// The keys are fetched by gpg itself, the changes are found by comparing
+// the key listings before and after the refresh.
+
+package gpggohigh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ExpiryChangeType is a changed expiration time of a key or subkey.
+type ExpiryChangeType struct {
+	Fingerprint string    // of the primary key or subkey
+	Old         time.Time // zero if it did not expire
+	New         time.Time // zero if it does not expire anymore
+}
+
+// KeyChangeType describes how a key changed by a refresh.
+type KeyChangeType struct {
+	Fingerprint    string             // of the primary key
+	UserID         string             // the primary user ID
+	NewSignatures  int                // number of new user ID signatures
+	NewUserIDs     []string           // user IDs added to the key
+	RevokedUserIDs []string           // user IDs revoked since
+	RemovedUserIDs []string           // user IDs no longer on the key
+	NewSubKeys     []string           // fingerprints of subkeys added to the key
+	RevokedSubKeys []string           // fingerprints of subkeys revoked since
+	RemovedSubKeys []string           // fingerprints of subkeys no longer on the key
+	Revoked        bool               // the key has been revoked
+	ExpiryChanges  []ExpiryChangeType // changed expiration times
+
+	// NewCertifications are the new signatures and revocations of other
+	// keys on the user IDs, only found if the keys were listed with their
+	// signatures.
+	NewCertifications []CertificationType
+}
+
+// RefreshReportType is the result of RefreshKeys.
+type RefreshReportType struct {
+	Changes   []KeyChangeType // the keys which changed
+	Unchanged []string        // fingerprints of the keys without changes
+	NotFound  []string        // patterns not matching a key in the keyring
+	Errors    []error         // failed fetches, the other keys were refreshed
+}
+
+// RefreshKeys fetches the current versions of the keys of the keyring
+// matching patterns from the keyserver configured for gpg and from the
+// Web Key Directory of their mail addresses, imports them and reports
+// the changes, e.g. to keep the recipient keys of a service current.
+// Keys are only updated, new keys are not imported. Failures of single
+// fetches are reported in the report, an error is only returned, if no
+// fetch succeeded.
+func RefreshKeys(patterns []string) (report RefreshReportType, err error) {
+	report, err = refreshKeys(gpgCommand{}, patterns)
+	if err != nil {
+		return report, fmt.Errorf("RefreshKeys - %w", err)
+	}
+	return report, nil
+}
+
+// refreshKeys implements RefreshKeys running cmd with the arguments set.
+func refreshKeys(cmd gpgCommand, patterns []string) (report RefreshReportType,
+	err error) {
+
+	if len(patterns) == 0 {
+		return report, fmt.Errorf("no patterns given")
+	}
+
+	myContext, err := newContext(SessionOptions{HomeDir: cmd.homeDir})
+	if err != nil {
+		return report, err
+	}
+	defer myContext.Release()
+
+	before := make(map[string]KeyType)
+	var fingerprints, emails []string
+	for _, pattern := range patterns {
+		keys, err := keyList(context.Background(), myContext, pattern)
+		if err != nil {
+			return report, err
+		}
+		if len(keys) == 0 {
+			report.NotFound = append(report.NotFound, pattern)
+		}
+		for _, k := range keys {
+			if _, ok := before[k.Fingerprint]; ok {
+				continue
+			}
+			before[k.Fingerprint] = k
+			fingerprints = append(fingerprints, k.Fingerprint)
+			for _, uid := range k.UserIDs {
+				if uid.Address != "" && !slices.Contains(emails, uid.Address) {
+					emails = append(emails, uid.Address)
+				}
+			}
+		}
+	}
+	if len(fingerprints) == 0 {
+		return report, fmt.Errorf("%w for %s", ErrKeyNotFound, strings.Join(patterns, ", "))
+	}
+
+	fetched := 0
+	cmd.args = append([]string{"--refresh-keys", "--"}, fingerprints...)
+	if _, err := runImport(cmd, "RefreshKeys"); err != nil {
+		report.Errors = append(report.Errors, fmt.Errorf("%s: %w", KeySourceKeyserver, err))
+	} else {
+		fetched++
+	}
+	for _, email := range emails {
+		cmd.args = []string{"--auto-key-locate", "clear,nodefault," + KeySourceWKD,
+			"--locate-external-keys", "--", email}
+		if _, err := runImport(cmd, "RefreshKeys"); err != nil {
+			report.Errors = append(report.Errors,
+				fmt.Errorf("%s %s: %w", KeySourceWKD, email, err))
+			continue
+		}
+		fetched++
+	}
+	if fetched == 0 {
+		return report, errors.Join(report.Errors...)
+	}
+
+	for _, fpr := range fingerprints {
+		after, err := keyList(context.Background(), myContext, fpr)
+		if err != nil {
+			return report, err
+		}
+		if len(after) == 0 {
+			// deleted meanwhile, nothing to compare
+			continue
+		}
+		change, changed := keyChange(before[fpr], after[0])
+		if changed {
+			report.Changes = append(report.Changes, change)
+		} else {
+			report.Unchanged = append(report.Unchanged, fpr)
+		}
+	}
+	return report, nil
+}
+
+// keyChange compares two listings of the same key and reports, whether
+// and how it changed.
+func keyChange(old, key KeyType) (change KeyChangeType, changed bool) {
+	change.Fingerprint = key.Fingerprint
+	if len(key.UserIDs) > 0 {
+		change.UserID = key.UserIDs[0].UserID
+	}
+	change.Revoked = key.Revoked && !old.Revoked
+
+	oldUIDs := make(map[string]KeyUserIDsType)
+	for _, uid := range old.UserIDs {
+		oldUIDs[uid.UserID] = uid
+	}
+	for _, uid := range key.UserIDs {
+		o, ok := oldUIDs[uid.UserID]
+		switch {
+		case !ok:
+			change.NewUserIDs = append(change.NewUserIDs, uid.UserID)
+		case uid.Revoked && !o.Revoked:
+			change.RevokedUserIDs = append(change.RevokedUserIDs, uid.UserID)
+		}
+		if n := signatureCount(uid) - signatureCount(o); n > 0 {
+			change.NewSignatures += n
+		}
+		change.NewCertifications = append(change.NewCertifications,
+			newCertifications(key.KeyID, o, uid)...)
+	}
+	for _, uid := range old.UserIDs {
+		if !slices.ContainsFunc(key.UserIDs, func(u KeyUserIDsType) bool {
+			return u.UserID == uid.UserID
+		}) {
+			change.RemovedUserIDs = append(change.RemovedUserIDs, uid.UserID)
+		}
+	}
+
+	oldSubKeys := make(map[string]SubKeyType)
+	for _, sk := range old.SubKeys {
+		oldSubKeys[sk.Fingerprint] = sk
+	}
+	for _, sk := range key.SubKeys {
+		o, ok := oldSubKeys[sk.Fingerprint]
+		if !ok {
+			change.NewSubKeys = append(change.NewSubKeys, sk.Fingerprint)
+			continue
+		}
+		// the primary key is the first subkey, its revocation is reported
+		// for the key
+		if sk.Revoked && !o.Revoked && sk.Fingerprint != key.Fingerprint {
+			change.RevokedSubKeys = append(change.RevokedSubKeys, sk.Fingerprint)
+		}
+		if !sk.Expires.Equal(o.Expires) {
+			change.ExpiryChanges = append(change.ExpiryChanges, ExpiryChangeType{
+				Fingerprint: sk.Fingerprint, Old: o.Expires, New: sk.Expires})
+		}
+	}
+	for _, sk := range old.SubKeys {
+		if !slices.ContainsFunc(key.SubKeys, func(s SubKeyType) bool {
+			return s.Fingerprint == sk.Fingerprint
+		}) {
+			change.RemovedSubKeys = append(change.RemovedSubKeys, sk.Fingerprint)
+		}
+	}
+
+	changed = change.Revoked || change.NewSignatures > 0 ||
+		len(change.NewUserIDs) > 0 || len(change.RevokedUserIDs) > 0 ||
+		len(change.RemovedUserIDs) > 0 || len(change.NewSubKeys) > 0 ||
+		len(change.RevokedSubKeys) > 0 || len(change.RemovedSubKeys) > 0 ||
+		len(change.NewCertifications) > 0 || len(change.ExpiryChanges) > 0
+	return change, changed
+}
+
+// signatureCount returns the number of signatures of a user ID.
+func signatureCount(uid KeyUserIDsType) (n int) {
+	for _, sigs := range uid.Signatures {
+		n += len(sigs)
+	}
+	return n
+}
+
+// EOF
@@ -0,0 +1,158 @@
+/* report.go - rendering of verification and decryption results
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// ReportFormat selects the output of RenderVerification.
+type ReportFormat int
+
+const (
+	ReportText  ReportFormat = iota // readable text, a block for each signature
+	ReportJSON                      // a JSON object, see SignatureJSONType
+	ReportTable                     // a line for each signature with aligned columns
+)
+
+// VerificationReport is the result of a verification or decryption
+// rendered by RenderVerification.
+type VerificationReport struct {
+	// Filename is the file name embedded in the data, may be empty.
+	Filename string
+	// Signatures are the verified signatures, see ResolveSigners. Without
+	// the signing keys only the fingerprints of the signers are shown.
+	Signatures []ResolvedSignature
+	// Decryption is the result of the decryption, nil for a verification.
+	// The table does not show it.
+	Decryption *gpgme.DecryptResultType
+}
+
+// RenderVerification renders the result in the format, so all programs
+// using this package report verifications the same way. Times are
+// written in UTC. The text and the table end with a newline.
+func RenderVerification(result VerificationReport, format ReportFormat) ([]byte, error) {
+	switch format {
+	case ReportText:
+		return renderVerificationText(result), nil
+	case ReportJSON:
+		report := struct {
+			Filename   string                 `json:"file_name,omitempty"`
+			Decryption *DecryptResultJSONType `json:"decryption,omitempty"`
+			Signatures []SignatureJSONType    `json:"signatures"`
+		}{Filename: result.Filename, Signatures: []SignatureJSONType{}}
+		if result.Decryption != nil {
+			decryption := NewDecryptResultJSON(*result.Decryption)
+			report.Decryption = &decryption
+		}
+		for _, sig := range result.Signatures {
+			report.Signatures = append(report.Signatures, NewResolvedSignatureJSON(sig))
+		}
+		out, err := json.Marshal(report)
+		if err != nil {
+			return nil, fmt.Errorf("RenderVerification - %w", err)
+		}
+		return out, nil
+	case ReportTable:
+		return renderVerificationTable(result), nil
+	}
+	return nil, fmt.Errorf("RenderVerification - unknown format %d", format)
+}
+
+// renderVerificationText renders the result as ReportText.
+func renderVerificationText(result VerificationReport) []byte {
+	var b bytes.Buffer
+	if result.Filename != "" {
+		fmt.Fprintf(&b, "File name:   %s\n", result.Filename)
+	}
+	if d := result.Decryption; d != nil {
+		fmt.Fprintf(&b, "Decrypted:   %s\n", orNone(d.SymkeyAlgo))
+		for _, r := range d.Recipients {
+			fmt.Fprintf(&b, "Recipient:   %s (%s) %s\n", r.KeyID,
+				gpgme.PubkeyAlgoName(r.PubkeyAlgo), CondErrStr(r.Status, "ok"))
+		}
+		if d.LegacyCipherNoMDC {
+			b.WriteString("Warning:     not integrity protected\n")
+		}
+		if d.WrongKeyUsage {
+			b.WriteString("Warning:     wrong key usage\n")
+		}
+	}
+	if len(result.Signatures) == 0 {
+		b.WriteString("Signatures:  none\n")
+	}
+	for i, sig := range result.Signatures {
+		fmt.Fprintf(&b, "Signature %d: %s\n", i+1, ClassifySignature(sig.Signature))
+		fmt.Fprintf(&b, "  Signer:      %s\n", sig.Signer())
+		fmt.Fprintf(&b, "  Fingerprint: %s\n", sig.Fingerprint)
+		fmt.Fprintf(&b, "  Created:     %s\n", reportTime(sig.Timestamp))
+		if !sig.ExpTimestamp.IsZero() {
+			fmt.Fprintf(&b, "  Expires:     %s\n", reportTime(sig.ExpTimestamp))
+		}
+		fmt.Fprintf(&b, "  Validity:    %s\n", GnuPGValidity2String(sig.Validity))
+		fmt.Fprintf(&b, "  Summary:     %s\n",
+			orNone(strings.Join(SigSumToStrings(sig.Summary), ", ")))
+		fmt.Fprintf(&b, "  Status:      %s\n", CondErrStr(sig.Status, "good"))
+		if name := gpgme.PubkeyAlgoName(sig.PubkeyAlgo); name != "" {
+			fmt.Fprintf(&b, "  Algorithms:  %s, %s\n", name, gpgme.HashAlgoName(sig.HashAlgo))
+		}
+	}
+	return b.Bytes()
+}
+
+// renderVerificationTable renders the result as ReportTable.
+func renderVerificationTable(result VerificationReport) []byte {
+	var b bytes.Buffer
+	w := tabwriter.NewWriter(&b, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "STATE\tSIGNER\tFINGERPRINT\tCREATED\tVALIDITY")
+	for _, sig := range result.Signatures {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", ClassifySignature(sig.Signature),
+			sig.Signer(), sig.Fingerprint, reportTime(sig.Timestamp),
+			GnuPGValidity2String(sig.Validity))
+	}
+	_ = w.Flush()
+	return b.Bytes()
+}
+
+// reportTime formats t for a report, "-" for the zero time.
+func reportTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// orNone returns s or "none", if s is empty.
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// EOF
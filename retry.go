@@ -0,0 +1,232 @@
+/* retry.go - retry handling for transient engine errors
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// Error codes from libgpg-error which are not transient, GpgError.Is
+// maps them to the errors of this package. gpgme.go only defines the
+// codes it needs itself, so the values are taken from gpg-error.h.
+const (
+	errCodeNoPubkey      gpgme.ErrorCode = 9  // GPG_ERR_NO_PUBKEY
+	errCodeBadPassphrase gpgme.ErrorCode = 11 // GPG_ERR_BAD_PASSPHRASE
+	errCodeNoSeckey      gpgme.ErrorCode = 17 // GPG_ERR_NO_SECKEY
+	errCodeNotFound      gpgme.ErrorCode = 27 // GPG_ERR_NOT_FOUND
+)
+
+// Error codes from libgpg-error which are considered to be transient.
+const (
+	errCodeKeyserver        gpgme.ErrorCode = 40  // GPG_ERR_KEYSERVER
+	errCodeTimeout          gpgme.ErrorCode = 62  // GPG_ERR_TIMEOUT
+	errCodeNoAgent          gpgme.ErrorCode = 77  // GPG_ERR_NO_AGENT
+	errCodeAgent            gpgme.ErrorCode = 78  // GPG_ERR_AGENT
+	errCodeNoDirmngr        gpgme.ErrorCode = 92  // GPG_ERR_NO_DIRMNGR
+	errCodeDirmngr          gpgme.ErrorCode = 93  // GPG_ERR_DIRMNGR
+	errCodeServerFailed     gpgme.ErrorCode = 219 // GPG_ERR_SERVER_FAILED
+	errCodeAssConnectFailed gpgme.ErrorCode = 259 // GPG_ERR_ASS_CONNECT_FAILED
+)
+
+// System errors are mapped to GPG_ERR_SYSTEM_ERROR ored with the errno.
+const errCodeSystemError gpgme.ErrorCode = 1 << 15 // GPG_ERR_SYSTEM_ERROR
+
+const errCodeMask = 65535 // GPG_ERR_CODE_MASK
+
+const (
+	errCodeEAGAIN       = errCodeSystemError | 6
+	errCodeECONNREFUSED = errCodeSystemError | 25
+	errCodeECONNRESET   = errCodeSystemError | 26
+	errCodeEHOSTUNREACH = errCodeSystemError | 42
+	errCodeEINTR        = errCodeSystemError | 47
+	errCodeENETUNREACH  = errCodeSystemError | 74
+	errCodeETIMEDOUT    = errCodeSystemError | 132
+)
+
+// RetryPolicy defines how an operation failing with a transient
+// engine error is retried.
+// The delay between the attempts starts with InitialDelay and is
+// multiplied by Multiplier after each attempt, limited by MaxDelay.
+// A policy is applied to the operations by SetRetryPolicy or
+// SessionOptions.Retry, or to any function by Do and DoCtx.
+type RetryPolicy struct {
+	MaxAttempts   int           // number of attempts, values < 1 are treated as 1
+	InitialDelay  time.Duration // delay before the second attempt
+	MaxDelay      time.Duration // maximal delay, 0 means unlimited
+	Multiplier    float64       // growth of the delay, values < 1 are treated as 1
+	RelaunchAgent bool          // launch gpg-agent and dirmngr between attempts
+}
+
+// DefaultRetryPolicy is a reasonable policy for batch jobs.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:   3,
+	InitialDelay:  500 * time.Millisecond,
+	MaxDelay:      10 * time.Second,
+	Multiplier:    2,
+	RelaunchAgent: true,
+}
+
+// IsTransientError returns true if err is caused by a gpgme error or a
+// GpgError which is likely to disappear on a retry, like a not running
+// gpg-agent or a temporary keyserver failure.
+func IsTransientError(err error) bool {
+	var code gpgme.ErrorCode
+	var gErr gpgme.Error
+	var gpgErr *GpgError
+	switch {
+	case errors.As(err, &gErr):
+		code = gErr.Code()
+	case errors.As(err, &gpgErr):
+		code = gpgErr.Code
+	default:
+		return false
+	}
+	switch code {
+	case errCodeKeyserver, errCodeTimeout, errCodeNoAgent, errCodeAgent,
+		errCodeNoDirmngr, errCodeDirmngr, errCodeServerFailed,
+		errCodeAssConnectFailed, errCodeEAGAIN, errCodeECONNREFUSED,
+		errCodeECONNRESET, errCodeEHOSTUNREACH, errCodeEINTR,
+		errCodeENETUNREACH, errCodeETIMEDOUT:
+		return true
+	}
+	return false
+}
+
+var retryPolicy struct {
+	sync.RWMutex
+	policy *RetryPolicy
+}
+
+// SetRetryPolicy sets the policy for transient errors of all following
+// operations of the package functions, and of the sessions without
+// SessionOptions.Retry. nil, the default, disables the retries.
+// The policy is honored by the operations calling gpg directly, and by
+// EncryptBytes, DecryptBytes, SignBytes, VerifyBytes, EncryptFile,
+// DecryptFile, ImportKeys and KeyserverSearch, their variants with a
+// context or another signature mode, and the Session methods of the
+// same name. An operation is only retried, if its input can be read
+// again and no output has been written yet.
+func SetRetryPolicy(p *RetryPolicy) {
+	retryPolicy.Lock()
+	defer retryPolicy.Unlock()
+	if p != nil {
+		copied := *p
+		p = &copied
+	}
+	retryPolicy.policy = p
+}
+
+// currentRetryPolicy returns p, or the policy of SetRetryPolicy if p is
+// nil, which may be nil as well.
+func currentRetryPolicy(p *RetryPolicy) *RetryPolicy {
+	if p != nil {
+		return p
+	}
+	retryPolicy.RLock()
+	defer retryPolicy.RUnlock()
+	return retryPolicy.policy
+}
+
+// withRetry runs op with the policy p, see currentRetryPolicy, or once
+// without a policy. The daemons of the home directory homeDir are
+// relaunched between the attempts, the waiting for the next attempt
+// stops when ctx is done.
+func withRetry(ctx context.Context, p *RetryPolicy, homeDir string, op func() error) error {
+	p = currentRetryPolicy(p)
+	if p == nil {
+		return op()
+	}
+	return p.retry(ctx, homeDir, op, nil)
+}
+
+// Do runs op until it succeeds, fails with a non transient error or the
+// maximal number of attempts is reached. The error of the last attempt
+// is returned.
+func (p RetryPolicy) Do(op func() error) error {
+	return p.DoCtx(context.Background(), op)
+}
+
+// DoCtx runs op like Do, but stops waiting for the next attempt when ctx
+// is done, and returns the error of ctx together with the one of the
+// last attempt.
+func (p RetryPolicy) DoCtx(ctx context.Context, op func() error) error {
+	return p.retry(ctx, "", op, nil)
+}
+
+// retry implements DoCtx, relaunching the daemons of the home directory
+// homeDir. If replay is not nil, it is called before each further
+// attempt and ends the retries, if it returns false.
+func (p RetryPolicy) retry(ctx context.Context, homeDir string, op func() error,
+	replay func() bool) (err error) {
+
+	attempts := max(p.MaxAttempts, 1)
+	multiplier := max(p.Multiplier, 1)
+	delay := p.InitialDelay
+
+	for attempt := 1; ; attempt++ {
+		err = op()
+		if err == nil || !IsTransientError(err) {
+			return err
+		}
+		if attempt >= attempts {
+			return fmt.Errorf("RetryPolicy - giving up after %d attempts: %w",
+				attempt, err)
+		}
+		if replay != nil && !replay() {
+			return err
+		}
+		if p.RelaunchAgent {
+			// a failing launch is not fatal, the next attempt will tell
+			_ = relaunchDaemons(homeDir)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("RetryPolicy - %w after %d attempts: %w",
+				ctx.Err(), attempt, err)
+		case <-timer.C:
+		}
+		delay = time.Duration(float64(delay) * multiplier)
+		if p.MaxDelay > 0 && delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+	}
+}
+
+// relaunchDaemons makes sure gpg-agent and dirmngr of the home directory
+// homeDir are running, empty for the one of gpgme.
+func relaunchDaemons(homeDir string) error {
+	for _, daemon := range []string{DaemonAgent, DaemonDirmngr} {
+		if err := controlDaemon(homeDir, "--launch", daemon); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EOF
@@ -0,0 +1,75 @@
+/* retry_test.go - tests of the retry policy
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithRetry(t *testing.T) {
+	transient := &GpgError{Code: errCodeNoAgent}
+	permanent := &GpgError{Code: errCodeNoSeckey}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		policy       *RetryPolicy
+		errs         []error // results of the attempts, nil after the last
+		wantAttempts int
+		wantErr      error
+	}{
+		{"no policy", context.Background(), nil,
+			[]error{transient}, 1, transient},
+		{"success", context.Background(), &RetryPolicy{MaxAttempts: 3},
+			[]error{transient, nil}, 2, nil},
+		{"not transient", context.Background(), &RetryPolicy{MaxAttempts: 3},
+			[]error{permanent, nil}, 1, permanent},
+		{"giving up", context.Background(), &RetryPolicy{MaxAttempts: 2},
+			[]error{transient, transient, nil}, 2, transient},
+		{"cancelled", cancelled, &RetryPolicy{MaxAttempts: 3, InitialDelay: time.Hour},
+			[]error{transient, nil}, 1, context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := withRetry(tt.ctx, tt.policy, "", func() error {
+				attempts++
+				if attempts > len(tt.errs) {
+					return nil
+				}
+				return tt.errs[attempts-1]
+			})
+			if attempts != tt.wantAttempts {
+				t.Errorf("%d attempts, want %d", attempts, tt.wantAttempts)
+			}
+			if (tt.wantErr == nil) != (err == nil) || !errors.Is(err, tt.wantErr) {
+				t.Errorf("withRetry = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// EOF
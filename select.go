@@ -0,0 +1,202 @@
+/* select.go - selecting the key for a recipient for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// SelectionPolicy configures SelectEncryptionKey.
+type SelectionPolicy struct {
+	// MinValidity is the minimum validity of the user ID with the mail
+	// address, e.g. gpgme.ValidityFull. The zero value accepts any
+	// validity except gpgme.ValidityNever.
+	MinValidity gpgme.Validity
+	// DenyFingerprints are keys which must not be selected.
+	DenyFingerprints []string
+}
+
+// RejectedKeyType is a key matching the mail address, which was not
+// selected.
+type RejectedKeyType struct {
+	Fingerprint string
+	Reason      string
+}
+
+// KeySelectionType is the key selected by SelectEncryptionKey and why.
+type KeySelectionType struct {
+	Key      KeyType
+	SubKey   SubKeyType        // the subkey gpg encrypts to
+	Reasons  []string          // why the key was selected
+	Rejected []RejectedKeyType // the other matching keys
+}
+
+// SelectEncryptionKey selects the best key for encrypting to the mail
+// address email out of all keys with a user ID for it: the key and the
+// user ID must be valid, i.e. not expired, revoked, disabled or invalid,
+// and the key must have a valid subkey for encryption. Of these keys the
+// one with the highest validity of the user ID is selected, then the one
+// with the newest encryption subkey. The selection tells the reasons and
+// why the other keys were rejected.
+// If no key is usable, the error matches ErrRecipientNotFound with
+// errors.Is and the selection still tells the rejected keys.
+func SelectEncryptionKey(email string, policy SelectionPolicy) (
+	selection KeySelectionType, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return selection, fmt.Errorf("SelectEncryptionKey - %w", err)
+	}
+	defer myContext.Release()
+
+	selection, err = selectEncryptionKey(myContext, email, policy)
+	if err != nil {
+		return selection, fmt.Errorf("SelectEncryptionKey - %w", err)
+	}
+	return selection, nil
+}
+
+// keyCandidate is a usable key for SelectEncryptionKey.
+type keyCandidate struct {
+	key      KeyType
+	subKey   SubKeyType
+	validity gpgme.Validity // of the user ID with the address
+}
+
+// selectEncryptionKey implements SelectEncryptionKey using myContext.
+func selectEncryptionKey(myContext *gpgme.Context, email string,
+	policy SelectionPolicy) (selection KeySelectionType, err error) {
+
+	email = strings.TrimSpace(email)
+	if err := checkAddrSpec(email); err != nil {
+		return selection, fmt.Errorf("not a mail address: %w", err)
+	}
+
+	keys, err := keyList(context.Background(), myContext, "<"+email+">")
+	if err != nil {
+		return selection, err
+	}
+
+	now := time.Now()
+	var candidates []keyCandidate
+	for _, k := range keys {
+		c, reason := encryptionCandidate(k, email, policy, now)
+		if reason != "" {
+			selection.Rejected = append(selection.Rejected,
+				RejectedKeyType{Fingerprint: k.Fingerprint, Reason: reason})
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+	if len(candidates) == 0 {
+		return selection, fmt.Errorf("%w: %s", ErrRecipientNotFound, email)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].validity != candidates[j].validity {
+			return candidates[i].validity > candidates[j].validity
+		}
+		return candidates[i].subKey.Created.After(candidates[j].subKey.Created)
+	})
+
+	best := candidates[0]
+	selection.Key, selection.SubKey = best.key, best.subKey
+	selection.Reasons = append(selection.Reasons, fmt.Sprintf(
+		"valid key with user ID validity %s", GnuPGValidity2String(best.validity)))
+	for _, c := range candidates[1:] {
+		reason := "older encryption subkey"
+		if c.validity < best.validity {
+			reason = "lower user ID validity " + GnuPGValidity2String(c.validity)
+		}
+		selection.Rejected = append(selection.Rejected,
+			RejectedKeyType{Fingerprint: c.key.Fingerprint, Reason: reason})
+	}
+	if len(candidates) > 1 {
+		selection.Reasons = append(selection.Reasons, fmt.Sprintf(
+			"best of %d usable keys", len(candidates)))
+	}
+	return selection, nil
+}
+
+// encryptionCandidate checks whether the key k can be used to encrypt to
+// email. It returns the candidate or why it can not be used.
+func encryptionCandidate(k KeyType, email string, policy SelectionPolicy,
+	now time.Time) (c keyCandidate, reason string) {
+
+	for _, fpr := range policy.DenyFingerprints {
+		if FingerprintsEqual(fpr, k.Fingerprint) {
+			return c, "denied by policy"
+		}
+	}
+	switch {
+	case k.Revoked:
+		return c, "revoked"
+	case k.Expired:
+		return c, "expired"
+	case k.Disabled:
+		return c, "disabled"
+	case k.Invalid:
+		return c, "invalid"
+	}
+
+	found := false
+	for _, uid := range k.UserIDs {
+		if !strings.EqualFold(uid.Address, email) || uid.Revoked || uid.Invalid ||
+			uid.Validity == gpgme.ValidityNever {
+			continue
+		}
+		if !found || uid.Validity > c.validity {
+			c.validity = uid.Validity
+		}
+		found = true
+	}
+	if !found {
+		return c, "no valid user ID for " + email
+	}
+	if c.validity < policy.MinValidity {
+		return c, "user ID validity " + GnuPGValidity2String(c.validity) +
+			" below " + GnuPGValidity2String(policy.MinValidity)
+	}
+
+	// gpg encrypts to the newest valid encryption subkey
+	for _, sk := range k.SubKeys {
+		if !strings.Contains(sk.Capabilities, "e") || sk.Revoked || sk.Expired ||
+			sk.Disabled || sk.Invalid || (!sk.Expires.IsZero() && sk.Expires.Before(now)) {
+			continue
+		}
+		if c.subKey.Fingerprint == "" || sk.Created.After(c.subKey.Created) {
+			c.subKey = sk
+		}
+	}
+	if c.subKey.Fingerprint == "" {
+		return c, "no valid subkey for encryption"
+	}
+	c.key = k
+	return c, ""
+}
+
+// EOF
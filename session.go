@@ -0,0 +1,1601 @@
+/* session.go - reusable configured gpgme context for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// SessionOptions configures the gpgme context of a Session.
+// The zero value is the configuration used by the package functions.
+// With the CMS protocol, the methods which call gpg directly, like
+// DeleteKey or VerifyBytesAt, fail with ErrOpenPGPOnly.
+type SessionOptions struct {
+	Protocol     gpgme.Protocol     // OpenPGP (the zero value) or CMS for S/MIME
+	Armor        bool               // ASCII armor the output
+	HomeDir      string             // GnuPG home directory, empty for the one of SetHomeDir
+	PinEntryMode gpgme.PinEntryMode // how passphrases are requested
+
+	// Passphrase, if set, answers the passphrase requests for decryption
+	// and signing instead of pinentry. It implies loopback pinentry mode,
+	// which is what non-interactive services need. See StaticPassphrase
+	// for a fixed passphrase.
+	Passphrase PassphraseFunc
+
+	// EncryptToSelf adds the own key of the user to the recipients of all
+	// encryptions of the session, so the user can decrypt the result.
+	// The key is SelfKey or, if empty, the DefaultKey of the home directory.
+	EncryptToSelf bool
+	SelfKey       string
+
+	// RequireCompliance makes NewSession fail, if the engine does not
+	// guarantee de-vs compliance, see EngineCompliance, and decryptions
+	// fail, if the decrypted data is not de-vs compliant. Decrypted files
+	// are already written, when the error is returned.
+	RequireCompliance bool
+
+	// Retry, if set, overrides the retry policy of SetRetryPolicy for the
+	// operations of the session.
+	Retry *RetryPolicy
+}
+
+// newContext creates a gpgme context configured with opts.
+// The caller has to release the context.
+func newContext(opts SessionOptions) (*gpgme.Context, error) {
+	logDebug("creating gpgme context", "protocol", opts.Protocol,
+		"homedir", opts.HomeDir, "armor", opts.Armor)
+	myContext, err := gpgme.New()
+	if err != nil {
+		logDebug("gpgme.New failed", "error", err)
+		return nil, fmt.Errorf("gpgme.New failed: %w", err)
+	}
+
+	err = myContext.SetProtocol(opts.Protocol)
+	if err != nil {
+		myContext.Release()
+		return nil, fmt.Errorf("SetProtocol failed: %w", err)
+	}
+
+	if opts.HomeDir != "" {
+		err = myContext.SetEngineInfo(opts.Protocol, "", opts.HomeDir)
+		if err != nil {
+			myContext.Release()
+			return nil, fmt.Errorf("SetEngineInfo failed: %w", err)
+		}
+	}
+
+	myContext.SetArmor(opts.Armor)
+
+	if opts.PinEntryMode != gpgme.PinEntryDefault {
+		err = myContext.SetPinEntryMode(opts.PinEntryMode)
+		if err != nil {
+			myContext.Release()
+			return nil, fmt.Errorf("SetPinEntryMode failed: %w", err)
+		}
+	}
+
+	err = setPassphraseFunc(myContext, opts.Passphrase)
+	if err != nil {
+		myContext.Release()
+		return nil, fmt.Errorf("setting passphrase callback failed: %w", err)
+	}
+
+	return myContext, nil
+}
+
+// Session holds a configured gpgme context, which is reused by all its
+// operations. Repeated operations avoid the setup of a new context and
+// share the same options.
+// A Session may be used by several goroutines, the operations are
+// serialized. Close releases the context.
+type Session struct {
+	mu         sync.Mutex
+	ctx        *gpgme.Context
+	opts       SessionOptions
+	compliance ComplianceInfo
+	cleanup    func() error // called by Close after releasing the context
+}
+
+// NewSession creates a Session with the options opts.
+func NewSession(opts SessionOptions) (*Session, error) {
+	var compliance ComplianceInfo
+	if opts.RequireCompliance {
+		var err error
+		compliance, err = engineCompliance(opts.HomeDir)
+		if err != nil {
+			return nil, fmt.Errorf("NewSession - %w", err)
+		}
+		if !compliance.DEVS {
+			return nil, fmt.Errorf("NewSession - engine in mode %q: %w",
+				compliance.Mode, ErrNotCompliant)
+		}
+	}
+
+	myContext, err := newContext(opts)
+	if err != nil {
+		return nil, fmt.Errorf("NewSession - %w", err)
+	}
+	return &Session{ctx: myContext, opts: opts, compliance: compliance}, nil
+}
+
+// Compliance returns the compliance of the encryption and signing
+// operations of the session, see EngineCompliance. For decryptions see
+// DecryptCompliance.
+func (s *Session) Compliance() (info ComplianceInfo, err error) {
+	if s.opts.RequireCompliance {
+		return s.compliance, nil
+	}
+	info, err = engineCompliance(s.opts.HomeDir)
+	if err != nil {
+		return info, fmt.Errorf("Compliance - %w", err)
+	}
+	return info, nil
+}
+
+// checkCompliance returns err or, if the session requires compliance and
+// the decryption with result is not compliant, ErrNotCompliant.
+func (s *Session) checkCompliance(operation string, result gpgme.DecryptResultType,
+	err error) error {
+
+	if err != nil || !s.opts.RequireCompliance || result.IsDEVS {
+		return err
+	}
+	return fmt.Errorf("%s - decrypted data: %w", operation, ErrNotCompliant)
+}
+
+// Options returns the options the session was created with.
+func (s *Session) Options() SessionOptions {
+	return s.opts
+}
+
+// Close releases the context of the session.
+// The session must not be used afterwards.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx == nil {
+		return nil
+	}
+	s.ctx.Release()
+	s.ctx = nil
+	if s.cleanup != nil {
+		if err := s.cleanup(); err != nil {
+			return fmt.Errorf("Session.Close - %w", err)
+		}
+	}
+	return nil
+}
+
+// appendSelf adds the own key of the user to recipients, if the session
+// option EncryptToSelf is set.
+func (s *Session) appendSelf(recipients []string) ([]string, error) {
+	if !s.opts.EncryptToSelf {
+		return recipients, nil
+	}
+	key := s.opts.SelfKey
+	if key == "" {
+		var err error
+		key, err = defaultKey(s.opts.HomeDir)
+		if err != nil {
+			return nil, fmt.Errorf("finding own key failed: %w", err)
+		}
+	}
+	return append(slices.Clip(recipients), key), nil
+}
+
+// CanEncryptTo checks a key like the package function CanEncryptTo.
+func (s *Session) CanEncryptTo(pattern string) (usable bool,
+	problems []KeyUsabilityProblem, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usable, problems, err = canEncryptTo(s.ctx, pattern)
+	if err != nil {
+		return false, nil, fmt.Errorf("CanEncryptTo - %w", err)
+	}
+	return usable, problems, nil
+}
+
+// CanSignWith checks a key like the package function CanSignWith.
+func (s *Session) CanSignWith(pattern string) (usable bool,
+	problems []KeyUsabilityProblem, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usable, problems, err = canSignWith(s.ctx, pattern)
+	if err != nil {
+		return false, nil, fmt.Errorf("CanSignWith - %w", err)
+	}
+	return usable, problems, nil
+}
+
+// WhyCannotEncrypt explains why data can not be encrypted to a key like
+// the package function WhyCannotEncrypt.
+func (s *Session) WhyCannotEncrypt(pattern string) (problems []KeyUsabilityProblem,
+	err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	problems, err = whyCannot(s.ctx, pattern, "e")
+	if err != nil {
+		return nil, fmt.Errorf("WhyCannotEncrypt - %w", err)
+	}
+	return problems, nil
+}
+
+// WhyCannotSign explains why data can not be signed with a key like the
+// package function WhyCannotSign.
+func (s *Session) WhyCannotSign(pattern string) (problems []KeyUsabilityProblem,
+	err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	problems, err = whyCannot(s.ctx, pattern, "s")
+	if err != nil {
+		return nil, fmt.Errorf("WhyCannotSign - %w", err)
+	}
+	return problems, nil
+}
+
+// EncryptFile encrypts a file like the package function EncryptFile.
+// If the session uses ASCII armor, the default destination has the
+// extension `.asc`.
+func (s *Session) EncryptFile(sourceFilename, destinationFilename string,
+	recipients []string, sign bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recipients, err := s.appendSelf(recipients)
+	if err != nil {
+		return fmt.Errorf("EncryptFile - %w", err)
+	}
+	return s.retry(context.Background(), func() error {
+		return encryptFile(s.ctx, sourceFilename, destinationFilename, recipients,
+			sign, false)
+	})
+}
+
+// EncryptFileWithPolicy encrypts a file like the package function
+// EncryptFileWithPolicy.
+func (s *Session) EncryptFileWithPolicy(sourceFilename, destinationFilename string,
+	recipients []string, sign bool, policy RecipientPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recipients, err := s.appendSelf(recipients)
+	if err == nil {
+		err = checkRecipientPolicy(s.ctx, recipients, policy)
+	}
+	if err != nil {
+		return fmt.Errorf("EncryptFile - %w", err)
+	}
+	return encryptFile(s.ctx, sourceFilename, destinationFilename, recipients, sign, false)
+}
+
+// EncryptFileOptions encrypts a file like the package function
+// EncryptFileOptions, the armor setting is taken from the session options.
+func (s *Session) EncryptFileOptions(sourceFilename, destinationFilename string,
+	recipients []string, sign bool, opts EncryptOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recipients, err := s.appendSelf(recipients)
+	var cmd gpgCommand
+	if err == nil {
+		cmd, err = s.command("")
+	}
+	if err == nil {
+		err = encryptFileOptions(cmd, sourceFilename, destinationFilename,
+			recipients, sign, s.opts.Armor, opts)
+	}
+	if err != nil {
+		return fmt.Errorf("EncryptFile - %w", err)
+	}
+	return nil
+}
+
+// EncryptFileSplit encrypts a file into parts of limited size like the
+// package function EncryptFileSplit, the armor setting is taken from the
+// session options.
+func (s *Session) EncryptFileSplit(sourceFilename, destinationFilename string,
+	recipients []string, sign bool, partSize int64, opts EncryptOptions) (
+	parts []string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recipients, err = s.appendSelf(recipients)
+	var cmd gpgCommand
+	if err == nil {
+		cmd, err = s.command("")
+	}
+	if err == nil {
+		parts, err = encryptFileSplit(cmd, sourceFilename, destinationFilename,
+			recipients, sign, s.opts.Armor, partSize, opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("EncryptFileSplit - %w", err)
+	}
+	return parts, nil
+}
+
+// WatchAndEncrypt encrypts the files of a drop directory like the package
+// function WatchAndEncrypt, using the configuration of the session. The
+// session is not locked while watching.
+func (s *Session) WatchAndEncrypt(ctx context.Context, dir string, recipients []string,
+	opts WatchOptions) error {
+	s.mu.Lock()
+	recipients, err := s.appendSelf(recipients)
+	var cmd gpgCommand
+	if err == nil {
+		cmd, err = s.command("")
+	}
+	s.mu.Unlock()
+	if err == nil {
+		err = watchAndEncrypt(ctx, cmd, dir, recipients, opts)
+	}
+	if err != nil {
+		return fmt.Errorf("WatchAndEncrypt - %w", err)
+	}
+	return nil
+}
+
+// EncryptFileSigned encrypts and signs a file like the package function
+// EncryptFileSigned, using the configuration of the session.
+func (s *Session) EncryptFileSigned(sourceFilename, destinationFilename string,
+	recipients, signers []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recipients, err := s.appendSelf(recipients)
+	if err != nil {
+		return fmt.Errorf("EncryptFileSigned - %w", err)
+	}
+	return encryptFileSigned(s.opts, sourceFilename, destinationFilename, recipients, signers)
+}
+
+// EncryptFileStrict encrypts a file like the package function EncryptFileStrict.
+func (s *Session) EncryptFileStrict(sourceFilename, destinationFilename string,
+	recipients []string, sign bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recipients, err := s.appendSelf(recipients)
+	if err != nil {
+		return fmt.Errorf("EncryptFileStrict - %w", err)
+	}
+	return encryptFile(s.ctx, sourceFilename, destinationFilename, recipients, sign, true)
+}
+
+// ResolveRecipients resolves recipients like the package function
+// ResolveRecipients.
+func (s *Session) ResolveRecipients(recipients []string) (keys []*gpgme.Key,
+	warnings []RecipientWarning, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys, warnings, err = resolveRecipients(s.ctx, recipients)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ResolveRecipients - %w", err)
+	}
+	return keys, warnings, nil
+}
+
+// SelectEncryptionKey selects the best key of the session's keyring for a
+// mail address like the package function SelectEncryptionKey.
+func (s *Session) SelectEncryptionKey(email string, policy SelectionPolicy) (
+	selection KeySelectionType, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	selection, err = selectEncryptionKey(s.ctx, email, policy)
+	if err != nil {
+		return selection, fmt.Errorf("SelectEncryptionKey - %w", err)
+	}
+	return selection, nil
+}
+
+// EncryptFileToWriter encrypts a file to w like the package function
+// EncryptFileToWriter, the armor setting is taken from the session options.
+func (s *Session) EncryptFileToWriter(sourceFilename string, w io.Writer,
+	recipients []string, sign bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recipients, err := s.appendSelf(recipients)
+	if err != nil {
+		return fmt.Errorf("EncryptFileToWriter - %w", err)
+	}
+	return encryptFileToWriter(context.Background(), s.ctx, sourceFilename, w,
+		recipients, sign)
+}
+
+// DecryptFile decrypts a file like the package function DecryptFile.
+func (s *Session) DecryptFile(cypherFilename, clearFilename string) (
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = s.retry(context.Background(), func() (err error) {
+		decryptionResult, filename, signatures, warning, err = decryptFile(s.ctx,
+			cypherFilename, clearFilename)
+		return err
+	})
+	err = s.checkCompliance("DecryptFile", decryptionResult, err)
+	return
+}
+
+// DecryptParts decrypts an encrypted file split into parts like the
+// package function DecryptParts.
+func (s *Session) DecryptParts(parts []string, clearFilename string,
+	opts DecryptOptions) (decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.command("")
+	if err != nil {
+		err = fmt.Errorf("DecryptParts - %w", err)
+		return
+	}
+	decryptionResult, filename, signatures, warning, err = decryptParts(
+		context.Background(), s.ctx, cmd, parts, clearFilename, opts)
+	err = s.checkCompliance("DecryptParts", decryptionResult, err)
+	return
+}
+
+// DecryptFileOptions decrypts a file like the package function
+// DecryptFileOptions.
+func (s *Session) DecryptFileOptions(cypherFilename, clearFilename string,
+	opts DecryptOptions) (decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.command("")
+	if err != nil {
+		err = fmt.Errorf("DecryptFile - %w", err)
+		return
+	}
+	decryptionResult, filename, signatures, warning, err = decryptFileOptions(
+		context.Background(), s.ctx, cmd, cypherFilename, clearFilename, opts)
+	err = s.checkCompliance("DecryptFile", decryptionResult, err)
+	return
+}
+
+// EncryptBytes encrypts a memory buffer like the package function
+// EncryptBytes, the armor setting is taken from the session options.
+func (s *Session) EncryptBytes(plainText []byte, recipients []string, sign bool) (
+	cipherText []byte, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recipients, err = s.appendSelf(recipients)
+	if err != nil {
+		return nil, fmt.Errorf("EncryptBytes - %w", err)
+	}
+	err = s.retry(context.Background(), func() (err error) {
+		cipherText, err = encryptBytes(context.Background(), s.ctx, plainText,
+			recipients, sign)
+		return err
+	})
+	return cipherText, err
+}
+
+// EncryptBytesTo encrypts a memory buffer to recipients given as keys,
+// fingerprints or public keys like the package function EncryptBytesTo,
+// the armor setting is taken from the session options.
+func (s *Session) EncryptBytesTo(plainText []byte, recipients []Recipient, sign bool) (
+	cipherText []byte, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	self, err := s.appendSelf(nil)
+	if err != nil {
+		return nil, fmt.Errorf("EncryptBytesTo - %w", err)
+	}
+	for _, pattern := range self {
+		keys, err := findKeys(s.ctx, pattern, false)
+		if err != nil {
+			return nil, fmt.Errorf("EncryptBytesTo - finding own key failed: %w", err)
+		}
+		for _, key := range keys {
+			recipients = append(slices.Clip(recipients), Recipient{Key: key})
+		}
+	}
+	return encryptBytesTo(s.ctx, plainText, recipients, sign, s.opts.Armor)
+}
+
+// EncryptBytesWithPolicy encrypts a memory buffer like the package
+// function EncryptBytesWithPolicy, the armor setting is taken from the
+// session options.
+func (s *Session) EncryptBytesWithPolicy(plainText []byte, recipients []string,
+	sign bool, policy RecipientPolicy) (cipherText []byte, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recipients, err = s.appendSelf(recipients)
+	if err == nil {
+		err = checkRecipientPolicy(s.ctx, recipients, policy)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("EncryptBytes - %w", err)
+	}
+	return encryptBytes(context.Background(), s.ctx, plainText, recipients, sign)
+}
+
+// EncryptBytesOptions encrypts a memory buffer like the package function
+// EncryptBytesOptions, the armor setting is taken from the session options.
+func (s *Session) EncryptBytesOptions(plainText []byte, recipients []string,
+	sign bool, opts EncryptOptions) (cipherText []byte, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recipients, err = s.appendSelf(recipients)
+	var cmd gpgCommand
+	if err == nil {
+		cmd, err = s.command("")
+	}
+	if err == nil {
+		cipherText, err = encryptBytesOptions(cmd, plainText, recipients, sign,
+			s.opts.Armor, opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("EncryptBytes - %w", err)
+	}
+	return cipherText, nil
+}
+
+// DecryptBytes decrypts a memory buffer like the package function DecryptBytes.
+func (s *Session) DecryptBytes(cipherText []byte) (plainText []byte,
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+	return s.decryptBytes(context.Background(), cipherText)
+}
+
+// decryptBytes implements DecryptBytes and the package function
+// DecryptBytesCtx with a default pool.
+func (s *Session) decryptBytes(ctx context.Context, cipherText []byte) (plainText []byte,
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = s.retry(ctx, func() (err error) {
+		plainText, decryptionResult, filename, signatures, warning, err = decryptBytes(
+			ctx, s.ctx, cipherText)
+		return err
+	})
+	err = s.checkCompliance("DecryptBytes", decryptionResult, err)
+	if err != nil {
+		plainText = nil
+	}
+	return
+}
+
+// DecryptBytesSessionKey decrypts a memory buffer and returns its session
+// key like the package function DecryptBytesSessionKey.
+func (s *Session) DecryptBytesSessionKey(cipherText []byte) (plainText []byte,
+	sessionKey string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.command("")
+	if err == nil {
+		plainText, sessionKey, err = decryptBytesSessionKey(cmd, cipherText)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("DecryptBytesSessionKey - %w", err)
+	}
+	return plainText, sessionKey, nil
+}
+
+// DecryptWithSessionKey decrypts a memory buffer with a session key like
+// the package function DecryptWithSessionKey.
+func (s *Session) DecryptWithSessionKey(cipherText []byte, sessionKey string) (
+	plainText []byte, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		plainText, err = decryptWithSessionKey(cmd,
+			cipherText, sessionKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("DecryptWithSessionKey - %w", err)
+	}
+	return plainText, nil
+}
+
+// EncryptStream encrypts the data read from r into w like the package
+// function EncryptStream, the armor setting is taken from the session options.
+func (s *Session) EncryptStream(r io.Reader, w io.Writer, recipients []string,
+	sign bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recipients, err := s.appendSelf(recipients)
+	if err != nil {
+		return fmt.Errorf("EncryptStream - %w", err)
+	}
+	return encryptStream(context.Background(), s.ctx, "EncryptStream", r, w,
+		recipients, sign)
+}
+
+// EncryptFileHandle encrypts an open file like the package function
+// EncryptFileHandle, the armor setting is taken from the session options.
+func (s *Session) EncryptFileHandle(in, out *os.File, recipients []string,
+	sign bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recipients, err := s.appendSelf(recipients)
+	if err != nil {
+		return fmt.Errorf("EncryptFileHandle - %w", err)
+	}
+	return encryptFileHandle(s.ctx, in, out, recipients, sign)
+}
+
+// DecryptFileHandle decrypts an open file like the package function
+// DecryptFileHandle.
+func (s *Session) DecryptFileHandle(in, out *os.File) (
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	decryptionResult, filename, signatures, warning, err = decryptFileHandle(s.ctx,
+		in, out)
+	err = s.checkCompliance("DecryptFileHandle", decryptionResult, err)
+	return
+}
+
+// DecryptStream decrypts the data read from r into w like the package
+// function DecryptStream.
+func (s *Session) DecryptStream(r io.Reader, w io.Writer) (
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	decryptionResult, filename, signatures, warning, err = decryptStream(
+		context.Background(), s.ctx, "DecryptStream", r, w)
+	err = s.checkCompliance("DecryptStream", decryptionResult, err)
+	return
+}
+
+// SignBytes signs a memory buffer with the signature mode mode, see
+// SignBytesMode. The armor setting is taken from the session options.
+// Unlike the package function, reaching the end of the data is not
+// reported as io.EOF.
+func (s *Session) SignBytes(plainText []byte, signWith string, mode gpgme.SigMode) (
+	cipherText []byte, signingFingerPrints []string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = s.retry(context.Background(), func() (err error) {
+		cipherText, _, signingFingerPrints, err = signBytes(context.Background(), s.ctx,
+			plainText, signWith, mode)
+		return err
+	})
+	if err == io.EOF {
+		err = nil
+	}
+	return
+}
+
+// SignBytesMulti signs a memory buffer with several keys like the package
+// function SignBytesMulti. The armor setting is taken from the session
+// options, the passphrases are requested from the passphrase function.
+func (s *Session) SignBytesMulti(plainText []byte, signWith []string,
+	mode gpgme.SigMode) (cipherText []byte, newSignatures []NewSignatureType, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return signBytesMulti(context.Background(), s.ctx, plainText, signWith, mode)
+}
+
+// VerifyBytes verifies a signature like the package function VerifyBytes.
+func (s *Session) VerifyBytes(cipherText []byte) (plainText []byte,
+	signatures []gpgme.Signature, filename string, err error) {
+	return s.verifyBytes(context.Background(), cipherText)
+}
+
+// verifyBytes implements VerifyBytes and the package function
+// VerifyBytesCtx with a default pool.
+func (s *Session) verifyBytes(ctx context.Context, cipherText []byte) (plainText []byte,
+	signatures []gpgme.Signature, filename string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = s.retry(ctx, func() (err error) {
+		plainText, signatures, filename, err = verifyBytes(ctx, s.ctx, cipherText)
+		return err
+	})
+	return plainText, signatures, filename, err
+}
+
+// ImportKeys imports keys into the keyring of the session like the
+// package function ImportKeys.
+func (s *Session) ImportKeys(keyData []byte) (result *gpgme.ImportResult, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = s.retry(context.Background(), func() error {
+		dataIn, err := gpgme.NewDataBytes(keyData)
+		if err != nil {
+			return fmt.Errorf("NewData (in) failed: %w", err)
+		}
+		defer dataIn.Close()
+
+		result, err = importDataWith(s.ctx, dataIn)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ImportKeys - %w", err)
+	}
+	return result, nil
+}
+
+// VerifyFile verifies a signed file like the package function VerifyFile.
+func (s *Session) VerifyFile(signedFilename, plainFilename string) (
+	destination string, signatures []gpgme.Signature, filename string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return verifyFile(s.ctx, signedFilename, plainFilename)
+}
+
+// VerifyFileDetached verifies a detached signature of a file like the
+// package function VerifyFileDetached.
+func (s *Session) VerifyFileDetached(signatureFilename, dataFilename string) (
+	signatures []gpgme.Signature, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return verifyFileDetached(s.ctx, signatureFilename, dataFilename)
+}
+
+// VerifyGitObject verifies the signature of a git commit or tag like the
+// package function VerifyGitObject.
+func (s *Session) VerifyGitObject(raw []byte) (result GitSignatureType, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return verifyGitObject(s.ctx, raw)
+}
+
+// VerifyBytesAt verifies a signature at the time at like the package
+// function VerifyBytesAt.
+func (s *Session) VerifyBytesAt(cipherText []byte, at time.Time) (plainText []byte,
+	signatures []gpgme.Signature, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		plainText, signatures, err = verifyBytesAt(cmd,
+			cipherText, at)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("VerifyBytesAt - %w", err)
+	}
+	return plainText, signatures, nil
+}
+
+// VerifyFileDetachedAt verifies a detached signature of a file at the
+// time at like the package function VerifyFileDetachedAt.
+func (s *Session) VerifyFileDetachedAt(signatureFilename, dataFilename string,
+	at time.Time) (signatures []gpgme.Signature, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		signatures, err = verifyFileDetachedAt(cmd,
+			signatureFilename, dataFilename, at)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("VerifyFileDetachedAt - %w", err)
+	}
+	return signatures, nil
+}
+
+// VerifyBytesWithPolicy verifies a signature and checks it against
+// policy like the package function VerifyBytesWithPolicy.
+func (s *Session) VerifyBytesWithPolicy(cipherText []byte, policy VerifyPolicy) (
+	plainText []byte, decision VerifyDecision, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return verifyBytesWithPolicy(s.ctx, cipherText, policy)
+}
+
+// ResolveSigners looks up the keys which made the signatures in the
+// keyring of the session like the package function ResolveSigners.
+func (s *Session) ResolveSigners(signatures []gpgme.Signature) (
+	resolved []ResolvedSignature, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resolved, err = resolveSigners(s.ctx, signatures)
+	if err != nil {
+		return nil, fmt.Errorf("ResolveSigners - %w", err)
+	}
+	return resolved, nil
+}
+
+// DecryptKeys looks up the recipients of decrypted data in the keyring of
+// the session like the package function DecryptKeys.
+func (s *Session) DecryptKeys(result gpgme.DecryptResultType) (keys DecryptKeysType,
+	err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys, err = decryptKeys(s.ctx, result)
+	if err != nil {
+		return keys, fmt.Errorf("DecryptKeys - %w", err)
+	}
+	return keys, nil
+}
+
+// DecryptFileWithPolicy decrypts a file and checks the signatures against
+// policy like the package function DecryptFileWithPolicy.
+func (s *Session) DecryptFileWithPolicy(cypherFilename, clearFilename string,
+	policy VerifyPolicy) (decryptionResult gpgme.DecryptResultType, filename string,
+	decision VerifyDecision, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	decryptionResult, filename, decision, err = decryptFileWithPolicy(s.ctx,
+		cypherFilename, clearFilename, policy)
+	err = s.checkCompliance("DecryptFile", decryptionResult, err)
+	return
+}
+
+// EncryptDirectory encrypts a directory tree like the package function
+// EncryptDirectory.
+func (s *Session) EncryptDirectory(dir, destinationFilename string,
+	recipients []string, sign bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recipients, err := s.appendSelf(recipients)
+	if err != nil {
+		return fmt.Errorf("EncryptDirectory - %w", err)
+	}
+	return encryptDirectory(context.Background(), s.ctx, dir, destinationFilename,
+		recipients, sign)
+}
+
+// DecryptArchive decrypts and extracts an archive like the package
+// function DecryptArchive.
+func (s *Session) DecryptArchive(cypherFilename, destinationDir string) (
+	decryptionResult gpgme.DecryptResultType, signatures []gpgme.Signature,
+	warning string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	decryptionResult, signatures, warning, err = decryptArchive(context.Background(),
+		s.ctx, cypherFilename, destinationDir)
+	err = s.checkCompliance("DecryptArchive", decryptionResult, err)
+	return
+}
+
+// DeleteKey removes a key from the keyring of the session like the
+// package function DeleteKey. Only OpenPGP keys can be deleted.
+func (s *Session) DeleteKey(fingerprint string, allowSecret, force bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.command(fingerprint)
+	if err == nil {
+		err = deleteKey(cmd, fingerprint, allowSecret, force)
+	}
+	if err != nil {
+		return fmt.Errorf("DeleteKey - %w", err)
+	}
+	return nil
+}
+
+// SetKeyExpiry sets the expiration time of a key like the package
+// function SetKeyExpiry.
+func (s *Session) SetKeyExpiry(fingerprint string, expiry time.Time,
+	subkeyFingerprints ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.command(fingerprint)
+	if err == nil {
+		err = setKeyExpiry(cmd, fingerprint, expiry, subkeyFingerprints)
+	}
+	if err != nil {
+		return fmt.Errorf("SetKeyExpiry - %w", err)
+	}
+	return nil
+}
+
+// DisableKey disables a key of the session's keyring like the package
+// function DisableKey.
+func (s *Session) DisableKey(fingerprint string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		err = editKey(cmd, fingerprint,
+			stepsFunc(DisableKeySteps()))
+	}
+	if err != nil {
+		return fmt.Errorf("DisableKey - %w", err)
+	}
+	return nil
+}
+
+// EnableKey enables a disabled key of the session's keyring like the
+// package function EnableKey.
+func (s *Session) EnableKey(fingerprint string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		err = editKey(cmd, fingerprint,
+			stepsFunc(EnableKeySteps()))
+	}
+	if err != nil {
+		return fmt.Errorf("EnableKey - %w", err)
+	}
+	return nil
+}
+
+// AddUserID adds a user ID to a key like the package function AddUserID.
+func (s *Session) AddUserID(fingerprint, uid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.command(fingerprint)
+	if err == nil {
+		err = editUserID(cmd, "--quick-add-uid", fingerprint, uid)
+	}
+	if err != nil {
+		return fmt.Errorf("AddUserID - %w", err)
+	}
+	return nil
+}
+
+// RevokeUserID revokes a user ID of a key like the package function
+// RevokeUserID.
+func (s *Session) RevokeUserID(fingerprint, uid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.command(fingerprint)
+	if err == nil {
+		err = editUserID(cmd, "--quick-revoke-uid", fingerprint, uid)
+	}
+	if err != nil {
+		return fmt.Errorf("RevokeUserID - %w", err)
+	}
+	return nil
+}
+
+// CertifyKey signs user IDs of a key like the package function
+// CertifyKey, using the configuration of the session.
+func (s *Session) CertifyKey(signerFPR, targetFPR string, uids []string,
+	opts CertifyOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := certifyKey(s.opts, signerFPR, targetFPR, uids, opts)
+	if err != nil {
+		return fmt.Errorf("CertifyKey - %w", err)
+	}
+	return nil
+}
+
+// SetOwnerTrust sets the owner trust of a key like the package function
+// SetOwnerTrust.
+func (s *Session) SetOwnerTrust(fingerprint string, trust gpgme.Validity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		err = setOwnerTrust(cmd, fingerprint, trust)
+	}
+	if err != nil {
+		return fmt.Errorf("SetOwnerTrust - %w", err)
+	}
+	return nil
+}
+
+// ExportSSHPublicKey returns the authentication subkey of a key of the
+// session's keyring in the OpenSSH format like the package function
+// ExportSSHPublicKey.
+func (s *Session) ExportSSHPublicKey(fingerprint string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return exportSSHPublicKey(s.ctx, fingerprint)
+}
+
+// ExportKeyMinimal exports the public key with the given fingerprint
+// without third-party signatures like the package function
+// ExportKeyMinimal, ASCII armored if the session is.
+func (s *Session) ExportKeyMinimal(fingerprint string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return exportKeyMinimal(s.ctx, fingerprint)
+}
+
+// PublishWKD writes the keys matching the patterns of the session's
+// keyring into a Web Key Directory like the package function PublishWKD.
+func (s *Session) PublishWKD(root string, patterns []string, opts WKDOptions) (
+	files []WKDFileType, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		files, err = publishWKD(s.ctx, cmd, root,
+			patterns, opts)
+	}
+	if err != nil {
+		return files, fmt.Errorf("PublishWKD - %w", err)
+	}
+	return files, nil
+}
+
+// GenerateOpenpgpkeyRecord returns the OPENPGPKEY DNS record of a key of
+// the session's keyring like the package function GenerateOpenpgpkeyRecord.
+func (s *Session) GenerateOpenpgpkeyRecord(fingerprint, email string) (
+	record OpenpgpkeyRecordType, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		record, err = generateOpenpgpkeyRecord(s.ctx, cmd,
+			fingerprint, email)
+	}
+	if err != nil {
+		return record, fmt.Errorf("GenerateOpenpgpkeyRecord - %w", err)
+	}
+	return record, nil
+}
+
+// ChangeKeyPassphrase changes the passphrase of a secret key of the
+// session's keyring like the package function ChangeKeyPassphrase.
+func (s *Session) ChangeKeyPassphrase(fingerprint, oldPassphrase,
+	newPassphrase string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		err = changeKeyPassphrase(cmd.homeDir, fingerprint, oldPassphrase, newPassphrase)
+	}
+	if err != nil {
+		return fmt.Errorf("ChangeKeyPassphrase - %w", err)
+	}
+	return nil
+}
+
+// ExportSecretKeyWithPassphrase exports a secret key of the session's
+// keyring protected by transferPassphrase like the package function
+// ExportSecretKeyWithPassphrase.
+func (s *Session) ExportSecretKeyWithPassphrase(fingerprint, passphrase,
+	transferPassphrase string, armored bool) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keyData []byte
+	cmd, err := s.plainCommand()
+	if err == nil {
+		keyData, err = exportSecretKeyWithPassphrase(cmd,
+			fingerprint, passphrase, transferPassphrase, armored)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ExportSecretKeyWithPassphrase - %w", err)
+	}
+	return keyData, nil
+}
+
+// EditKey edits a key of the session's keyring with gpg --edit-key like
+// the package function EditKey.
+func (s *Session) EditKey(fingerprint string, steps []KeyEditStep) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.command(fingerprint)
+	if err == nil {
+		err = editKey(cmd, fingerprint, stepsFunc(steps))
+	}
+	if err != nil {
+		return fmt.Errorf("EditKey - %w", err)
+	}
+	return nil
+}
+
+// EditKeyFunc edits a key of the session's keyring with gpg --edit-key
+// like the package function EditKeyFunc.
+func (s *Session) EditKeyFunc(fingerprint string, fn KeyEditFunc) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.command(fingerprint)
+	if err == nil {
+		err = editKey(cmd, fingerprint, fn)
+	}
+	if err != nil {
+		return fmt.Errorf("EditKeyFunc - %w", err)
+	}
+	return nil
+}
+
+// UserIDNumber returns the number of a user ID for gpg --edit-key like
+// the package function UserIDNumber.
+func (s *Session) UserIDNumber(fingerprint, uid string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var number int
+	cmd, err := s.plainCommand()
+	if err == nil {
+		number, err = userIDNumber(cmd, fingerprint, uid)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("UserIDNumber - %w", err)
+	}
+	return number, nil
+}
+
+// ExportOwnerTrust returns the owner trust of the keys of the session's
+// trust database like the package function ExportOwnerTrust.
+func (s *Session) ExportOwnerTrust() (entries []OwnerTrustEntry, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		entries, err = exportOwnerTrust(cmd)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ExportOwnerTrust - %w", err)
+	}
+	return entries, nil
+}
+
+// ImportOwnerTrust sets the owner trust of keys like the package function
+// ImportOwnerTrust.
+func (s *Session) ImportOwnerTrust(entries []OwnerTrustEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		err = importOwnerTrust(cmd, entries)
+	}
+	if err != nil {
+		return fmt.Errorf("ImportOwnerTrust - %w", err)
+	}
+	return nil
+}
+
+// AddSubkey adds a subkey to a key like the package function AddSubkey.
+func (s *Session) AddSubkey(fingerprint, algo string, capabilities KeyCaps,
+	expiry time.Time) (subkeyFingerprint string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expire, err := expiryTimeArg(expiry)
+	if err == nil {
+		var cmd gpgCommand
+		cmd, err = s.command(fingerprint)
+		if err == nil {
+			subkeyFingerprint, err = addSubkey(cmd, fingerprint, algo, capabilities, expire)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("AddSubkey - %w", err)
+	}
+	return subkeyFingerprint, nil
+}
+
+// KeyserverSearch searches the keyserver like the package function
+// KeyserverSearch.
+func (s *Session) KeyserverSearch(pattern string) (keys []KeyType, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = s.retry(context.Background(), func() (err error) {
+		keys, err = keyserverSearch(s.ctx, pattern)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("KeyserverSearch - %w", err)
+	}
+	return keys, nil
+}
+
+// KeyserverReceive imports keys from the keyserver into the keyring of
+// the session like the package function KeyserverReceive.
+func (s *Session) KeyserverReceive(fingerprints []string) (
+	result *gpgme.ImportResult, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		result, err = keyserverReceive(cmd, fingerprints)
+	}
+	if err != nil {
+		return result, fmt.Errorf("KeyserverReceive - %w", err)
+	}
+	return result, nil
+}
+
+// KeyserverSend sends keys of the keyring of the session to the
+// keyserver like the package function KeyserverSend.
+func (s *Session) KeyserverSend(fingerprints []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		err = keyserverSend(cmd, fingerprints)
+	}
+	if err != nil {
+		return fmt.Errorf("KeyserverSend - %w", err)
+	}
+	return nil
+}
+
+// LocateKeyByEmail finds a key for a mail address and imports it into
+// the keyring of the session like the package function LocateKeyByEmail.
+func (s *Session) LocateKeyByEmail(email string) (key KeyType, source string,
+	err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		key, source, err = locateKeyByEmail(cmd, email)
+	}
+	if err != nil {
+		return key, "", fmt.Errorf("LocateKeyByEmail - %w", err)
+	}
+	return key, source, nil
+}
+
+// RefreshKeys fetches and imports the current versions of keys of the
+// keyring of the session like the package function RefreshKeys.
+func (s *Session) RefreshKeys(patterns []string) (report RefreshReportType, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		report, err = refreshKeys(cmd, patterns)
+	}
+	if err != nil {
+		return report, fmt.Errorf("RefreshKeys - %w", err)
+	}
+	return report, nil
+}
+
+// SignBytesNotations signs a memory buffer with notations like the
+// package function SignBytesNotations.
+func (s *Session) SignBytesNotations(plainText []byte, signWith string,
+	mode gpgme.SigMode, notations []NotationType) (cipherText []byte, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.command(signWith)
+	if err == nil {
+		cipherText, err = signBytesNotations(cmd, plainText, signWith, mode,
+			s.opts.Armor, notations)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("SignBytesNotations - %w", err)
+	}
+	return cipherText, nil
+}
+
+// SignBytesOptions signs a memory buffer with notations and a policy URL
+// like the package function SignBytesOptions, the armor setting is taken
+// from the session options.
+func (s *Session) SignBytesOptions(plainText []byte, signWith string,
+	mode gpgme.SigMode, opts SignOptions) (cipherText []byte, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.command(signWith)
+	if err == nil {
+		cipherText, err = signBytesOptions(cmd, plainText, signWith, mode,
+			s.opts.Armor, opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("SignBytesOptions - %w", err)
+	}
+	return cipherText, nil
+}
+
+// SignFileOptions signs a file with notations and a policy URL like the
+// package function SignFileOptions, the armor setting is taken from the
+// session options.
+func (s *Session) SignFileOptions(sourceFilename, destinationFilename, signWith string,
+	mode gpgme.SigMode, opts SignOptions) (destination string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.command(signWith)
+	if err == nil {
+		destination, err = signFileOptions(cmd, sourceFilename, destinationFilename,
+			signWith, mode, s.opts.Armor, opts)
+	}
+	if err != nil {
+		return "", fmt.Errorf("SignFileOptions - %w", err)
+	}
+	return destination, nil
+}
+
+// VerifyBytesNotations returns the notations of the signatures like the
+// package function VerifyBytesNotations.
+func (s *Session) VerifyBytesNotations(cipherText []byte) (
+	sigNotations []SignatureNotationsType, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		sigNotations, err = verifyBytesNotations(cmd, cipherText)
+	}
+	if err != nil {
+		return sigNotations, fmt.Errorf("VerifyBytes - %w", err)
+	}
+	return sigNotations, nil
+}
+
+// retry runs op with the retry policy of the session, see withRetry.
+func (s *Session) retry(ctx context.Context, op func() error) error {
+	return withRetry(ctx, s.opts.Retry, s.opts.HomeDir, op)
+}
+
+// ErrOpenPGPOnly is returned by the Session methods which call gpg
+// directly, if the session uses another protocol than OpenPGP.
+var ErrOpenPGPOnly = errors.New("only supported for OpenPGP")
+
+// plainCommand returns a gpgCommand for the operations calling gpg
+// directly, using the home directory and the retry policy of the session.
+// It fails with ErrOpenPGPOnly for a CMS session, gpg would not use the
+// keys of gpgsm.
+func (s *Session) plainCommand() (gpgCommand, error) {
+	if s.opts.Protocol != gpgme.ProtocolOpenPGP {
+		return gpgCommand{}, fmt.Errorf("protocol %s: %w", protocolName(s.opts.Protocol),
+			ErrOpenPGPOnly)
+	}
+	return gpgCommand{homeDir: s.opts.HomeDir, retry: s.opts.Retry}, nil
+}
+
+// command returns a gpgCommand like plainCommand, which also uses the
+// passphrase of the session. uidHint is passed to the passphrase function.
+func (s *Session) command(uidHint string) (cmd gpgCommand, err error) {
+	cmd, err = s.plainCommand()
+	if err != nil {
+		return cmd, err
+	}
+	if s.opts.Passphrase != nil {
+		cmd.passphrase, err = s.opts.Passphrase(uidHint, false)
+		if err != nil {
+			return cmd, err
+		}
+		cmd.hasPassphrase = true
+	}
+	return cmd, nil
+}
+
+// KeyList returns the keys matching lookFor like the package function KeyList.
+func (s *Session) KeyList(lookFor string) (keys []KeyType, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return keyList(context.Background(), s.ctx, lookFor)
+}
+
+// KeyListFast returns the keys matching lookFor without their key
+// signatures like the package function KeyListFast.
+func (s *Session) KeyListFast(lookFor string) (keys []KeyType, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return keyListFiltered(context.Background(), s.ctx, lookFor,
+		KeyFilter{WithoutSignatures: true})
+}
+
+// LoadKeySignatures sets the key signatures on the user IDs of key like
+// the package function LoadKeySignatures.
+func (s *Session) LoadKeySignatures(key *KeyType) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return loadKeySignatures(s.ctx, key)
+}
+
+// LoadKeyDetails sets the details of key like the package function
+// LoadKeyDetails.
+func (s *Session) LoadKeyDetails(key *KeyType) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return loadKeyDetails(s.ctx, key)
+}
+
+// KeyListFiltered returns the keys matching lookFor and selected by filter
+// like the package function KeyListFiltered.
+func (s *Session) KeyListFiltered(lookFor string, filter KeyFilter) (
+	keys []KeyType, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return keyListFiltered(context.Background(), s.ctx, lookFor, filter)
+}
+
+// ListExpiringKeys returns the keys of the session's keyring about to
+// expire like the package function ListExpiringKeys.
+func (s *Session) ListExpiringKeys(within time.Duration, secretOnly bool) (
+	keys []ExpiringKeyType, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys, err = listExpiringKeys(s.ctx, within, secretOnly)
+	if err != nil {
+		return nil, fmt.Errorf("ListExpiringKeys - %w", err)
+	}
+	return keys, nil
+}
+
+// LaunchDaemon starts a daemon for the session's home directory like the
+// package function LaunchDaemon.
+func (s *Session) LaunchDaemon(daemon string) error {
+	if err := controlDaemon(s.opts.HomeDir, "--launch", daemon); err != nil {
+		return fmt.Errorf("LaunchDaemon - %w", err)
+	}
+	return nil
+}
+
+// ReloadDaemon reloads a daemon of the session's home directory like the
+// package function ReloadDaemon.
+func (s *Session) ReloadDaemon(daemon string) error {
+	if err := controlDaemon(s.opts.HomeDir, "--reload", daemon); err != nil {
+		return fmt.Errorf("ReloadDaemon - %w", err)
+	}
+	return nil
+}
+
+// KillDaemon stops a daemon of the session's home directory like the
+// package function KillDaemon.
+func (s *Session) KillDaemon(daemon string) error {
+	if err := controlDaemon(s.opts.HomeDir, "--kill", daemon); err != nil {
+		return fmt.Errorf("KillDaemon - %w", err)
+	}
+	return nil
+}
+
+// FlushPassphraseCache flushes the passphrase cache of the gpg-agent of
+// the session's home directory like the package function
+// FlushPassphraseCache.
+func (s *Session) FlushPassphraseCache(fingerprints ...string) error {
+	if err := flushPassphraseCache(s.opts.HomeDir, fingerprints); err != nil {
+		return fmt.Errorf("FlushPassphraseCache - %w", err)
+	}
+	return nil
+}
+
+// PresetPassphrase stores a passphrase in the cache of the gpg-agent of
+// the session's home directory like the package function PresetPassphrase.
+func (s *Session) PresetPassphrase(keygrip, passphrase string, ttl time.Duration) error {
+	if err := presetPassphrase(s.opts.HomeDir, keygrip, passphrase, ttl); err != nil {
+		return fmt.Errorf("PresetPassphrase - %w", err)
+	}
+	return nil
+}
+
+// EngineReport describes the GnuPG installation like the package
+// function EngineReport, for the session's home directory.
+func (s *Session) EngineReport() (report EngineReportType, err error) {
+	report.Engine, report.HomeDir, report.RequiredVersion, report.Version, err =
+		GpgEngineInfo()
+	if err != nil {
+		return report, fmt.Errorf("EngineReport - %w", err)
+	}
+	if s.opts.HomeDir != "" {
+		report.HomeDir = s.opts.HomeDir
+	}
+	err = engineReport(&report, report.HomeDir)
+	if err != nil {
+		return report, fmt.Errorf("EngineReport - %w", err)
+	}
+	return report, nil
+}
+
+// ReadConfig reads a configuration file of the session's home directory
+// like the package function ReadConfig.
+func (s *Session) ReadConfig(name string) (config *ConfigFile, err error) {
+	config, err = readConfig(configPath(s.opts.HomeDir, name))
+	if err != nil {
+		return nil, fmt.Errorf("ReadConfig - %w", err)
+	}
+	return config, nil
+}
+
+// ModifyConfig changes a configuration file of the session's home
+// directory like the package function ModifyConfig.
+func (s *Session) ModifyConfig(name string, fn func(config *ConfigFile) error) error {
+	err := modifyConfig(configPath(s.opts.HomeDir, name), fn)
+	if err != nil {
+		return fmt.Errorf("ModifyConfig - %w", err)
+	}
+	return nil
+}
+
+// ListPacketRecipients inspects an encrypted file like the package
+// function ListPacketRecipients.
+func (s *Session) ListPacketRecipients(filename string) (info PacketInfo, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		info, err = listPacketRecipients(s.ctx, cmd, filename)
+	}
+	if err != nil {
+		return info, fmt.Errorf("ListPacketRecipients - %w", err)
+	}
+	return info, nil
+}
+
+// HaveSecretKeyFor checks the secret keys for encrypted data like the
+// package function HaveSecretKeyFor.
+func (s *Session) HaveSecretKeyFor(cipherText []byte) (have bool,
+	keys []SecretKeyAvailability, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		have, keys, err = haveSecretKeyFor(s.ctx, cmd,
+			cipherText)
+	}
+	if err != nil {
+		return false, nil, fmt.Errorf("HaveSecretKeyFor - %w", err)
+	}
+	return have, keys, nil
+}
+
+// HaveSecretKeys checks the secret keys for key IDs like the package
+// function HaveSecretKeys.
+func (s *Session) HaveSecretKeys(keyIDs []string) (have bool,
+	keys []SecretKeyAvailability, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	have, keys, err = haveSecretKeys(s.ctx, keyIDs)
+	if err != nil {
+		return false, nil, fmt.Errorf("HaveSecretKeys - %w", err)
+	}
+	return have, keys, nil
+}
+
+// ListCardKeys returns the secret keys stored on smartcards like the
+// package function ListCardKeys.
+func (s *Session) ListCardKeys() (keys []KeyType, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys, err = listCardKeys(s.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ListCardKeys - %w", err)
+	}
+	return keys, nil
+}
+
+// KeyListWithTofu returns the keys matching lookFor with TOFU information
+// like the package function KeyListWithTofu.
+func (s *Session) KeyListWithTofu(lookFor string) (keys []KeyType, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return keyListWithTofu(s.ctx, lookFor)
+}
+
+// TofuInfo returns the TOFU information of a key like the package
+// function TofuInfo.
+func (s *Session) TofuInfo(fingerprint string) (infos map[string]TofuInfoType,
+	err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		infos, err = tofuInfo(cmd, fingerprint)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("TofuInfo - %w", err)
+	}
+	return infos, nil
+}
+
+// SetTofuPolicy sets the TOFU policy of a key like the package function
+// SetTofuPolicy.
+func (s *Session) SetTofuPolicy(fingerprint string, policy TofuPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cmd, err := s.plainCommand()
+	if err == nil {
+		err = setTofuPolicy(cmd, fingerprint, policy)
+	}
+	if err != nil {
+		return fmt.Errorf("SetTofuPolicy - %w", err)
+	}
+	return nil
+}
+
+// KeyListEach calls fn for each key matching lookFor and selected by
+// filter like the package function KeyListEachCtx.
+// fn must not use the session, which is locked during the listing.
+func (s *Session) KeyListEach(lookFor string, filter KeyFilter,
+	fn func(KeyType) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return keyListEach(context.Background(), s.ctx, lookFor, filter, fn)
+}
+
+// EOF
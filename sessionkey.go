@@ -0,0 +1,119 @@
+/* sessionkey.go - session key export and reuse for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// gpgme.go can not set the context flags export-session-key and
+// override-session-key, so gpg is called directly.
+
+package gpggohigh
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// DecryptBytesSessionKey decrypts a memory buffer like DecryptBytes and
+// also returns the session key of the encrypted data, like
+// `gpg --show-session-key`. The session key has the form
+// "<cipher algo>:<hex key>" and allows to decrypt the data again with
+// DecryptWithSessionKey, without the secret key.
+// Anyone knowing the session key can read the data, so it must be
+// stored as securely as the data itself.
+func DecryptBytesSessionKey(cipherText []byte) (plainText []byte,
+	sessionKey string, err error) {
+
+	plainText, sessionKey, err = decryptBytesSessionKey(gpgCommand{}, cipherText)
+	if err != nil {
+		return nil, "", fmt.Errorf("DecryptBytesSessionKey - %w", err)
+	}
+	return plainText, sessionKey, nil
+}
+
+// decryptBytesSessionKey implements DecryptBytesSessionKey running the
+// command cmd.
+func decryptBytesSessionKey(cmd gpgCommand, cipherText []byte) (
+	plainText []byte, sessionKey string, err error) {
+
+	var out bytes.Buffer
+	cmd.args = []string{"--decrypt", "--show-session-key"}
+	cmd.stdin = bytes.NewReader(cipherText)
+	cmd.stdout = &out
+	status, err := cmd.run()
+	auditStatusDecrypt("DecryptBytesSessionKey", status, err, cipherText, out.Bytes())
+	if err != nil {
+		return nil, "", err
+	}
+	args, found := findStatus(status, "SESSION_KEY")
+	if !found || len(args) == 0 {
+		return nil, "", fmt.Errorf("no session key reported")
+	}
+	return out.Bytes(), args[0], nil
+}
+
+// DecryptWithSessionKey decrypts a memory buffer with the session key
+// returned by DecryptBytesSessionKey, without using a secret key.
+// Signatures in the data are not verified.
+func DecryptWithSessionKey(cipherText []byte, sessionKey string) (
+	plainText []byte, err error) {
+
+	plainText, err = decryptWithSessionKey(gpgCommand{}, cipherText, sessionKey)
+	if err != nil {
+		return nil, fmt.Errorf("DecryptWithSessionKey - %w", err)
+	}
+	return plainText, nil
+}
+
+// decryptWithSessionKey implements DecryptWithSessionKey running the
+// command cmd.
+func decryptWithSessionKey(cmd gpgCommand, cipherText []byte, sessionKey string) (
+	[]byte, error) {
+
+	if sessionKey == "" {
+		return nil, fmt.Errorf("no session key given")
+	}
+	var out bytes.Buffer
+	cmd.args = []string{"--decrypt", "--skip-verify"}
+	cmd.stdin = bytes.NewReader(cipherText)
+	cmd.stdout = &out
+	cmd.fdOption = "--override-session-key-fd"
+	cmd.fdData = sessionKey
+	status, err := cmd.run()
+	auditStatusDecrypt("DecryptWithSessionKey", status, err, cipherText, out.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// auditStatusDecrypt audits the decryption of cipherText to plainText by
+// gpg as done by the function fn, with the status lines status.
+func auditStatusDecrypt(fn string, status []gpgStatus, err error,
+	cipherText, plainText []byte) {
+
+	audit(AuditDecrypt, fn, err, func(event *AuditEvent) {
+		event.Recipients = decryptRecipients(decryptResultFromStatus(status))
+		event.Signers = signatureFingerprints(signaturesFromStatus(status))
+		event.InputSHA256 = hashBytes(cipherText)
+		if err == nil {
+			event.OutputSHA256 = hashBytes(plainText)
+		}
+	})
+}
+
+// EOF
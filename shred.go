@@ -0,0 +1,90 @@
+/* shred.go - removing source files after encryption for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// Overwriting a file only destroys its content on file systems which
+// write in place. Copy-on-write and log-structured file systems (btrfs,
+// ZFS, APFS, F2FS), journals with data journaling, snapshots, backups
+// and the wear leveling of flash storage may keep copies of the old
+// content. Only encrypting the whole storage protects against that.
+
+package gpggohigh
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+)
+
+// RemoveMode selects what happens to the source file after a successful
+// encryption, see EncryptOptions.
+type RemoveMode int
+
+const (
+	RemoveSourceKeep      RemoveMode = iota // keep the source file
+	RemoveSourceUnlink                      // remove the source file
+	RemoveSourceOverwrite                   // overwrite the content with random data, then remove the file
+)
+
+// removeSource removes the file name as selected by mode.
+func removeSource(name string, mode RemoveMode) error {
+	switch mode {
+	case RemoveSourceKeep:
+		return nil
+	case RemoveSourceUnlink:
+	case RemoveSourceOverwrite:
+		if err := overwriteFile(name); err != nil {
+			return fmt.Errorf("overwriting %s failed: %w", name, err)
+		}
+	default:
+		return fmt.Errorf("unknown remove mode %d", mode)
+	}
+	if err := os.Remove(name); err != nil {
+		return fmt.Errorf("removing %s failed: %w", name, err)
+	}
+	return nil
+}
+
+// overwriteFile overwrites the content of the regular file name with
+// random data and flushes it to disk. The size is not changed.
+func overwriteFile(name string) error {
+	fh, err := os.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	info, err := fh.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("not a regular file")
+	}
+	if _, err := io.CopyN(fh, rand.Reader, info.Size()); err != nil {
+		return err
+	}
+	if err := fh.Sync(); err != nil {
+		return err
+	}
+	return fh.Close()
+}
+
+// EOF
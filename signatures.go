@@ -21,8 +21,10 @@
 package gpggohigh
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/kulbartsch/gpgme"
 )
@@ -38,23 +40,96 @@ import (
 //   - err: an error if the signing fails
 func SignBytes(plainText []byte, signWith string, armored bool) (
 	cipherText []byte, n int, signingFingerPrints []string, err error) {
+	return signBytesWith(context.Background(), plainText, signWith, gpgme.SigModeNormal, armored)
+}
+
+// SignBytesCtx signs a memory buffer like SignBytes. Like for
+// EncryptBytesCtx, ctx is only checked while plainText is read, not
+// while the passphrase is requested.
+func SignBytesCtx(ctx context.Context, plainText []byte, signWith string, armored bool) (
+	cipherText []byte, n int, signingFingerPrints []string, err error) {
+	return signBytesWith(ctx, plainText, signWith, gpgme.SigModeNormal, armored)
+}
 
-	myContext, err := gpgme.New()
+// SignBytesMode signs a memory buffer like SignBytes, but with a
+// selectable signature mode:
+//
+//   - gpgme.SigModeNormal: the signed data includes the signature
+//   - gpgme.SigModeDetach: only the signature is returned
+//   - gpgme.SigModeClear: the text is clearsigned, i.e. kept human readable
+//     with an inline signature; the output is always ASCII armored
+func SignBytesMode(plainText []byte, signWith string, mode gpgme.SigMode,
+	armored bool) (cipherText []byte, n int, signingFingerPrints []string, err error) {
+	return signBytesWith(context.Background(), plainText, signWith, mode, armored)
+}
+
+// SignBytesResult signs a memory buffer like SignBytesMode and also
+// returns the details of the created signatures, like the hash
+// algorithm, the signature class and the creation time.
+// Unlike SignBytes, reaching the end of the data is not reported as io.EOF.
+func SignBytesResult(plainText []byte, signWith string, mode gpgme.SigMode,
+	armored bool) (cipherText []byte, newSignatures []NewSignatureType, err error) {
+
+	myContext, err := newContext(SessionOptions{Armor: armored})
 	if err != nil {
-		err = fmt.Errorf("SignBytes - gpgme.New failed: %w", err)
-		return
+		return nil, nil, fmt.Errorf("SignBytes - %w", err)
 	}
 	defer myContext.Release()
 
-	err = myContext.SetProtocol(gpgme.ProtocolOpenPGP)
+	cipherText, _, _, err = signBytes(context.Background(), myContext, plainText,
+		signWith, mode)
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+
+	newSignatures, err = describeNewSignatures(myContext, plainText, cipherText, mode)
 	if err != nil {
-		err = fmt.Errorf("SignBytes - SetProtocol failed: %w", err)
+		return cipherText, nil, fmt.Errorf("SignBytes - %w", err)
+	}
+	return cipherText, newSignatures, nil
+}
+
+// signBytesWith creates a context with the armor setting and signs with it.
+func signBytesWith(ctx context.Context, plainText []byte, signWith string,
+	mode gpgme.SigMode, armored bool) (cipherText []byte, n int,
+	signingFingerPrints []string, err error) {
+
+	myContext, err := newContext(SessionOptions{Armor: armored})
+	if err != nil {
+		err = fmt.Errorf("SignBytes - %w", err)
 		return
 	}
+	defer myContext.Release()
 
-	myContext.SetArmor(armored)
+	err = withRetry(ctx, nil, "", func() (err error) {
+		cipherText, n, signingFingerPrints, err = signBytes(ctx, myContext, plainText,
+			signWith, mode)
+		return err
+	})
+	return
+}
+
+// signBytes implements SignBytes using myContext.
+func signBytes(ctx context.Context, myContext *gpgme.Context, plainText []byte,
+	signWith string, mode gpgme.SigMode) (cipherText []byte, n int,
+	signingFingerPrints []string, err error) {
+
+	defer func() {
+		// a successful signing ends with io.EOF, see SignBytes
+		auditErr := err
+		if auditErr == io.EOF {
+			auditErr = nil
+		}
+		audit(AuditSign, "SignBytes", auditErr, func(event *AuditEvent) {
+			event.Signers = signingFingerPrints
+			event.InputSHA256 = hashBytes(plainText)
+			if auditErr == nil {
+				event.OutputSHA256 = hashBytes(cipherText)
+			}
+		})
+	}()
 
-	dataIn, err := gpgme.NewDataBytes(plainText)
+	dataIn, err := newDataBytesCtx(ctx, plainText)
 	if err != nil {
 		err = fmt.Errorf("SignBytes - NewData (in) failed: %w", err)
 		return
@@ -69,7 +144,7 @@ func SignBytes(plainText []byte, signWith string, armored bool) (
 	defer dataOut.Close()
 
 	var thisRecipients []*gpgme.Key
-	keys, err := gpgme.FindKeys(signWith, true)
+	keys, err := findKeys(myContext, signWith, true)
 	if err != nil {
 		err = fmt.Errorf("SignBytes - FindKeys (out) failed: %w", err)
 		return
@@ -80,36 +155,177 @@ func SignBytes(plainText []byte, signWith string, armored bool) (
 		signingFingerPrints = append(signingFingerPrints, key.Fingerprint())
 	}
 
-	err = myContext.Sign(thisRecipients, dataIn, dataOut, gpgme.SigModeNormal)
+	err = myContext.Sign(thisRecipients, dataIn, dataOut, mode)
 	if err != nil {
-		err = fmt.Errorf("SignBytes - Encrypt failed: %w", err)
+		err = fmt.Errorf("SignBytes - Encrypt failed: %w", ctxError(ctx, err))
 		return
 	}
 
 	// dt := dataOut.Identify() // debug
 	// fmt.Printf("Identify: %s\n", DataTypeMapString[dt]) // debug
-	err = dataOut.Rewind()
+	cipherText, err = readData(dataOut)
 	if err != nil {
-		err = fmt.Errorf("SignBytes - Rewind failed: %w", err)
+		err = fmt.Errorf("SignBytes - %w", err)
 		return
 	}
+	// callers check for io.EOF as the success of the read
+	n, err = len(cipherText), io.EOF
+	return
+}
+
+// NewSignatureType describes a signature created by signing.
+type NewSignatureType struct {
+	Fingerprint string           // of the signing (sub)key
+	PubkeyAlgo  gpgme.PubkeyAlgo // algorithm of the signing key
+	HashAlgo    gpgme.HashAlgo   // hash algorithm of the signature
+	SigClass    int              // 0x00 for binary, 0x01 for text documents
+	Timestamp   time.Time        // creation time of the signature
+}
+
+// SignBytesMulti signs a memory buffer with several keys in one pass,
+// so the result carries one signature per signer.
+//
+//   - signWith: the keys to sign with, fingerprints or user IDs; each
+//     must select at least one secret key
+//   - mode: the signature mode, see SignBytesMode
+//   - newSignatures: one entry per created signature
+//
+// The passphrase of each key is requested separately, e.g. from the
+// passphrase function of a Session with the key as uidHint.
+func SignBytesMulti(plainText []byte, signWith []string, mode gpgme.SigMode,
+	armored bool) (cipherText []byte, newSignatures []NewSignatureType, err error) {
+
+	myContext, err := newContext(SessionOptions{Armor: armored})
+	if err != nil {
+		return nil, nil, fmt.Errorf("SignBytes - %w", err)
+	}
+	defer myContext.Release()
+
+	return signBytesMulti(context.Background(), myContext, plainText, signWith, mode)
+}
+
+// signBytesMulti implements SignBytesMulti using myContext.
+func signBytesMulti(ctx context.Context, myContext *gpgme.Context, plainText []byte,
+	signWith []string, mode gpgme.SigMode) (cipherText []byte,
+	newSignatures []NewSignatureType, err error) {
+
+	var signers []*gpgme.Key
+	defer func() {
+		audit(AuditSign, "SignBytes", err, func(event *AuditEvent) {
+			event.Signers = keyFingerprints(signers)
+			event.InputSHA256 = hashBytes(plainText)
+			if err == nil {
+				event.OutputSHA256 = hashBytes(cipherText)
+			}
+		})
+	}()
+
+	signers, err = findSigners(myContext, signWith)
+	if err != nil {
+		return nil, nil, fmt.Errorf("SignBytes - %w", err)
+	}
+
+	dataIn, err := newDataBytesCtx(ctx, plainText)
+	if err != nil {
+		return nil, nil, fmt.Errorf("SignBytes - NewData (in) failed: %w", err)
+	}
+	defer dataIn.Close()
+
+	dataOut, err := gpgme.NewData()
+	if err != nil {
+		return nil, nil, fmt.Errorf("SignBytes - NewData (out) failed: %w", err)
+	}
+	defer dataOut.Close()
+
+	err = myContext.Sign(signers, dataIn, dataOut, mode)
+	if err != nil {
+		return nil, nil, fmt.Errorf("SignBytes - Sign failed: %w", ctxError(ctx, err))
+	}
+
+	cipherText, err = readData(dataOut)
+	if err != nil {
+		return nil, nil, fmt.Errorf("SignBytes - %w", err)
+	}
+
+	newSignatures, err = describeNewSignatures(myContext, plainText, cipherText, mode)
+	if err != nil {
+		return cipherText, nil, fmt.Errorf("SignBytes - %w", err)
+	}
+	return cipherText, newSignatures, nil
+}
+
+// findSigners returns the secret keys selected by the signWith patterns.
+// Each pattern must select at least one key.
+func findSigners(myContext *gpgme.Context, signWith []string) (
+	signers []*gpgme.Key, err error) {
 
-	cipherTextPart := make([]byte, 10240) // , 10240)
-	cipherText = make([]byte, 0)          // , 10240)
-	// read cipher text in chunks and append to cipherText until io.EOF is reached
-	for {
-		n, err = dataOut.Read(cipherTextPart)
-		if err != nil && err != io.EOF {
-			err = fmt.Errorf("SignBytes - Read failed: %w", err)
-			return
+	if len(signWith) == 0 {
+		return nil, fmt.Errorf("no signer given")
+	}
+	for _, pattern := range signWith {
+		keys, err := findKeys(myContext, pattern, true)
+		if err != nil {
+			return nil, fmt.Errorf("FindKeys failed: %w", err)
 		}
-		cipherText = append(cipherText, cipherTextPart[:n]...)
-		if err == io.EOF {
-			break
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("no secret key for %q: %w", pattern, ErrKeyNotFound)
 		}
+		signers = append(signers, keys...)
 	}
+	return signers, nil
+}
 
-	return
+// describeNewSignatures describes the signatures just created in cipherText.
+// gpgme.go does not provide the sign result of gpgme, so the signatures
+// are verified to get their details.
+func describeNewSignatures(myContext *gpgme.Context, plainText, cipherText []byte,
+	mode gpgme.SigMode) ([]NewSignatureType, error) {
+
+	sigData, err := gpgme.NewDataBytes(cipherText)
+	if err != nil {
+		return nil, fmt.Errorf("NewData (signature) failed: %w", err)
+	}
+	defer sigData.Close()
+
+	var signatures []gpgme.Signature
+	if mode == gpgme.SigModeDetach {
+		signedData, err := gpgme.NewDataBytes(plainText)
+		if err != nil {
+			return nil, fmt.Errorf("NewData (signed) failed: %w", err)
+		}
+		defer signedData.Close()
+		_, signatures, err = myContext.Verify(sigData, signedData, nil)
+		if err != nil {
+			return nil, fmt.Errorf("Verify failed: %w", err)
+		}
+	} else {
+		plainOut, err := gpgme.NewData()
+		if err != nil {
+			return nil, fmt.Errorf("NewData (plain) failed: %w", err)
+		}
+		defer plainOut.Close()
+		_, signatures, err = myContext.Verify(sigData, nil, plainOut)
+		if err != nil {
+			return nil, fmt.Errorf("Verify failed: %w", err)
+		}
+	}
+
+	// gpg creates text signatures for clearsigned data and in text mode
+	sigClass := 0x00
+	if mode == gpgme.SigModeClear || myContext.TextMode() {
+		sigClass = 0x01
+	}
+	newSigs := make([]NewSignatureType, 0, len(signatures))
+	for _, sig := range signatures {
+		newSigs = append(newSigs, NewSignatureType{
+			Fingerprint: sig.Fingerprint,
+			PubkeyAlgo:  sig.PubkeyAlgo,
+			HashAlgo:    sig.HashAlgo,
+			SigClass:    sigClass,
+			Timestamp:   sig.Timestamp,
+		})
+	}
+	return newSigs, nil
 }
 
 // VerifyBytes verifies a signature on a memory buffer and returns the verification result.
@@ -120,21 +336,101 @@ func SignBytes(plainText []byte, signWith string, armored bool) (
 //   - err: an error if the verification fails
 func VerifyBytes(cipherText []byte) (plainText []byte, signatures []gpgme.Signature,
 	filename string, err error) {
+	return VerifyBytesCtx(context.Background(), cipherText)
+}
 
-	myContext, err := gpgme.New()
+// VerifyBytesCtx verifies a signature on a memory buffer like VerifyBytes.
+// The verification fails with the error of ctx, if ctx is done before
+// cipherText has been read completely; see DecryptBytesCtx.
+func VerifyBytesCtx(ctx context.Context, cipherText []byte) (plainText []byte,
+	signatures []gpgme.Signature, filename string, err error) {
+
+	if pool := defaultPool.Load(); pool != nil {
+		s, err := pool.acquire()
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("VerifyBytes - %w", err)
+		}
+		defer pool.release(s)
+		return s.verifyBytes(ctx, cipherText)
+	}
+
+	myContext, err := newContext(SessionOptions{})
 	if err != nil {
-		err = fmt.Errorf("VerifyBytes - gpgme.New failed: %w", err)
+		err = fmt.Errorf("VerifyBytes - %w", err)
 		return
 	}
 	defer myContext.Release()
 
-	err = myContext.SetProtocol(gpgme.ProtocolOpenPGP)
+	err = withRetry(ctx, nil, "", func() (err error) {
+		plainText, signatures, filename, err = verifyBytes(ctx, myContext, cipherText)
+		return err
+	})
+	return
+}
+
+// VerifyBytesWithKeys verifies a signature on a memory buffer like
+// VerifyBytes, but only with the public keys pubKeys, binary or ASCII
+// armored, instead of the keyring. The keys are imported into a
+// temporary home directory, see NewEphemeralHome, which is removed
+// afterwards. They are trusted ultimately there, so a good signature of
+// one of them is valid and the result does not depend on the keyring and
+// the trust database of the host. Signatures of other keys are reported
+// with a missing key.
+func VerifyBytesWithKeys(cipherText []byte, pubKeys [][]byte) (plainText []byte,
+	signatures []gpgme.Signature, filename string, err error) {
+
+	if len(pubKeys) == 0 {
+		err = fmt.Errorf("VerifyBytesWithKeys - no keys given")
+		return
+	}
+	home, err := NewEphemeralHome()
 	if err != nil {
-		err = fmt.Errorf("VerifyBytes - SetProtocol failed: %w", err)
+		err = fmt.Errorf("VerifyBytesWithKeys - %w", err)
 		return
 	}
+	defer func() {
+		if closeErr := home.Close(); closeErr != nil && (err == nil || err == io.EOF) {
+			plainText, signatures, filename = nil, nil, ""
+			err = fmt.Errorf("VerifyBytesWithKeys - %w", closeErr)
+		}
+	}()
 
-	dataIn, err := gpgme.NewDataBytes(cipherText)
+	cmd := gpgCommand{homeDir: home.opts.HomeDir}
+	for i, keyData := range pubKeys {
+		result, err := home.ImportKeys(keyData)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("VerifyBytesWithKeys - key %d: %w", i, err)
+		}
+		imported := ImportedFingerprints(result)
+		if len(imported) == 0 {
+			return nil, nil, "", fmt.Errorf("VerifyBytesWithKeys - key %d: no key imported", i)
+		}
+		for _, fpr := range imported {
+			if err := setOwnerTrust(cmd, fpr, gpgme.ValidityUltimate); err != nil {
+				return nil, nil, "", fmt.Errorf("VerifyBytesWithKeys - key %d: %w", i, err)
+			}
+		}
+	}
+	return home.VerifyBytes(cipherText)
+}
+
+// verifyBytes implements VerifyBytes using myContext.
+func verifyBytes(ctx context.Context, myContext *gpgme.Context, cipherText []byte) (
+	plainText []byte, signatures []gpgme.Signature, filename string, err error) {
+
+	defer func() {
+		// a successful verification ends with io.EOF like SignBytes
+		auditErr := err
+		if auditErr == io.EOF {
+			auditErr = nil
+		}
+		audit(AuditVerify, "VerifyBytes", auditErr, func(event *AuditEvent) {
+			event.Signers = signatureFingerprints(signatures)
+			event.InputSHA256 = hashBytes(cipherText)
+		})
+	}()
+
+	dataIn, err := newDataBytesCtx(ctx, cipherText)
 	if err != nil {
 		err = fmt.Errorf("VerifyBytes - NewData (in) failed: %w", err)
 		return
@@ -150,35 +446,171 @@ func VerifyBytes(cipherText []byte) (plainText []byte, signatures []gpgme.Signat
 
 	filename, signatures, err = myContext.Verify(dataIn, nil, dataOut)
 	if err != nil {
-		err = fmt.Errorf("VerifyBytes - Verify failed: %w", err)
+		err = fmt.Errorf("VerifyBytes - Verify failed: %w", ctxError(ctx, err))
 		return
 	}
 
-	err = dataOut.Rewind()
+	plainText, err = readData(dataOut)
 	if err != nil {
-		err = fmt.Errorf("VerifyBytes - Rewind failed: %w", err)
+		err = fmt.Errorf("VerifyBytes - %w", err)
 		return
 	}
+	// callers check for io.EOF as the success of the read
+	err = io.EOF
+	return
+}
 
-	var n int
-	plainTextPart := make([]byte, 10240)
-	for {
-		n, err = dataOut.Read(plainTextPart)
-		if err != nil && err != io.EOF {
-			err = fmt.Errorf("VerifyBytes - Read failed: %w", err)
-			return
-		}
-		if n > 0 {
-			plainText = append(plainText, plainTextPart[:n]...)
-		}
-		if err == io.EOF {
-			break
-		}
+// VerifyFile verifies the signed file signedFilename, which contains the
+// signature, and writes the data without the signature to plainFilename.
+// If plainFilename is empty, the extension `.gpg`, `.pgp` or `.asc` is
+// removed from signedFilename. An existing file is not overwritten.
+//
+//   - destination: the file the signed data was written to
+//   - signatures: a slice of gpgme.Signature containing the verification results
+//   - filename: the file name embedded in the signed data, if any
+//   - err: an error if the verification fails
+func VerifyFile(signedFilename, plainFilename string) (destination string,
+	signatures []gpgme.Signature, filename string, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		err = fmt.Errorf("VerifyFile - %w", err)
+		return
+	}
+	defer myContext.Release()
+
+	return verifyFile(myContext, signedFilename, plainFilename)
+}
+
+// verifyFile implements VerifyFile using myContext.
+func verifyFile(myContext *gpgme.Context, signedFilename, plainFilename string) (
+	destination string, signatures []gpgme.Signature, filename string, err error) {
+
+	defer func() {
+		audit(AuditVerify, "VerifyFile", err, func(event *AuditEvent) {
+			event.Signers = signatureFingerprints(signatures)
+			event.Input, event.InputSHA256 = signedFilename, hashFile(signedFilename)
+			if err == nil {
+				event.Output, event.OutputSHA256 = destination, hashFile(destination)
+			}
+		})
+	}()
+
+	dataIn, err := gpgme.NewData()
+	if err != nil {
+		err = fmt.Errorf("VerifyFile - NewData (in) failed: %w", err)
+		return
+	}
+	defer dataIn.Close()
+
+	err = dataIn.SetFileName(signedFilename)
+	if err != nil {
+		err = fmt.Errorf("VerifyFile - SetFileName (in) failed: %w", err)
+		return
+	}
+
+	destination, err = decryptDestination(signedFilename, plainFilename)
+	if err != nil {
+		err = fmt.Errorf("VerifyFile - %w", err)
+		return
+	}
+
+	dataOut, err := gpgme.NewData()
+	if err != nil {
+		err = fmt.Errorf("VerifyFile - NewData (out) failed: %w", err)
+		return
+	}
+	defer dataOut.Close()
+
+	err = dataOut.SetFileName(destination)
+	if err != nil {
+		err = fmt.Errorf("VerifyFile - SetFileName (out) failed: %w", err)
+		return
 	}
 
+	filename, signatures, err = myContext.Verify(dataIn, nil, dataOut)
+	if err != nil {
+		err = fmt.Errorf("VerifyFile - Verify failed: %w", err)
+		return
+	}
 	return
 }
 
+// VerifyFileDetached verifies the detached signature in the file
+// signatureFilename, binary or ASCII armored, for the file dataFilename.
+// The signatures contain the verification results.
+func VerifyFileDetached(signatureFilename, dataFilename string) (
+	signatures []gpgme.Signature, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("VerifyFileDetached - %w", err)
+	}
+	defer myContext.Release()
+
+	return verifyFileDetached(myContext, signatureFilename, dataFilename)
+}
+
+// verifyFileDetached implements VerifyFileDetached using myContext.
+func verifyFileDetached(myContext *gpgme.Context, signatureFilename,
+	dataFilename string) (signatures []gpgme.Signature, err error) {
+
+	defer func() {
+		audit(AuditVerify, "VerifyFileDetached", err, func(event *AuditEvent) {
+			event.Signers = signatureFingerprints(signatures)
+			event.Input, event.InputSHA256 = dataFilename, hashFile(dataFilename)
+		})
+	}()
+
+	dataSig, err := gpgme.NewData()
+	if err != nil {
+		return nil, fmt.Errorf("VerifyFileDetached - NewData (signature) failed: %w", err)
+	}
+	defer dataSig.Close()
+
+	err = dataSig.SetFileName(signatureFilename)
+	if err != nil {
+		return nil, fmt.Errorf("VerifyFileDetached - SetFileName (signature) failed: %w", err)
+	}
+
+	dataSigned, err := gpgme.NewData()
+	if err != nil {
+		return nil, fmt.Errorf("VerifyFileDetached - NewData (data) failed: %w", err)
+	}
+	defer dataSigned.Close()
+
+	err = dataSigned.SetFileName(dataFilename)
+	if err != nil {
+		return nil, fmt.Errorf("VerifyFileDetached - SetFileName (data) failed: %w", err)
+	}
+
+	_, signatures, err = myContext.Verify(dataSig, dataSigned, nil)
+	if err != nil {
+		return nil, fmt.Errorf("VerifyFileDetached - Verify failed: %w", err)
+	}
+	return signatures, nil
+}
+
+// ClearSignText clearsigns a text given as a slice of lines and returns
+// the signed text as a slice of lines, like produced by `gpg --clearsign`.
+//
+//   - text: the lines of the text to be signed
+//   - signWith: the key to sign with, can be a fingerprint or a user ID
+//   - signedText: the lines of the clearsigned text
+//   - signingFingerPrints: a slice of fingerprints of the keys used for signing
+//   - err: an error if the signing fails
+func ClearSignText(text []string, signWith string) (signedText []string,
+	signingFingerPrints []string, err error) {
+
+	signed, _, signingFingerPrints, err := SignBytesMode(TextArrayToBytes(text),
+		signWith, gpgme.SigModeClear, true)
+	// SignBytesMode reports reading until the end of the data as io.EOF
+	if err != nil && err != io.EOF {
+		return nil, signingFingerPrints, err
+	}
+	return BytesToTextArray(signed), signingFingerPrints, nil
+}
+
 // TextArrayToBytes converts a slice of strings to a byte slice separated by newlines.
 func TextArrayToBytes(text []string) []byte {
 	var result []byte
@@ -0,0 +1,112 @@
+/* signers.go - keys of the signers of verified data for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// gpgme only reports the fingerprint of the signing (sub)key, so the
+// key is looked up in the keyring to tell who made a signature.
+
+package gpggohigh
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// ResolvedSignature is a verified signature with the key which made it.
+type ResolvedSignature struct {
+	gpgme.Signature
+	// Key is the signing key, nil if it is not in the keyring.
+	Key *KeyType
+	// UserID is the primary user ID of the key, e.g.
+	// "Alice <alice@example.com>", empty if the key is unknown.
+	UserID string
+	// UserIDValidity is the validity of the primary user ID.
+	UserIDValidity gpgme.Validity
+	// Expires is the expiration time of the key, zero if the key does
+	// not expire or is unknown.
+	Expires time.Time
+}
+
+// Signer returns the primary user ID of the signing key, or the
+// fingerprint of the signature if the key is unknown, e.g. for
+// displaying "Signed by ...".
+func (s ResolvedSignature) Signer() string {
+	if s.UserID != "" {
+		return s.UserID
+	}
+	return s.Fingerprint
+}
+
+// ResolveSigners looks up the keys which made the signatures returned by
+// a verification or decryption, so the signers can be shown without
+// looking up the keys separately. Signatures made by keys which are not
+// in the keyring are returned without key.
+func ResolveSigners(signatures []gpgme.Signature) (resolved []ResolvedSignature,
+	err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("ResolveSigners - %w", err)
+	}
+	defer myContext.Release()
+
+	resolved, err = resolveSigners(myContext, signatures)
+	if err != nil {
+		return nil, fmt.Errorf("ResolveSigners - %w", err)
+	}
+	return resolved, nil
+}
+
+// resolveSigners implements ResolveSigners using myContext.
+func resolveSigners(myContext *gpgme.Context, signatures []gpgme.Signature) (
+	resolved []ResolvedSignature, err error) {
+
+	// several signatures may be made by the same key
+	keys := make(map[string]*KeyType)
+	for _, sig := range signatures {
+		r := ResolvedSignature{Signature: sig}
+		key, seen := keys[sig.Fingerprint]
+		if !seen && sig.Fingerprint != "" {
+			found, err := keyList(context.Background(), myContext, sig.Fingerprint)
+			if err != nil {
+				return nil, fmt.Errorf("KeyList failed: %w", err)
+			}
+			if len(found) > 0 {
+				key = &found[0]
+			}
+			keys[sig.Fingerprint] = key
+		}
+		if key != nil {
+			r.Key = key
+			r.Expires = key.Expires
+			// the first user ID is the primary one
+			if len(key.UserIDs) > 0 {
+				r.UserID = key.UserIDs[0].UserID
+				r.UserIDValidity = key.UserIDs[0].Validity
+			}
+		}
+		resolved = append(resolved, r)
+	}
+	return resolved, nil
+}
+
+// EOF
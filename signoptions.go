@@ -0,0 +1,249 @@
+/* signoptions.go - signatures with notations and a policy URL
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// gpgme.go can not add signature notations to a context, so like
+// SignBytesNotations the signing with SignOptions is done by calling gpg.
+
+package gpggohigh
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// SignOptions configures the data attached to a signature by
+// SignBytesOptions and SignFileOptions, e.g. a build ID or a reference
+// to the policy under which the document was signed.
+type SignOptions struct {
+	// Notations are attached as human readable notations, name to value.
+	// The names have the form name@domain, see NotationType.
+	Notations map[string]string
+	// CriticalNotations are the names of the Notations marked critical:
+	// a verifier not understanding them has to treat the signature as
+	// invalid. gpg does so unless the verifier configured the names with
+	// the gpg option --known-notation.
+	CriticalNotations []string
+	// PolicyURL is attached as signature policy URL, like the gpg option
+	// --sig-policy-url.
+	PolicyURL string
+	// CriticalPolicyURL marks the policy URL critical.
+	CriticalPolicyURL bool
+	// Time is the creation time of the signature, the current time if
+	// zero. gpg runs as if it were that time, like with the option
+	// --faked-system-time, so builds can create reproducible signatures.
+	// The signing key must be valid at that time.
+	Time time.Time
+}
+
+// args returns the gpg arguments attaching the notations and the policy
+// URL of opts to a signature.
+func (opts SignOptions) args() ([]string, error) {
+	names := make([]string, 0, len(opts.Notations))
+	for name := range opts.Notations {
+		names = append(names, name)
+	}
+	// a stable order of the notations in the signature
+	slices.Sort(names)
+
+	var notations []NotationType
+	for _, name := range names {
+		notations = append(notations, NotationType{
+			Name:          name,
+			Value:         opts.Notations[name],
+			Critical:      slices.Contains(opts.CriticalNotations, name),
+			HumanReadable: true,
+		})
+	}
+	for _, name := range opts.CriticalNotations {
+		if _, ok := opts.Notations[name]; !ok {
+			return nil, fmt.Errorf("critical notation %s not given", name)
+		}
+	}
+	args, err := notationArgs(notations)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.PolicyURL != "" {
+		policyURL := opts.PolicyURL
+		if opts.CriticalPolicyURL {
+			policyURL = "!" + policyURL
+		}
+		args = append(args, "--sig-policy-url", policyURL)
+	}
+	if !opts.Time.IsZero() {
+		args = append(args, fakedTimeArgs(opts.Time)...)
+	}
+	return args, nil
+}
+
+// SignBytesOptions signs a memory buffer like SignBytesMode and attaches
+// the notations and the policy URL of opts to the signature.
+func SignBytesOptions(plainText []byte, signWith string, mode gpgme.SigMode,
+	armored bool, opts SignOptions) (cipherText []byte, err error) {
+
+	cipherText, err = signBytesOptions(gpgCommand{}, plainText, signWith, mode,
+		armored, opts)
+	if err != nil {
+		return nil, fmt.Errorf("SignBytesOptions - %w", err)
+	}
+	return cipherText, nil
+}
+
+// signBytesOptions implements SignBytesOptions running the command cmd.
+func signBytesOptions(cmd gpgCommand, plainText []byte, signWith string,
+	mode gpgme.SigMode, armored bool, opts SignOptions) (cipherText []byte, err error) {
+
+	extra, err := opts.args()
+	if err != nil {
+		return nil, err
+	}
+	return signBytesArgs(cmd, "SignBytesOptions", plainText, signWith, mode, armored,
+		extra)
+}
+
+// signBytesArgs signs plainText running the command cmd with the extra
+// arguments attaching data to the signature. fn names the operation in
+// the audit.
+func signBytesArgs(cmd gpgCommand, fn string, plainText []byte, signWith string,
+	mode gpgme.SigMode, armored bool, extra []string) (cipherText []byte, err error) {
+
+	var status []gpgStatus
+	defer func() {
+		audit(AuditSign, fn, err, func(event *AuditEvent) {
+			event.Signers = createdSignatureFingerprints(status)
+			event.InputSHA256 = hashBytes(plainText)
+			if err == nil {
+				event.OutputSHA256 = hashBytes(cipherText)
+			}
+		})
+	}()
+
+	args, err := signOptionsArgs(signWith, mode, armored, extra)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	cmd.args = args
+	cmd.stdin = bytes.NewReader(plainText)
+	cmd.stdout = &out
+	status, err = cmd.run()
+	if err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// SignFileOptions signs the file sourceFilename with the key signWith
+// and the notations and the policy URL of opts, and writes the signed
+// data, or the signature for gpgme.SigModeDetach, to destinationFilename.
+// If destinationFilename is empty, the extension `.gpg`, `.sig` for a
+// detached signature, or `.asc` if armored or clearsigned is added, like
+// gpg does. An existing destination is overwritten, when the signature
+// is complete.
+func SignFileOptions(sourceFilename, destinationFilename, signWith string,
+	mode gpgme.SigMode, armored bool, opts SignOptions) (destination string, err error) {
+
+	destination, err = signFileOptions(gpgCommand{}, sourceFilename,
+		destinationFilename, signWith, mode, armored, opts)
+	if err != nil {
+		return "", fmt.Errorf("SignFileOptions - %w", err)
+	}
+	return destination, nil
+}
+
+// signFileOptions implements SignFileOptions running the command cmd.
+func signFileOptions(cmd gpgCommand, sourceFilename, destinationFilename,
+	signWith string, mode gpgme.SigMode, armored bool, opts SignOptions) (
+	destination string, err error) {
+
+	var status []gpgStatus
+	defer func() {
+		audit(AuditSign, "SignFileOptions", err, func(event *AuditEvent) {
+			event.Signers = createdSignatureFingerprints(status)
+			event.Input, event.InputSHA256 = sourceFilename, hashFile(sourceFilename)
+			if err == nil {
+				event.Output, event.OutputSHA256 = destination, hashFile(destination)
+			}
+		})
+	}()
+
+	extra, err := opts.args()
+	if err != nil {
+		return "", err
+	}
+	args, err := signOptionsArgs(signWith, mode, armored, extra)
+	if err != nil {
+		return "", err
+	}
+
+	destination = destinationFilename
+	if destination == "" {
+		switch {
+		case armored, mode == gpgme.SigModeClear:
+			destination = sourceFilename + ".asc"
+		case mode == gpgme.SigModeDetach:
+			destination = sourceFilename + ".sig"
+		default:
+			destination = sourceFilename + ".gpg"
+		}
+	}
+
+	outFilename, err := tempName(destination)
+	if err != nil {
+		return "", err
+	}
+	cmd.args = append(args, "--yes", "--output", outFilename, "--", sourceFilename)
+	if status, err = cmd.run(); err != nil {
+		_ = os.Remove(outFilename)
+		return "", err
+	}
+	if err = commitFile(outFilename, destination); err != nil {
+		return "", err
+	}
+	return destination, nil
+}
+
+// signOptionsArgs returns the gpg arguments for a signature with the
+// extra arguments, e.g. those of SignOptions.
+func signOptionsArgs(signWith string, mode gpgme.SigMode, armored bool,
+	extra []string) ([]string, error) {
+
+	op, err := signOperation(mode)
+	if err != nil {
+		return nil, err
+	}
+	if signWith == "" {
+		return nil, fmt.Errorf("no signing key given")
+	}
+	args := []string{op, "--local-user", signWith}
+	if armored {
+		args = append(args, "--armor")
+	}
+	return append(args, extra...), nil
+}
+
+// EOF
@@ -0,0 +1,108 @@
+/* sigsum.go - readable signature summaries for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import "github.com/kulbartsch/gpgme"
+
+// sigSumNames are the names of the bits of a signature summary in the
+// order of gpgme.
+var sigSumNames = []struct {
+	bit  gpgme.SigSum
+	name string
+}{
+	{gpgme.SigSumValid, "valid"},
+	{gpgme.SigSumGreen, "green"},
+	{gpgme.SigSumRed, "red"},
+	{gpgme.SigSumKeyRevoked, "key-revoked"},
+	{gpgme.SigSumKeyExpired, "key-expired"},
+	{gpgme.SigSumSigExpired, "sig-expired"},
+	{gpgme.SigSumKeyMissing, "key-missing"},
+	{gpgme.SigSumCRLMissing, "crl-missing"},
+	{gpgme.SigSumCRLTooOld, "crl-too-old"},
+	{gpgme.SigSumBadPolicy, "bad-policy"},
+	{gpgme.SigSumSysError, "sys-error"},
+}
+
+// SigSumToStrings returns the names of the bits set in the signature
+// summary sum, e.g. ["valid", "green"]. It is empty, never nil, if no
+// bit is set.
+func SigSumToStrings(sum gpgme.SigSum) (names []string) {
+	names = []string{}
+	for _, s := range sigSumNames {
+		if sum&s.bit != 0 {
+			names = append(names, s.name)
+		}
+	}
+	return names
+}
+
+// SignatureClassification is the overall state of a verified signature,
+// to present verification results consistently.
+type SignatureClassification int
+
+const (
+	SignatureValid      SignatureClassification = iota + 1 // good signature by a valid key
+	SignatureUnknownKey                                    // good signature, but the validity of the key is not known
+	SignatureBad                                           // the signature does not match the data
+	SignatureExpired                                       // the signature or the key is expired
+	SignatureRevoked                                       // the key is revoked
+	SignatureMissingKey                                    // the key is not available, the signature was not checked
+	SignatureError                                         // the signature could not be checked
+)
+
+// SignatureClassificationString maps a SignatureClassification to a
+// readable text.
+var SignatureClassificationString = map[SignatureClassification]string{
+	SignatureValid:      "valid",
+	SignatureUnknownKey: "valid, unknown key",
+	SignatureBad:        "bad",
+	SignatureExpired:    "expired",
+	SignatureRevoked:    "revoked",
+	SignatureMissingKey: "missing key",
+	SignatureError:      "error",
+}
+
+// String returns a readable text of the classification.
+func (c SignatureClassification) String() string {
+	return SignatureClassificationString[c]
+}
+
+// ClassifySignature returns the overall state of the signature sig.
+func ClassifySignature(sig gpgme.Signature) SignatureClassification {
+	sum := sig.Summary
+	switch {
+	case sum&gpgme.SigSumValid != 0:
+		return SignatureValid
+	case sum&gpgme.SigSumKeyRevoked != 0:
+		return SignatureRevoked
+	case sum&(gpgme.SigSumSigExpired|gpgme.SigSumKeyExpired) != 0:
+		return SignatureExpired
+	case sum&gpgme.SigSumKeyMissing != 0:
+		return SignatureMissingKey
+	case sum&gpgme.SigSumRed != 0:
+		return SignatureBad
+	case sum&gpgme.SigSumSysError != 0, sig.Status != nil:
+		return SignatureError
+	}
+	return SignatureUnknownKey
+}
+
+// EOF
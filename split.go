@@ -0,0 +1,304 @@
+/* split.go - encrypted output split into parts of limited size
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// The parts are plain pieces of the encrypted data like produced by
+// `split`, numbered from 001: `cat file.gpg.*` reassembles them.
+
+package gpggohigh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// partSuffix is the suffix of the first part.
+const partSuffix = ".001"
+
+// PartName returns the name of the part number n, counting from 1, of
+// the split encrypted file destination, e.g. "file.gpg.001".
+func PartName(destination string, n int) string {
+	return fmt.Sprintf("%s.%03d", destination, n)
+}
+
+// EncryptFileSplit encrypts a file like EncryptFileOptions, but writes
+// the encrypted data to numbered parts of at most partSize bytes, for
+// transports with a limit of the file size. The parts are named like
+// PartName, e.g. `file.gpg.001`, `file.gpg.002`. Following parts left
+// over by a former, longer encryption to the same destination are
+// removed, so FindParts finds only the new ones. The parts are written
+// only after the encryption succeeded, and former parts are restored,
+// if not all of them could be replaced.
+func EncryptFileSplit(sourceFilename, destinationFilename string,
+	recipients []string, sign, armored bool, partSize int64, opts EncryptOptions) (
+	parts []string, err error) {
+
+	parts, err = encryptFileSplit(gpgCommand{}, sourceFilename, destinationFilename,
+		recipients, sign, armored, partSize, opts)
+	if err != nil {
+		return nil, fmt.Errorf("EncryptFileSplit - %w", err)
+	}
+	return parts, nil
+}
+
+// encryptFileSplit implements EncryptFileSplit running the command cmd.
+func encryptFileSplit(cmd gpgCommand, sourceFilename, destinationFilename string,
+	recipients []string, sign, armored bool, partSize int64, opts EncryptOptions) (
+	parts []string, err error) {
+
+	if partSize <= 0 {
+		return nil, fmt.Errorf("invalid part size %d", partSize)
+	}
+	destination := optionsDestination(sourceFilename, destinationFilename, armored)
+	defer func() {
+		audit(AuditEncrypt, "EncryptFileSplit", err, func(event *AuditEvent) {
+			event.Recipients = recipientFingerprints(cmd.homeDir, recipients)
+			event.Input, event.InputSHA256 = sourceFilename, hashFile(sourceFilename)
+			if err == nil && len(parts) > 0 {
+				// the hash of the reassembled encrypted data
+				event.Output, event.OutputSHA256 = parts[0], hashFile(parts[0], parts[1:]...)
+			}
+		})
+	}()
+
+	args, err := encryptFileArgs(recipients, sign, armored, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &partWriter{destination: destination, size: partSize}
+	cmd.args = append(args, "--output", "-", "--", sourceFilename)
+	cmd.stdout = io.MultiWriter(append([]io.Writer{w}, opts.Tee...)...)
+	_, err = cmd.run()
+	if closeErr := w.close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		w.remove()
+		return nil, err
+	}
+
+	// before the rename, so a failure keeps the former parts
+	if opts.PreserveMetadata {
+		for _, temp := range w.temps {
+			if err := copyMetadata(sourceFilename, temp); err != nil {
+				w.remove()
+				return nil, fmt.Errorf("copying the metadata failed: %w", err)
+			}
+		}
+	}
+	parts, err = commitParts(w.temps, destination)
+	if err != nil {
+		return nil, err
+	}
+	// parts of a former encryption would be taken for a continuation
+	for n := len(parts) + 1; ; n++ {
+		if err := os.Remove(PartName(destination, n)); err != nil {
+			break
+		}
+	}
+	return parts, removeSource(sourceFilename, opts.RemoveSource)
+}
+
+// commitParts renames the complete temporary files temps to the parts of
+// destination. Existing parts are kept as backups until all parts are
+// renamed, so a failure restores them and never leaves a mix of former
+// and new parts, which could not be decrypted.
+func commitParts(temps []string, destination string) (parts []string, err error) {
+	var committed, backups []string // backups[i] is "" if there was no part i
+	defer func() {
+		if err == nil {
+			for _, backup := range backups {
+				if backup != "" {
+					_ = os.Remove(backup)
+				}
+			}
+			return
+		}
+		for i, part := range committed {
+			_ = os.Remove(part)
+			if backups[i] != "" {
+				_ = os.Rename(backups[i], part)
+			}
+		}
+		for _, temp := range temps[len(committed):] {
+			_ = os.Remove(temp)
+		}
+	}()
+
+	for i, temp := range temps {
+		part := PartName(destination, i+1)
+		var backup string
+		if _, statErr := os.Lstat(part); statErr == nil {
+			backup, err = tempName(part)
+			if err == nil {
+				err = os.Rename(part, backup)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("keeping %s failed: %w", part, err)
+			}
+		}
+		if err = commitFile(temp, part); err != nil {
+			if backup != "" {
+				_ = os.Rename(backup, part)
+			}
+			return nil, fmt.Errorf("writing %s failed: %w", part, err)
+		}
+		committed = append(committed, part)
+		backups = append(backups, backup)
+	}
+	return committed, nil
+}
+
+// partWriter writes to temporary files of at most size bytes for the
+// parts of destination.
+type partWriter struct {
+	destination string
+	size        int64
+	temps       []string // the names of the temporary files
+	current     *os.File // the part being written
+	written     int64    // to the current part
+}
+
+// Write implements io.Writer, starting a new part when the current one
+// is full.
+func (w *partWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		if w.current == nil || w.written == w.size {
+			if err := w.close(); err != nil {
+				return n, err
+			}
+			fh, err := createTemp(w.destination)
+			if err != nil {
+				return n, err
+			}
+			w.current, w.written = fh, 0
+			w.temps = append(w.temps, fh.Name())
+		}
+		chunk := p
+		if int64(len(chunk)) > w.size-w.written {
+			chunk = chunk[:w.size-w.written]
+		}
+		m, err := w.current.Write(chunk)
+		n += m
+		w.written += int64(m)
+		if err != nil {
+			return n, err
+		}
+		p = p[m:]
+	}
+	return n, nil
+}
+
+// close closes the current part.
+func (w *partWriter) close() error {
+	if w.current == nil {
+		return nil
+	}
+	err := w.current.Close()
+	w.current = nil
+	return err
+}
+
+// remove removes the temporary files not yet renamed to a part.
+func (w *partWriter) remove() {
+	_ = w.close()
+	for _, temp := range w.temps {
+		_ = os.Remove(temp)
+	}
+}
+
+// FindParts returns the parts of a split encrypted file, given the name
+// of the first part like "file.gpg.001", in their order.
+func FindParts(firstPart string) (parts []string, err error) {
+	destination, found := strings.CutSuffix(firstPart, partSuffix)
+	if !found {
+		return nil, fmt.Errorf("FindParts - %s is not a first part", firstPart)
+	}
+	for n := 1; ; n++ {
+		part := PartName(destination, n)
+		_, err := os.Stat(part)
+		if errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("FindParts - Stat failed: %w", err)
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("FindParts - %w", os.ErrNotExist)
+	}
+	return parts, nil
+}
+
+// DecryptParts decrypts the encrypted file split into parts, e.g. by
+// EncryptFileSplit, like DecryptFileOptions. The parts are read in the
+// given order. If clearFilename is empty, the destination is derived
+// from the first part without its number, e.g. `file` for
+// `file.gpg.001`.
+func DecryptParts(parts []string, clearFilename string, opts DecryptOptions) (
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		err = fmt.Errorf("DecryptParts - %w", err)
+		return
+	}
+	defer myContext.Release()
+
+	return decryptParts(context.Background(), myContext, gpgCommand{}, parts,
+		clearFilename, opts)
+}
+
+// decryptParts implements DecryptParts using myContext, or running the
+// command cmd for RestoreTimestamp.
+func decryptParts(ctx context.Context, myContext *gpgme.Context, cmd gpgCommand, parts []string,
+	clearFilename string, opts DecryptOptions) (
+	decryptionResult gpgme.DecryptResultType, filename string,
+	signatures []gpgme.Signature, warning string, err error) {
+
+	if len(parts) == 0 {
+		err = fmt.Errorf("DecryptParts - no parts given")
+		return
+	}
+	readers := make([]io.Reader, 0, len(parts))
+	for _, part := range parts {
+		fh, openErr := os.Open(part)
+		if openErr != nil {
+			err = fmt.Errorf("DecryptParts - Open (in) failed: %w", openErr)
+			return
+		}
+		defer fh.Close()
+		readers = append(readers, fh)
+	}
+
+	cypherFilename := strings.TrimSuffix(parts[0], partSuffix)
+	return decryptReaderOptions(ctx, myContext, cmd, io.MultiReader(readers...), parts,
+		cypherFilename, clearFilename, opts)
+}
+
+// EOF
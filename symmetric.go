@@ -0,0 +1,183 @@
+/* symmetric.go - passphrase based encryption for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// Symmetric encryption is done by calling gpg directly, because
+// gpgme.go always passes a recipient list to gpgme, which makes
+// gpgme refuse a symmetric only encryption.
+// Decryption works with gpgme and loopback pinentry.
+
+package gpggohigh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// EncryptBytesSymmetric encrypts a memory buffer with a passphrase
+// like `gpg --symmetric` does.
+//
+//   - plainText: the data to be encrypted
+//   - passphrase: provides the passphrase, e.g. StaticPassphrase("secret")
+//   - armored: if true, the output will be ASCII armored
+//   - cipherText: the encrypted data
+//   - err: an error if the encryption fails
+func EncryptBytesSymmetric(plainText []byte, passphrase PassphraseFunc,
+	armored bool) (cipherText []byte, err error) {
+
+	defer func() {
+		audit(AuditEncrypt, "EncryptBytesSymmetric", err, func(event *AuditEvent) {
+			event.InputSHA256 = hashBytes(plainText)
+			if err == nil {
+				event.OutputSHA256 = hashBytes(cipherText)
+			}
+		})
+	}()
+
+	if passphrase == nil {
+		return nil, fmt.Errorf("EncryptBytesSymmetric - no passphrase given")
+	}
+	p, err := passphrase("", false)
+	if err != nil {
+		return nil, fmt.Errorf("EncryptBytesSymmetric - passphrase failed: %w", err)
+	}
+
+	var out bytes.Buffer
+	cmd := gpgCommand{
+		args:          []string{"--symmetric"},
+		stdin:         bytes.NewReader(plainText),
+		stdout:        &out,
+		passphrase:    p,
+		hasPassphrase: true,
+	}
+	if armored {
+		cmd.args = append(cmd.args, "--armor")
+	}
+	_, err = cmd.run()
+	if err != nil {
+		return nil, fmt.Errorf("EncryptBytesSymmetric - %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// EncryptFileSymmetric encrypts a file with a passphrase like
+// `gpg --symmetric` does.
+// sourceFilename is the file to encrypt, it will not be deleted.
+// destinationFilename is the file to save the encrypted file.
+// If the destinationFilename is empty, the sourceFilename is used
+// with an added `.gpg` extension.
+// passphrase provides the passphrase, e.g. StaticPassphrase("secret").
+func EncryptFileSymmetric(sourceFilename, destinationFilename string,
+	passphrase PassphraseFunc) (err error) {
+
+	destination := destinationFilename
+	if destination == "" {
+		destination = sourceFilename + ".gpg"
+	}
+	defer func() {
+		audit(AuditEncrypt, "EncryptFileSymmetric", err, func(event *AuditEvent) {
+			event.Input, event.InputSHA256 = sourceFilename, hashFile(sourceFilename)
+			if err == nil {
+				event.Output, event.OutputSHA256 = destination, hashFile(destination)
+			}
+		})
+	}()
+
+	if passphrase == nil {
+		return fmt.Errorf("EncryptFileSymmetric - no passphrase given")
+	}
+	p, err := passphrase("", false)
+	if err != nil {
+		return fmt.Errorf("EncryptFileSymmetric - passphrase failed: %w", err)
+	}
+
+	outFilename, err := tempName(destination)
+	if err != nil {
+		return fmt.Errorf("EncryptFileSymmetric - %w", err)
+	}
+	cmd := gpgCommand{
+		args: []string{"--symmetric", "--output", outFilename,
+			"--", sourceFilename},
+		passphrase:    p,
+		hasPassphrase: true,
+	}
+	_, err = cmd.run()
+	if err != nil {
+		_ = os.Remove(outFilename)
+		return fmt.Errorf("EncryptFileSymmetric - %w", err)
+	}
+
+	err = commitFile(outFilename, destination)
+	if err != nil {
+		return fmt.Errorf("EncryptFileSymmetric - writing %s failed: %w", destination, err)
+	}
+	return nil
+}
+
+// DecryptBytesSymmetric decrypts a memory buffer which was encrypted
+// with a passphrase. The passphrase is requested from passphrase
+// instead of pinentry.
+// The return values are the same as for DecryptBytes.
+func DecryptBytesSymmetric(cipherText []byte, passphrase PassphraseFunc) (
+	plainText []byte, decryptionResult gpgme.DecryptResultType,
+	filename string, signatures []gpgme.Signature, warning string, err error) {
+
+	if passphrase == nil {
+		err = fmt.Errorf("DecryptBytesSymmetric - no passphrase given")
+		return
+	}
+
+	myContext, err := newContext(SessionOptions{Passphrase: passphrase})
+	if err != nil {
+		err = fmt.Errorf("DecryptBytes - %w", err)
+		return
+	}
+	defer myContext.Release()
+
+	return decryptBytes(context.Background(), myContext, cipherText)
+}
+
+// DecryptFileSymmetric decrypts a file which was encrypted with a
+// passphrase. The passphrase is requested from passphrase instead of
+// pinentry.
+// The file names and return values are the same as for DecryptFile.
+func DecryptFileSymmetric(cypherFilename, clearFilename string,
+	passphrase PassphraseFunc) (decryptionResult gpgme.DecryptResultType,
+	filename string, signatures []gpgme.Signature, warning string, err error) {
+
+	if passphrase == nil {
+		err = fmt.Errorf("DecryptFileSymmetric - no passphrase given")
+		return
+	}
+
+	myContext, err := newContext(SessionOptions{Passphrase: passphrase})
+	if err != nil {
+		err = fmt.Errorf("DecryptFile - %w", err)
+		return
+	}
+	defer myContext.Release()
+
+	return decryptFile(myContext, cypherFilename, clearFilename)
+}
+
+// EOF
@@ -0,0 +1,132 @@
+/* testsupport.go - test keys and samples for users of gpggohigh
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// Package testsupport provides fixed OpenPGP test keys, isolated GnuPG
+// home directories and golden encrypted and signed samples, so users of
+// gpggohigh can test their integrations without touching real keyrings.
+//
+// The keys have no passphrase and must never be used for anything but
+// tests. They were created on 2025-01-01 and do not expire, so the
+// samples stay verifiable.
+package testsupport
+
+import (
+	"embed"
+	"testing"
+
+	"github.com/gnupg-com/gpggohigh"
+)
+
+//go:embed testdata
+var testdata embed.FS
+
+// mustRead returns the content of a file of testdata.
+func mustRead(name string) []byte {
+	data, err := testdata.ReadFile("testdata/" + name)
+	if err != nil {
+		panic("testsupport: " + err.Error())
+	}
+	return data
+}
+
+// TestKey is a test key with its ASCII armored public and secret key.
+type TestKey struct {
+	UserID        string
+	Email         string
+	Fingerprint   string // of the primary key, which signs and certifies
+	EncryptSubKey string // fingerprint of the subkey for encryption
+	PublicKey     []byte
+	SecretKey     []byte // without passphrase
+}
+
+// The test keys, both ed25519 primary keys with a cv25519 encryption
+// subkey.
+var (
+	Alice = TestKey{
+		UserID:        "Alice Test <alice@example.org>",
+		Email:         "alice@example.org",
+		Fingerprint:   "B5DDE31CF970C0F41F6B9E13FA62705D7381A2A9",
+		EncryptSubKey: "1842BCB5A9CD2E6CA59033664FED432D3C1327F3",
+		PublicKey:     mustRead("alice.pub.asc"),
+		SecretKey:     mustRead("alice.sec.asc"),
+	}
+	Bob = TestKey{
+		UserID:        "Bob Test <bob@example.org>",
+		Email:         "bob@example.org",
+		Fingerprint:   "919C2B7343B8319CD68201BACC698E1B92E86C86",
+		EncryptSubKey: "9D4EEE35F38A8418D3FFEC7BAF7AE8CFBB710D5B",
+		PublicKey:     mustRead("bob.pub.asc"),
+		SecretKey:     mustRead("bob.sec.asc"),
+	}
+)
+
+// Golden samples, all ASCII armored. Their content is Message.
+var (
+	// Message is the plain text of the samples.
+	Message = mustRead("message.txt")
+	// Encrypted is Message encrypted to Alice.
+	Encrypted = mustRead("encrypted.asc")
+	// EncryptedSigned is Message encrypted to Alice and signed by Bob.
+	EncryptedSigned = mustRead("encrypted-signed.asc")
+	// Signed is Message signed by Alice.
+	Signed = mustRead("signed.asc")
+	// ClearSigned is Message clear signed by Alice.
+	ClearSigned = mustRead("clearsigned.asc")
+	// DetachedSignature is a detached signature of Alice over Message.
+	DetachedSignature = mustRead("detached.asc")
+	// Symmetric is Message symmetrically encrypted with SymmetricPassphrase.
+	Symmetric = mustRead("symmetric.asc")
+)
+
+// SymmetricPassphrase is the passphrase of Symmetric.
+const SymmetricPassphrase = "test passphrase"
+
+// NewHome creates an isolated GnuPG home directory with the public keys
+// of publicKeys and the public and secret keys of secretKeys imported
+// and returns a Session bound to it, see gpggohigh.NewEphemeralHome.
+// The caller has to close the session.
+func NewHome(publicKeys, secretKeys []TestKey) (*gpggohigh.Session, error) {
+	var keys [][]byte
+	for _, k := range publicKeys {
+		keys = append(keys, k.PublicKey)
+	}
+	for _, k := range secretKeys {
+		keys = append(keys, k.PublicKey, k.SecretKey)
+	}
+	return gpggohigh.NewEphemeralHome(keys...)
+}
+
+// Home is NewHome for tests: it fails the test, if the home directory
+// can not be created, and closes the session at the end of the test.
+func Home(tb testing.TB, publicKeys, secretKeys []TestKey) *gpggohigh.Session {
+	tb.Helper()
+	session, err := NewHome(publicKeys, secretKeys)
+	if err != nil {
+		tb.Fatalf("testsupport: %v", err)
+	}
+	tb.Cleanup(func() {
+		if err := session.Close(); err != nil {
+			tb.Errorf("testsupport: %v", err)
+		}
+	})
+	return session
+}
+
+// EOF
@@ -0,0 +1,252 @@
+/* timestamp.go - signing and verifying at another time than now
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// gpgme has no option for the time of an operation, so gpg is called
+// with --faked-system-time and the verification result is built from
+// its status lines like gpgme does.
+
+package gpggohigh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// The status of signatures verified by VerifyBytesAt, with the texts of
+// the errors gpgme reports.
+var (
+	errSigBad        = errors.New("Bad signature")
+	errSigExpired    = errors.New("Signature expired")
+	errSigKeyExpired = errors.New("Key expired")
+	errSigRevoked    = errors.New("Certificate revoked")
+	errSigNoPubkey   = errors.New("No public key")
+	errSigGeneral    = errors.New("General error")
+)
+
+// fakedTimeArgs returns the gpg arguments to run as if it were the time
+// t. The exclamation mark stops the clock at t.
+func fakedTimeArgs(t time.Time) []string {
+	return []string{"--faked-system-time", strconv.FormatInt(t.Unix(), 10) + "!"}
+}
+
+// VerifyBytesAt verifies the signed data like VerifyBytes, but evaluates
+// the signatures and the keys at the time at instead of now, e.g. at the
+// time archived material was signed: a key which expired or a signature
+// which expired since then is still reported as valid. A key revoked
+// later is reported as revoked nevertheless, because gpg does not know
+// the time of the revocation.
+func VerifyBytesAt(cipherText []byte, at time.Time) (plainText []byte,
+	signatures []gpgme.Signature, err error) {
+
+	plainText, signatures, err = verifyBytesAt(gpgCommand{}, cipherText, at)
+	if err != nil {
+		return nil, nil, fmt.Errorf("VerifyBytesAt - %w", err)
+	}
+	return plainText, signatures, nil
+}
+
+// verifyBytesAt implements VerifyBytesAt running the command cmd.
+func verifyBytesAt(cmd gpgCommand, cipherText []byte, at time.Time) (
+	plainText []byte, signatures []gpgme.Signature, err error) {
+
+	defer func() {
+		audit(AuditVerify, "VerifyBytesAt", err, func(event *AuditEvent) {
+			event.Signers = signatureFingerprints(signatures)
+			event.InputSHA256 = hashBytes(cipherText)
+		})
+	}()
+
+	var out bytes.Buffer
+	cmd.args = append([]string{"--verify", "--output", "-"}, fakedTimeArgs(at)...)
+	cmd.args = append(cmd.args, "-")
+	cmd.stdin = bytes.NewReader(cipherText)
+	cmd.stdout = &out
+	signatures, err = runVerifyAt(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out.Bytes(), signatures, nil
+}
+
+// VerifyFileDetachedAt verifies the detached signature in the file
+// signatureFilename for the file dataFilename like VerifyFileDetached,
+// but at the time at like VerifyBytesAt.
+func VerifyFileDetachedAt(signatureFilename, dataFilename string, at time.Time) (
+	signatures []gpgme.Signature, err error) {
+
+	signatures, err = verifyFileDetachedAt(gpgCommand{}, signatureFilename,
+		dataFilename, at)
+	if err != nil {
+		return nil, fmt.Errorf("VerifyFileDetachedAt - %w", err)
+	}
+	return signatures, nil
+}
+
+// verifyFileDetachedAt implements VerifyFileDetachedAt running the
+// command cmd.
+func verifyFileDetachedAt(cmd gpgCommand, signatureFilename, dataFilename string,
+	at time.Time) (signatures []gpgme.Signature, err error) {
+
+	defer func() {
+		audit(AuditVerify, "VerifyFileDetachedAt", err, func(event *AuditEvent) {
+			event.Signers = signatureFingerprints(signatures)
+			event.Input, event.InputSHA256 = dataFilename, hashFile(dataFilename)
+		})
+	}()
+
+	cmd.args = append([]string{"--verify"}, fakedTimeArgs(at)...)
+	cmd.args = append(cmd.args, "--", signatureFilename, dataFilename)
+	return runVerifyAt(cmd)
+}
+
+// runVerifyAt runs the verification command cmd and returns the
+// signatures. Like with gpgme, a bad signature is not an error.
+func runVerifyAt(cmd gpgCommand) ([]gpgme.Signature, error) {
+	status, err := cmd.run()
+	signatures := signaturesFromStatus(status)
+	if len(signatures) == 0 {
+		if err == nil {
+			err = fmt.Errorf("no signature found")
+		}
+		return nil, err
+	}
+	return signatures, nil
+}
+
+// signaturesFromStatus builds the verification result from the status
+// lines of gpg. Each signature starts with NEWSIG.
+func signaturesFromStatus(status []gpgStatus) (signatures []gpgme.Signature) {
+	var sig *gpgme.Signature
+	for _, s := range status {
+		if s.Keyword == "NEWSIG" {
+			if sig != nil {
+				signatures = append(signatures, finishSignature(*sig))
+			}
+			sig = &gpgme.Signature{}
+			continue
+		}
+		if sig == nil {
+			continue
+		}
+		switch s.Keyword {
+		case "GOODSIG":
+		case "BADSIG":
+			sig.Status = errSigBad
+		case "EXPSIG":
+			sig.Status = errSigExpired
+			sig.Summary |= gpgme.SigSumSigExpired
+		case "EXPKEYSIG":
+			sig.Status = errSigKeyExpired
+			sig.Summary |= gpgme.SigSumKeyExpired
+		case "REVKEYSIG":
+			sig.Status = errSigRevoked
+			sig.Summary |= gpgme.SigSumKeyRevoked
+		case "ERRSIG":
+			// <keyid> <pkalgo> <hashalgo> <class> <time> <rc> [<fpr>]
+			if len(s.Args) > 5 {
+				sig.Fingerprint = s.Args[0]
+				if len(s.Args) > 6 && s.Args[6] != "-" {
+					sig.Fingerprint = s.Args[6]
+				}
+				pubkeyAlgo, _ := strconv.Atoi(s.Args[1])
+				hashAlgo, _ := strconv.Atoi(s.Args[2])
+				sig.PubkeyAlgo = gpgme.PubkeyAlgo(pubkeyAlgo)
+				sig.HashAlgo = gpgme.HashAlgo(hashAlgo)
+				sig.Timestamp = statusTime(s.Args[4])
+				sig.Status = errSigGeneral
+				if s.Args[5] == strconv.Itoa(int(errCodeNoPubkey)) {
+					sig.Status = errSigNoPubkey
+					sig.Summary |= gpgme.SigSumKeyMissing
+				}
+			}
+		case "VALIDSIG":
+			// <fpr> <date> <timestamp> <expires> <version> <reserved>
+			// <pkalgo> <hashalgo> <class> [<primary fpr>]
+			if len(s.Args) > 7 {
+				sig.Fingerprint = s.Args[0]
+				sig.Timestamp = statusTime(s.Args[2])
+				sig.ExpTimestamp = statusTime(s.Args[3])
+				pubkeyAlgo, _ := strconv.Atoi(s.Args[6])
+				hashAlgo, _ := strconv.Atoi(s.Args[7])
+				sig.PubkeyAlgo = gpgme.PubkeyAlgo(pubkeyAlgo)
+				sig.HashAlgo = gpgme.HashAlgo(hashAlgo)
+			}
+		case "TRUST_UNDEFINED":
+			sig.Validity = gpgme.ValidityUndefined
+		case "TRUST_NEVER":
+			sig.Validity = gpgme.ValidityNever
+		case "TRUST_MARGINAL":
+			sig.Validity = gpgme.ValidityMarginal
+		case "TRUST_FULLY":
+			sig.Validity = gpgme.ValidityFull
+		case "TRUST_ULTIMATE":
+			sig.Validity = gpgme.ValidityUltimate
+		}
+	}
+	if sig != nil {
+		signatures = append(signatures, finishSignature(*sig))
+	}
+	return signatures
+}
+
+// finishSignature computes the summary of sig like gpgme.
+func finishSignature(sig gpgme.Signature) gpgme.Signature {
+	switch sig.Validity {
+	case gpgme.ValidityFull, gpgme.ValidityUltimate:
+		if sig.Status == nil || errors.Is(sig.Status, errSigExpired) ||
+			errors.Is(sig.Status, errSigKeyExpired) {
+			sig.Summary |= gpgme.SigSumGreen
+		}
+	case gpgme.ValidityNever:
+		sig.Summary |= gpgme.SigSumRed
+	}
+	if errors.Is(sig.Status, errSigBad) {
+		sig.Summary |= gpgme.SigSumRed
+	}
+	if errors.Is(sig.Status, errSigGeneral) {
+		sig.Summary |= gpgme.SigSumSysError
+	}
+	// valid is only green without any problem
+	if sig.Summary == gpgme.SigSumGreen {
+		sig.Summary |= gpgme.SigSumValid
+	}
+	return sig
+}
+
+// statusTime converts a time of a status line, seconds since the epoch
+// or ISO 8601 like 20250101T120000, zero for 0 or an empty field.
+func statusTime(field string) time.Time {
+	if strings.Contains(field, "T") {
+		t, err := time.Parse("20060102T150405", field)
+		if err != nil {
+			return time.Time{}
+		}
+		return t
+	}
+	return colonTime(field)
+}
+
+// EOF
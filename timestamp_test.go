@@ -0,0 +1,140 @@
+/* timestamp_test.go - tests of the signature status parsing
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+func TestSignaturesFromStatus(t *testing.T) {
+	const fpr = "4AAE7570D5062F3CC8DCCB9E22340A7B19813D3D"
+	validSig := "VALIDSIG " + fpr + " 2025-01-01 1735689600 0 4 0 22 10 00 " + fpr
+	created := time.Unix(1735689600, 0)
+
+	tests := []struct {
+		name   string
+		status []gpgStatus
+		want   gpgme.Signature
+	}{
+		{"good and fully trusted",
+			statusLines("NEWSIG", "GOODSIG 22340A7B19813D3D Alice", validSig,
+				"TRUST_FULLY 0 pgp"),
+			gpgme.Signature{Fingerprint: fpr, Summary: gpgme.SigSumValid | gpgme.SigSumGreen,
+				Validity: gpgme.ValidityFull, Timestamp: created,
+				PubkeyAlgo: gpgme.PubkeyAlgo(22), HashAlgo: gpgme.HashAlgo(10)}},
+		{"good and unknown trust",
+			statusLines("NEWSIG", "GOODSIG 22340A7B19813D3D Alice", validSig,
+				"TRUST_UNDEFINED 0 pgp"),
+			gpgme.Signature{Fingerprint: fpr, Validity: gpgme.ValidityUndefined,
+				Timestamp: created, PubkeyAlgo: gpgme.PubkeyAlgo(22),
+				HashAlgo: gpgme.HashAlgo(10)}},
+		{"good and never trusted",
+			statusLines("NEWSIG", "GOODSIG 22340A7B19813D3D Alice", validSig,
+				"TRUST_NEVER 0 pgp"),
+			gpgme.Signature{Fingerprint: fpr, Summary: gpgme.SigSumRed,
+				Validity: gpgme.ValidityNever, Timestamp: created,
+				PubkeyAlgo: gpgme.PubkeyAlgo(22), HashAlgo: gpgme.HashAlgo(10)}},
+		{"bad",
+			statusLines("NEWSIG", "BADSIG 22340A7B19813D3D Alice"),
+			gpgme.Signature{Status: errSigBad, Summary: gpgme.SigSumRed}},
+		{"expired key",
+			statusLines("NEWSIG", "EXPKEYSIG 22340A7B19813D3D Alice", validSig,
+				"TRUST_ULTIMATE 0 pgp"),
+			gpgme.Signature{Fingerprint: fpr, Status: errSigKeyExpired,
+				Summary:  gpgme.SigSumKeyExpired | gpgme.SigSumGreen,
+				Validity: gpgme.ValidityUltimate, Timestamp: created,
+				PubkeyAlgo: gpgme.PubkeyAlgo(22), HashAlgo: gpgme.HashAlgo(10)}},
+		{"revoked key",
+			statusLines("NEWSIG", "REVKEYSIG 22340A7B19813D3D Alice"),
+			gpgme.Signature{Status: errSigRevoked, Summary: gpgme.SigSumKeyRevoked}},
+		{"missing key",
+			statusLines("NEWSIG", "ERRSIG 22340A7B19813D3D 22 10 00 1735689600 9 -"),
+			gpgme.Signature{Fingerprint: "22340A7B19813D3D", Status: errSigNoPubkey,
+				Summary: gpgme.SigSumKeyMissing, Timestamp: created,
+				PubkeyAlgo: gpgme.PubkeyAlgo(22), HashAlgo: gpgme.HashAlgo(10)}},
+		{"missing key with fingerprint",
+			statusLines("NEWSIG", "ERRSIG 22340A7B19813D3D 22 10 00 1735689600 9 "+fpr),
+			gpgme.Signature{Fingerprint: fpr, Status: errSigNoPubkey,
+				Summary: gpgme.SigSumKeyMissing, Timestamp: created,
+				PubkeyAlgo: gpgme.PubkeyAlgo(22), HashAlgo: gpgme.HashAlgo(10)}},
+		{"other error",
+			statusLines("NEWSIG", "ERRSIG 22340A7B19813D3D 22 10 00 20250101T000000 4"),
+			gpgme.Signature{Fingerprint: "22340A7B19813D3D", Status: errSigGeneral,
+				Summary: gpgme.SigSumSysError, Timestamp: created.UTC(),
+				PubkeyAlgo: gpgme.PubkeyAlgo(22), HashAlgo: gpgme.HashAlgo(10)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := signaturesFromStatus(tt.status)
+			if len(got) != 1 {
+				t.Fatalf("got %d signatures, want 1", len(got))
+			}
+			sig := got[0]
+			if sig.Fingerprint != tt.want.Fingerprint || sig.Status != tt.want.Status ||
+				sig.Summary != tt.want.Summary || sig.Validity != tt.want.Validity ||
+				!sig.Timestamp.Equal(tt.want.Timestamp) ||
+				!sig.ExpTimestamp.Equal(tt.want.ExpTimestamp) ||
+				sig.PubkeyAlgo != tt.want.PubkeyAlgo || sig.HashAlgo != tt.want.HashAlgo {
+				t.Errorf("signaturesFromStatus =\n%+v\nwant\n%+v", sig, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignaturesFromStatusCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		status []gpgStatus
+		want   int
+	}{
+		{"none", statusLines("PLAINTEXT 62 0", "GOODSIG 22340A7B19813D3D Alice"), 0},
+		{"two", statusLines("NEWSIG", "GOODSIG 22340A7B19813D3D Alice",
+			"NEWSIG", "BADSIG 22340A7B19813D3D Alice"), 2},
+	}
+	for _, tt := range tests {
+		if got := len(signaturesFromStatus(tt.status)); got != tt.want {
+			t.Errorf("%s: got %d signatures, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStatusTime(t *testing.T) {
+	tests := []struct {
+		field string
+		want  time.Time
+	}{
+		{"1735689600", time.Unix(1735689600, 0)},
+		{"20250101T120000", time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"0", time.Time{}},
+		{"", time.Time{}},
+		{"2025T", time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := statusTime(tt.field); !got.Equal(tt.want) {
+			t.Errorf("statusTime(%q) = %v, want %v", tt.field, got, tt.want)
+		}
+	}
+}
+
+// EOF
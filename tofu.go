@@ -0,0 +1,246 @@
+/* tofu.go - trust on first use information for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// gpgme.go does not provide the TOFU information of gpgme
+// (GPGME_KEYLIST_MODE_WITH_TOFU), so it is read from the colon listing
+// of gpg. The TOFU data only exists, if gpg is used with the trust model
+// tofu or tofu+pgp.
+
+package gpggohigh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// TofuPolicy is the TOFU policy of a binding of a key and a user ID.
+type TofuPolicy string
+
+// TOFU policies as used by gpg --tofu-policy.
+const (
+	TofuPolicyAuto    TofuPolicy = "auto"
+	TofuPolicyGood    TofuPolicy = "good"
+	TofuPolicyUnknown TofuPolicy = "unknown"
+	TofuPolicyBad     TofuPolicy = "bad"
+	TofuPolicyAsk     TofuPolicy = "ask"
+)
+
+// TofuInfoType is the TOFU information of a user ID of a key.
+type TofuInfoType struct {
+	// Validity is 0 for a conflict, 1 for no history, 2 for too little
+	// history, 3 for enough history for basic trust and 4 for a lot of
+	// history.
+	Validity  int
+	Policy    TofuPolicy
+	SignCount int       // number of verified signatures
+	EncrCount int       // number of encryptions to the key
+	SignFirst time.Time // first verified signature, zero if none
+	SignLast  time.Time // last verified signature, zero if none
+	EncrFirst time.Time // first encryption, zero if none
+	EncrLast  time.Time // last encryption, zero if none
+}
+
+// KeyListWithTofu returns the keys matching lookFor like KeyList, with
+// the TOFU information set on the user IDs which have one.
+func KeyListWithTofu(lookFor string) (keys []KeyType, err error) {
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("KeyList -Create context failed - %w", err)
+	}
+	defer myContext.Release()
+
+	return keyListWithTofu(myContext, lookFor)
+}
+
+// keyListWithTofu implements KeyListWithTofu using myContext.
+func keyListWithTofu(myContext *gpgme.Context, lookFor string) (
+	keys []KeyType, err error) {
+
+	keys, err = keyList(context.Background(), myContext, lookFor)
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := tofuInfos(gpgCommand{homeDir: contextHomeDir(myContext)}, lookFor)
+	if err != nil {
+		return nil, fmt.Errorf("KeyList -TOFU info failed - %w", err)
+	}
+	for i := range keys {
+		byUID := infos[keys[i].Fingerprint]
+		for j := range keys[i].UserIDs {
+			if info, ok := byUID[keys[i].UserIDs[j].UserID]; ok {
+				keys[i].UserIDs[j].Tofu = &info
+			}
+		}
+	}
+	return keys, nil
+}
+
+// TofuInfo returns the TOFU information of the key with the given
+// fingerprint by user ID, e.g. for the signer of a verified signature.
+// The fingerprint of a subkey may be given.
+func TofuInfo(fingerprint string) (infos map[string]TofuInfoType, err error) {
+	infos, err = tofuInfo(gpgCommand{}, fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("TofuInfo - %w", err)
+	}
+	return infos, nil
+}
+
+// tofuInfo implements TofuInfo running the command cmd.
+func tofuInfo(cmd gpgCommand, fingerprint string) (map[string]TofuInfoType, error) {
+	if !isFingerprint(fingerprint) {
+		return nil, fmt.Errorf("not a fingerprint: %q", fingerprint)
+	}
+	infos, err := tofuInfos(cmd, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("key %s: %w", fingerprint, ErrKeyNotFound)
+	}
+	for _, byUID := range infos {
+		return byUID, nil
+	}
+	return nil, nil
+}
+
+// tofuInfos lists the keys matching pattern with gpg and returns their
+// TOFU information by primary key fingerprint and user ID.
+func tofuInfos(cmd gpgCommand, pattern string) (
+	map[string]map[string]TofuInfoType, error) {
+
+	var out bytes.Buffer
+	cmd.args = []string{"--trust-model", "tofu+pgp", "--with-colons",
+		"--fixed-list-mode", "--with-tofu-info", "--list-keys"}
+	if pattern != "" {
+		cmd.args = append(cmd.args, "--", pattern)
+	}
+	cmd.stdout = &out
+	if _, err := cmd.run(); err != nil {
+		return nil, err
+	}
+
+	infos := make(map[string]map[string]TofuInfoType)
+	var fpr, uid string
+	expectFpr := false
+	for _, line := range strings.Split(out.String(), "\n") {
+		fields := strings.Split(line, ":")
+		switch fields[0] {
+		case "pub":
+			fpr, uid, expectFpr = "", "", true
+		case "fpr":
+			if expectFpr && len(fields) > 9 {
+				fpr = fields[9]
+				infos[fpr] = make(map[string]TofuInfoType)
+			}
+			expectFpr = false
+		case "uid":
+			uid = ""
+			if len(fields) > 9 {
+				uid = unescapeColon(fields[9])
+			}
+		case "tfs":
+			if fpr != "" && uid != "" && len(fields) > 9 {
+				infos[fpr][uid] = parseTofuStats(fields)
+			}
+		case "sub":
+			uid = ""
+		}
+	}
+	return infos, nil
+}
+
+// parseTofuStats returns the TOFU information of a tfs record.
+func parseTofuStats(fields []string) (info TofuInfoType) {
+	info.Validity, _ = strconv.Atoi(fields[2])
+	info.SignCount, _ = strconv.Atoi(fields[3])
+	info.EncrCount, _ = strconv.Atoi(fields[4])
+	info.Policy = TofuPolicy(fields[5])
+	info.SignFirst = colonTime(fields[6])
+	info.SignLast = colonTime(fields[7])
+	info.EncrFirst = colonTime(fields[8])
+	info.EncrLast = colonTime(fields[9])
+	return info
+}
+
+// colonTime returns the time of a timestamp field of the colon listing,
+// zero if it is empty or 0.
+func colonTime(field string) time.Time {
+	seconds, err := strconv.ParseInt(field, 10, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+// unescapeColon returns a string field of the colon listing with the
+// C-style escapes like \x3a replaced.
+func unescapeColon(field string) string {
+	if !strings.Contains(field, `\x`) {
+		return field
+	}
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+3 < len(field) && field[i+1] == 'x' {
+			if c, err := strconv.ParseUint(field[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(field[i])
+	}
+	return b.String()
+}
+
+// SetTofuPolicy sets the TOFU policy of all bindings of the key with the
+// given fingerprint, like `gpg --tofu-policy`.
+func SetTofuPolicy(fingerprint string, policy TofuPolicy) error {
+	err := setTofuPolicy(gpgCommand{}, fingerprint, policy)
+	if err != nil {
+		return fmt.Errorf("SetTofuPolicy - %w", err)
+	}
+	return nil
+}
+
+// setTofuPolicy implements SetTofuPolicy running the command cmd.
+func setTofuPolicy(cmd gpgCommand, fingerprint string, policy TofuPolicy) error {
+	if !isFingerprint(fingerprint) {
+		return fmt.Errorf("not a fingerprint: %q", fingerprint)
+	}
+	switch policy {
+	case TofuPolicyAuto, TofuPolicyGood, TofuPolicyUnknown, TofuPolicyBad, TofuPolicyAsk:
+	default:
+		return fmt.Errorf("unknown TOFU policy %q", policy)
+	}
+	cmd.args = []string{"--trust-model", "tofu+pgp", "--tofu-policy",
+		string(policy), fingerprint}
+	_, err := cmd.run()
+	return err
+}
+
+// EOF
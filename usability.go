@@ -0,0 +1,413 @@
+/* usability.go - checks whether keys can be used for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// UsabilityProblem describes why a key can not be used for an operation.
+type UsabilityProblem int
+
+const (
+	UsabilityNoKey        UsabilityProblem = iota + 1 // no key matches
+	UsabilityExpired                                  // the key is expired
+	UsabilityRevoked                                  // the key is revoked
+	UsabilityDisabled                                 // the key is disabled
+	UsabilityInvalid                                  // the key is invalid
+	UsabilityNoCapability                             // no valid subkey has the needed capability
+	UsabilityNoSecretKey                              // the secret key is not available
+	UsabilityNotTrusted                               // no user ID is valid, the owner trust is insufficient
+)
+
+// UsabilityProblemString maps a UsabilityProblem to a readable text.
+var UsabilityProblemString = map[UsabilityProblem]string{
+	UsabilityNoKey:        "no key found",
+	UsabilityExpired:      "expired",
+	UsabilityRevoked:      "revoked",
+	UsabilityDisabled:     "disabled",
+	UsabilityInvalid:      "invalid",
+	UsabilityNoCapability: "missing capability",
+	UsabilityNoSecretKey:  "no secret key",
+	UsabilityNotTrusted:   "not trusted",
+}
+
+// KeyUsabilityProblem is a reason why a key matching the pattern given
+// to CanEncryptTo or CanSignWith is not usable.
+type KeyUsabilityProblem struct {
+	Fingerprint string           // the concerned key, empty for UsabilityNoKey
+	Problem     UsabilityProblem // what is wrong
+	Since       time.Time        // when the key or subkey expired, set by WhyCannotEncrypt and WhyCannotSign
+	Detail      string           // an explanation, set by WhyCannotEncrypt and WhyCannotSign
+}
+
+// String returns a readable description of the problem.
+func (p KeyUsabilityProblem) String() string {
+	text := UsabilityProblemString[p.Problem]
+	if p.Fingerprint != "" {
+		text = fmt.Sprintf("key %s: %s", p.Fingerprint, text)
+	}
+	if !p.Since.IsZero() {
+		text += " on " + p.Since.Format(time.DateOnly)
+	}
+	if p.Detail != "" {
+		text += " (" + p.Detail + ")"
+	}
+	return text
+}
+
+// CanEncryptTo reports whether data can currently be encrypted to the
+// key selected by pattern, e.g. a fingerprint or an email address: a
+// matching key exists, which is not expired, revoked, disabled or
+// invalid and has a subkey for encryption.
+// If usable is false, problems tells the reason for every matching key.
+func CanEncryptTo(pattern string) (usable bool, problems []KeyUsabilityProblem,
+	err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return false, nil, fmt.Errorf("CanEncryptTo - %w", err)
+	}
+	defer myContext.Release()
+
+	usable, problems, err = canEncryptTo(myContext, pattern)
+	if err != nil {
+		return false, nil, fmt.Errorf("CanEncryptTo - %w", err)
+	}
+	return usable, problems, nil
+}
+
+// canEncryptTo implements CanEncryptTo using myContext.
+func canEncryptTo(myContext *gpgme.Context, pattern string) (usable bool,
+	problems []KeyUsabilityProblem, err error) {
+
+	keys, err := findKeys(myContext, pattern, false)
+	if err != nil {
+		return false, nil, fmt.Errorf("FindKeys failed: %w", err)
+	}
+	if len(keys) == 0 {
+		return false, []KeyUsabilityProblem{{Problem: UsabilityNoKey}}, nil
+	}
+
+	for _, k := range keys {
+		problem := keyUsabilityProblem(k)
+		if problem == 0 && !k.CanEncrypt() {
+			problem = UsabilityNoCapability
+		}
+		if problem == 0 {
+			return true, nil, nil
+		}
+		problems = append(problems, KeyUsabilityProblem{
+			Fingerprint: k.Fingerprint(), Problem: problem})
+	}
+	return false, problems, nil
+}
+
+// CanSignWith reports whether data can currently be signed with the key
+// selected by pattern: a matching key with an available secret key
+// exists, which is not expired, revoked, disabled or invalid and has a
+// subkey for signing. A secret key stored on a smartcard counts as
+// available, the card itself is not checked.
+// If usable is false, problems tells the reason for every matching key.
+func CanSignWith(pattern string) (usable bool, problems []KeyUsabilityProblem,
+	err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return false, nil, fmt.Errorf("CanSignWith - %w", err)
+	}
+	defer myContext.Release()
+
+	usable, problems, err = canSignWith(myContext, pattern)
+	if err != nil {
+		return false, nil, fmt.Errorf("CanSignWith - %w", err)
+	}
+	return usable, problems, nil
+}
+
+// canSignWith implements CanSignWith using myContext.
+func canSignWith(myContext *gpgme.Context, pattern string) (usable bool,
+	problems []KeyUsabilityProblem, err error) {
+
+	keys, err := findKeys(myContext, pattern, true)
+	if err != nil {
+		return false, nil, fmt.Errorf("FindKeys failed: %w", err)
+	}
+	if len(keys) == 0 {
+		// tell apart unknown keys and public keys without secret key
+		public, err := findKeys(myContext, pattern, false)
+		if err != nil {
+			return false, nil, fmt.Errorf("FindKeys failed: %w", err)
+		}
+		if len(public) == 0 {
+			return false, []KeyUsabilityProblem{{Problem: UsabilityNoKey}}, nil
+		}
+		for _, k := range public {
+			problems = append(problems, KeyUsabilityProblem{
+				Fingerprint: k.Fingerprint(), Problem: UsabilityNoSecretKey})
+		}
+		return false, problems, nil
+	}
+
+	for _, k := range keys {
+		problem := keyUsabilityProblem(k)
+		switch {
+		case problem != 0:
+		case !k.CanSign():
+			problem = UsabilityNoCapability
+		case !hasSecretSubKey(k):
+			problem = UsabilityNoSecretKey
+		default:
+			return true, nil, nil
+		}
+		problems = append(problems, KeyUsabilityProblem{
+			Fingerprint: k.Fingerprint(), Problem: problem})
+	}
+	return false, problems, nil
+}
+
+// WhyCannotEncrypt explains why data can not be encrypted to the key
+// selected by pattern. For every matching key it lists all problems,
+// e.g. that the key expired on a date, has only a subkey for signing
+// or that no user ID is valid, because the owner trust of the
+// certifying keys is insufficient. It returns no problems, if a
+// matching key is usable.
+// The encryption functions of this package trust all recipient keys,
+// so they also encrypt to keys which only have the problem
+// UsabilityNotTrusted, gpg with its default trust model refuses them.
+func WhyCannotEncrypt(pattern string) (problems []KeyUsabilityProblem, err error) {
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("WhyCannotEncrypt - %w", err)
+	}
+	defer myContext.Release()
+
+	problems, err = whyCannot(myContext, pattern, "e")
+	if err != nil {
+		return nil, fmt.Errorf("WhyCannotEncrypt - %w", err)
+	}
+	return problems, nil
+}
+
+// WhyCannotSign explains like WhyCannotEncrypt why data can not be
+// signed with the key selected by pattern, including a missing secret
+// key. It returns no problems, if a matching key is usable.
+func WhyCannotSign(pattern string) (problems []KeyUsabilityProblem, err error) {
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("WhyCannotSign - %w", err)
+	}
+	defer myContext.Release()
+
+	problems, err = whyCannot(myContext, pattern, "s")
+	if err != nil {
+		return nil, fmt.Errorf("WhyCannotSign - %w", err)
+	}
+	return problems, nil
+}
+
+// whyCannot implements WhyCannotEncrypt and WhyCannotSign for the
+// capability "e" or "s" using myContext.
+func whyCannot(myContext *gpgme.Context, pattern, capability string) (
+	problems []KeyUsabilityProblem, err error) {
+
+	keys, err := findKeys(myContext, pattern, false)
+	if err != nil {
+		return nil, fmt.Errorf("FindKeys failed: %w", err)
+	}
+	if len(keys) == 0 {
+		return []KeyUsabilityProblem{{Problem: UsabilityNoKey,
+			Detail: fmt.Sprintf("no key matches %q", pattern)}}, nil
+	}
+
+	// the public key listing does not tell which secret keys exist
+	secret := make(map[string]bool)
+	if capability == "s" {
+		secretKeys, err := findKeys(myContext, pattern, true)
+		if err != nil {
+			return nil, fmt.Errorf("FindKeys failed: %w", err)
+		}
+		for _, k := range secretKeys {
+			for sk := k.SubKeys(); sk != nil; sk = sk.Next() {
+				secret[sk.Fingerprint()] = sk.Secret()
+			}
+		}
+	}
+
+	// gpgme.go does not provide the capabilities of subkeys
+	facts, err := colonKeys(contextHomeDir(myContext), pattern, false)
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return nil, fmt.Errorf("listing the subkeys failed: %w", err)
+	}
+	for _, k := range keys {
+		found := keyProblems(k, facts, secret, capability)
+		if len(found) == 0 {
+			return nil, nil
+		}
+		problems = append(problems, found...)
+	}
+	return problems, nil
+}
+
+// keyProblems returns all problems of the key k for the capability,
+// using the subkey facts of the colon listing and, for signing, which
+// subkeys have a secret key.
+func keyProblems(k *gpgme.Key, facts map[string]colonKey, secret map[string]bool,
+	capability string) (problems []KeyUsabilityProblem) {
+
+	add := func(problem UsabilityProblem, since time.Time, detail string) {
+		problems = append(problems, KeyUsabilityProblem{Fingerprint: k.Fingerprint(),
+			Problem: problem, Since: since, Detail: detail})
+	}
+
+	if problem := keyUsabilityProblem(k); problem != 0 {
+		var since time.Time
+		if problem == UsabilityExpired && k.SubKeys() != nil {
+			since = k.SubKeys().Expires()
+		}
+		add(problem, since, "")
+	}
+
+	// the subkeys with the capability and the capabilities of the others
+	var valid, expired int
+	var lastExpired time.Time
+	var haveSecret bool
+	var others string
+	for sk := k.SubKeys(); sk != nil; sk = sk.Next() {
+		capabilities := facts[sk.Fingerprint()].Capabilities
+		if !strings.Contains(capabilities, capability) {
+			if !sk.Revoked() && !sk.Expired() && !sk.Invalid() && !sk.Disabled() {
+				others += capabilities
+			}
+			continue
+		}
+		switch {
+		case sk.Expired():
+			expired++
+			if sk.Expires().After(lastExpired) {
+				lastExpired = sk.Expires()
+			}
+		case sk.Revoked(), sk.Invalid(), sk.Disabled():
+		default:
+			valid++
+			haveSecret = haveSecret || secret[sk.Fingerprint()] || sk.CardNumber() != ""
+		}
+	}
+	switch {
+	case valid > 0:
+	case expired > 0:
+		add(UsabilityNoCapability, lastExpired,
+			fmt.Sprintf("the %s subkey expired", capabilityName(capability)))
+	default:
+		add(UsabilityNoCapability, time.Time{},
+			"the key can only "+capabilityNames(others))
+	}
+	if capability == "s" && valid > 0 && !haveSecret {
+		add(UsabilityNoSecretKey, time.Time{},
+			"the secret key of the signing subkey is not available")
+	}
+
+	if capability == "e" {
+		trusted := false
+		for uid := k.UserIDs(); uid != nil; uid = uid.Next() {
+			if !uid.Revoked() && !uid.Invalid() &&
+				(uid.Validity() == gpgme.ValidityMarginal ||
+					uid.Validity() == gpgme.ValidityFull ||
+					uid.Validity() == gpgme.ValidityUltimate) {
+				trusted = true
+			}
+		}
+		if !trusted {
+			add(UsabilityNotTrusted, time.Time{}, "no user ID has at least "+
+				"marginal validity, the owner trust of the certifying keys is insufficient")
+		}
+	}
+	return problems
+}
+
+// capabilityName returns the name of a capability letter of gpg.
+func capabilityName(capability string) string {
+	switch capability {
+	case "e":
+		return "encryption"
+	case "s":
+		return "signing"
+	case "c":
+		return "certification"
+	case "a":
+		return "authentication"
+	}
+	return capability
+}
+
+// capabilityNames describes the capabilities, e.g. "sc" as "sign and
+// certify".
+func capabilityNames(capabilities string) string {
+	var names []string
+	for _, c := range []struct{ letter, name string }{
+		{"s", "sign"}, {"c", "certify"}, {"e", "encrypt"}, {"a", "authenticate"}} {
+		if strings.Contains(capabilities, c.letter) {
+			names = append(names, c.name)
+		}
+	}
+	switch len(names) {
+	case 0:
+		return "be used for nothing"
+	case 1:
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// keyUsabilityProblem returns why the key can not be used at all, or 0.
+func keyUsabilityProblem(k *gpgme.Key) UsabilityProblem {
+	switch {
+	case k.Revoked():
+		return UsabilityRevoked
+	case k.Expired():
+		return UsabilityExpired
+	case k.Disabled():
+		return UsabilityDisabled
+	case k.Invalid():
+		return UsabilityInvalid
+	}
+	return 0
+}
+
+// hasSecretSubKey reports whether a valid subkey of k has its secret
+// part available, which is not the case for the stubs left by moving
+// secret keys to an offline backup.
+func hasSecretSubKey(k *gpgme.Key) bool {
+	for sk := k.SubKeys(); sk != nil; sk = sk.Next() {
+		if sk.Secret() && !sk.Revoked() && !sk.Expired() && !sk.Disabled() &&
+			!sk.Invalid() {
+			return true
+		}
+	}
+	return false
+}
+
+// EOF
@@ -0,0 +1,182 @@
+/* userid.go - parsing OpenPGP user IDs for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// ErrInvalidUserID is returned by ParseUserID for a user ID which is not
+// of the form "Name (Comment) <email>" or has an invalid mail address.
+var ErrInvalidUserID = errors.New("invalid user ID")
+
+// UserIDParts are the parts of a user ID "Name (Comment) <email>".
+// Each part may be empty.
+type UserIDParts struct {
+	Name    string
+	Comment string
+	Email   string // the address without angle brackets
+}
+
+// String returns the user ID of the parts in the form gpg creates it.
+func (p UserIDParts) String() string {
+	var parts []string
+	if p.Name != "" {
+		parts = append(parts, p.Name)
+	}
+	if p.Comment != "" {
+		parts = append(parts, "("+p.Comment+")")
+	}
+	if p.Email != "" {
+		parts = append(parts, "<"+p.Email+">")
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseUserID splits a user ID like "Alice (work) <alice@example.com>"
+// into its parts. A user ID may also be only a mail address or only a
+// name. The mail address must be a valid RFC 5322 address (addr-spec).
+// The error matches ErrInvalidUserID with errors.Is.
+func ParseUserID(uid string) (parts UserIDParts, err error) {
+	rest := strings.TrimSpace(uid)
+	if rest == "" {
+		return parts, fmt.Errorf("%w: empty", ErrInvalidUserID)
+	}
+
+	switch {
+	case strings.HasSuffix(rest, ">"):
+		start := strings.LastIndexByte(rest, '<')
+		if start < 0 {
+			return parts, fmt.Errorf("%w: %q has no opening <", ErrInvalidUserID, uid)
+		}
+		parts.Email = rest[start+1 : len(rest)-1]
+		rest = strings.TrimSpace(rest[:start])
+	case !strings.ContainsAny(rest, " ()") && strings.Contains(rest, "@"):
+		// only a mail address
+		parts.Email, rest = rest, ""
+	}
+	if parts.Email != "" || strings.HasSuffix(uid, "<>") {
+		if err := checkAddrSpec(parts.Email); err != nil {
+			return UserIDParts{}, fmt.Errorf("%w: %q: %w", ErrInvalidUserID, uid, err)
+		}
+	}
+
+	if strings.HasSuffix(rest, ")") {
+		start := strings.LastIndexByte(rest, '(')
+		if start < 0 {
+			return UserIDParts{}, fmt.Errorf("%w: %q has no opening (",
+				ErrInvalidUserID, uid)
+		}
+		parts.Comment = rest[start+1 : len(rest)-1]
+		rest = strings.TrimSpace(rest[:start])
+	}
+	if strings.ContainsAny(rest, "<>") {
+		return UserIDParts{}, fmt.Errorf("%w: %q has a misplaced < or >",
+			ErrInvalidUserID, uid)
+	}
+	parts.Name = rest
+	return parts, nil
+}
+
+// checkAddrSpec checks that email is a plain RFC 5322 address without a
+// display name.
+func checkAddrSpec(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil {
+		return err
+	}
+	if addr.Name != "" || addr.Address != email {
+		return fmt.Errorf("%q is not a plain mail address", email)
+	}
+	return nil
+}
+
+// Parts returns the parsed user ID, see ParseUserID.
+func (u KeyUserIDsType) Parts() (UserIDParts, error) {
+	return ParseUserID(u.UserID)
+}
+
+// PrimaryUserID returns the primary user ID of the key as selected by
+// gpg: of the valid user IDs, which are not revoked, the newest one
+// flagged as primary by its self-signature or, if none is flagged, the
+// newest one. gpgme.go does not report the flag, but gpg lists the
+// primary user ID first, so the first valid user ID is returned. If the
+// key has no valid user ID, the one with the newest self-signature is
+// returned, which requires the key signatures, see LoadKeySignatures,
+// else the first one. ok is false, if the key has no user IDs.
+func (k KeyType) PrimaryUserID() (uid KeyUserIDsType, ok bool) {
+	if len(k.UserIDs) == 0 {
+		return uid, false
+	}
+	for _, u := range k.UserIDs {
+		if !u.Revoked && !u.Invalid {
+			return u, true
+		}
+	}
+
+	uid = k.UserIDs[0]
+	newest := k.selfSignatureTime(uid)
+	for _, u := range k.UserIDs[1:] {
+		if created := k.selfSignatureTime(u); created.After(newest) {
+			uid, newest = u, created
+		}
+	}
+	return uid, true
+}
+
+// selfSignatureTime returns the creation time of the newest
+// self-signature of the user ID u, the zero time if there is none or the
+// signatures are not listed. Revocations are not counted.
+func (k KeyType) selfSignatureTime(u KeyUserIDsType) (created time.Time) {
+	for _, sig := range u.Signatures[k.KeyID] {
+		if !sig.Revoked && !sig.Invalid && sig.CreationTime.After(created) {
+			created = sig.CreationTime
+		}
+	}
+	return created
+}
+
+// DisplayName returns the name of the key for user interfaces, made of
+// the name and the mail address of the primary user ID, e.g.
+// "Alice <alice@example.com>", without the comment. It is the user ID
+// itself, e.g. the subject of X.509 certificates, if it has neither, and
+// the long key ID, if the key has no user IDs.
+func (k KeyType) DisplayName() string {
+	uid, ok := k.PrimaryUserID()
+	if !ok {
+		return k.KeyID
+	}
+	switch {
+	case uid.Name != "" && uid.Address != "":
+		return uid.Name + " <" + uid.Address + ">"
+	case uid.Name != "":
+		return uid.Name
+	case uid.Address != "":
+		return uid.Address
+	}
+	return uid.UserID
+}
+
+// EOF
@@ -0,0 +1,93 @@
+/* userid_test.go - tests of the user ID parsing
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseUserID(t *testing.T) {
+	tests := []struct {
+		uid   string
+		want  UserIDParts
+		fails bool
+	}{
+		{uid: "Alice (work) <alice@example.com>",
+			want: UserIDParts{Name: "Alice", Comment: "work", Email: "alice@example.com"}},
+		{uid: "Alice Doe <alice@example.com>",
+			want: UserIDParts{Name: "Alice Doe", Email: "alice@example.com"}},
+		{uid: "<alice@example.com>", want: UserIDParts{Email: "alice@example.com"}},
+		{uid: "alice@example.com", want: UserIDParts{Email: "alice@example.com"}},
+		{uid: "  Alice  ", want: UserIDParts{Name: "Alice"}},
+		{uid: "Release Key (2025)", want: UserIDParts{Name: "Release Key", Comment: "2025"}},
+		{uid: "", fails: true},
+		{uid: "   ", fails: true},
+		{uid: "Alice alice@example.com>", fails: true},
+		{uid: "Alice <not an address>", fails: true},
+		{uid: "Alice <Bob <bob@example.com>>", fails: true},
+		{uid: "Alice <>", fails: true},
+		{uid: "Alice comment) <alice@example.com>", fails: true},
+		{uid: "Alice <x> <alice@example.com>", fails: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseUserID(tt.uid)
+		switch {
+		case tt.fails:
+			if !errors.Is(err, ErrInvalidUserID) {
+				t.Errorf("ParseUserID(%q) = %+v, %v, want ErrInvalidUserID", tt.uid, got, err)
+			}
+		case err != nil:
+			t.Errorf("ParseUserID(%q): %v", tt.uid, err)
+		case got != tt.want:
+			t.Errorf("ParseUserID(%q) = %+v, want %+v", tt.uid, got, tt.want)
+		}
+	}
+}
+
+func TestUserIDPartsString(t *testing.T) {
+	tests := []struct {
+		parts UserIDParts
+		want  string
+	}{
+		{UserIDParts{Name: "Alice", Comment: "work", Email: "alice@example.com"},
+			"Alice (work) <alice@example.com>"},
+		{UserIDParts{Name: "Alice", Email: "alice@example.com"}, "Alice <alice@example.com>"},
+		{UserIDParts{Email: "alice@example.com"}, "<alice@example.com>"},
+		{UserIDParts{Name: "Alice"}, "Alice"},
+		{UserIDParts{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.parts.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.parts, got, tt.want)
+		}
+		if tt.want == "" {
+			continue
+		}
+		// the user IDs created by String parse to the same parts
+		parsed, err := ParseUserID(tt.want)
+		if err != nil || parsed != tt.parts {
+			t.Errorf("ParseUserID(%q) = %+v, %v, want %+v", tt.want, parsed, err, tt.parts)
+		}
+	}
+}
+
+// EOF
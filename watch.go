@@ -0,0 +1,200 @@
+/* watch.go - encrypting the files of a drop directory for the gpgme.go library
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// The directory is polled, so no platform specific notification API is
+// needed and files on network file systems are found, too.
+
+package gpggohigh
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// WatchOptions configures WatchAndEncrypt.
+type WatchOptions struct {
+	// TargetDir receives the encrypted files, named like the source
+	// file with the extension `.gpg`, or `.asc` if Armored is set.
+	// It must not be the watched directory.
+	TargetDir string
+	// Interval is the time between two scans of the directory, 2 seconds
+	// if zero.
+	Interval time.Duration
+	// SettleTime is how long the size and modification time of a file
+	// must not change before it is encrypted, so files still being
+	// written are not encrypted. It is Interval if zero.
+	SettleTime time.Duration
+	Sign       bool           // sign the files with the default key
+	Armored    bool           // ASCII armor the encrypted files
+	Encrypt    EncryptOptions // algorithms and the removal of the source file
+	// Retry is the policy for transient errors, DefaultRetryPolicy if
+	// MaxAttempts is zero.
+	Retry RetryPolicy
+	// OnEncrypted, if set, is called after a file has been encrypted.
+	OnEncrypted func(source, destination string)
+	// OnError, if set, is called if a file can not be encrypted. The
+	// file is tried again, when it changed.
+	OnError func(source string, err error)
+}
+
+// watchedFile is the state of a file in the watched directory.
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+	since   time.Time // when the size and modification time were seen first
+	done    bool      // this version was encrypted or failed
+}
+
+// WatchAndEncrypt watches the drop directory dir and encrypts every new
+// or changed file in it to the recipients, writing the result to
+// opts.TargetDir, e.g. for a hot folder integration. Files whose name
+// starts with a dot and subdirectories are ignored. Unless the source
+// files are removed, see EncryptOptions.RemoveSource, a file is only
+// encrypted again after it changed.
+// WatchAndEncrypt runs until ctx is done and then returns nil. Errors
+// encrypting single files are passed to opts.OnError, only errors of
+// the directory itself end the watching.
+func WatchAndEncrypt(ctx context.Context, dir string, recipients []string,
+	opts WatchOptions) error {
+
+	err := watchAndEncrypt(ctx, gpgCommand{}, dir, recipients, opts)
+	if err != nil {
+		return fmt.Errorf("WatchAndEncrypt - %w", err)
+	}
+	return nil
+}
+
+// watchAndEncrypt implements WatchAndEncrypt running cmd for the
+// encryption.
+func watchAndEncrypt(ctx context.Context, cmd gpgCommand, dir string,
+	recipients []string, opts WatchOptions) error {
+
+	if len(recipients) == 0 {
+		return fmt.Errorf("no recipients given")
+	}
+	if opts.TargetDir == "" {
+		return fmt.Errorf("no target directory given")
+	}
+	source, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	target, err := filepath.Abs(opts.TargetDir)
+	if err != nil {
+		return err
+	}
+	if source == target {
+		return fmt.Errorf("target directory is the watched directory %s", dir)
+	}
+	if info, err := os.Stat(target); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("target %s is not a directory", target)
+	}
+
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	settle := opts.SettleTime
+	if settle <= 0 {
+		settle = interval
+	}
+	retry := opts.Retry
+	if retry.MaxAttempts == 0 {
+		retry = DefaultRetryPolicy
+	}
+	// the encryptions are retried here, with the daemons of the home
+	// directory of cmd, not once more by cmd
+	cmd.retry = &RetryPolicy{}
+	extension := ".gpg"
+	if opts.Armored {
+		extension = ".asc"
+	}
+
+	files := make(map[string]*watchedFile)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		entries, err := os.ReadDir(source)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		seen := make(map[string]bool)
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.Type().IsRegular() || strings.HasPrefix(name, ".") {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				// removed meanwhile
+				continue
+			}
+			seen[name] = true
+
+			f := files[name]
+			if f == nil || f.size != info.Size() || !f.modTime.Equal(info.ModTime()) {
+				files[name] = &watchedFile{size: info.Size(), modTime: info.ModTime(),
+					since: now}
+				continue
+			}
+			if f.done || now.Sub(f.since) < settle {
+				continue
+			}
+
+			if err := ctx.Err(); err != nil {
+				return nil
+			}
+			f.done = true
+			sourceFile := filepath.Join(source, name)
+			destination := filepath.Join(target, name+extension)
+			err = retry.retry(ctx, cmd.homeDir, func() error {
+				return encryptFileOptions(cmd, sourceFile, destination, recipients,
+					opts.Sign, opts.Armored, opts.Encrypt)
+			}, nil)
+			switch {
+			case err != nil && opts.OnError != nil:
+				opts.OnError(sourceFile, err)
+			case err == nil && opts.OnEncrypted != nil:
+				opts.OnEncrypted(sourceFile, destination)
+			}
+		}
+		for name := range files {
+			if !seen[name] {
+				delete(files, name)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// EOF
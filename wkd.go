@@ -0,0 +1,259 @@
+/* wkd.go - publishing keys in a Web Key Directory
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+// A Web Key Directory serves the key of a mail address by HTTPS under
+// the z-base-32 encoded SHA-1 hash of the lower case local part, see
+// draft-koch-openpgp-webkey-service. The advanced method uses the host
+// openpgpkey.<domain> with the path
+// `.well-known/openpgpkey/<domain>/hu/<hash>`, the direct method the
+// host <domain> with the path `.well-known/openpgpkey/hu/<hash>`. The
+// directory of the hu directory must contain a policy file.
+
+package gpggohigh
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kulbartsch/gpgme"
+)
+
+// zbase32 is the z-base-32 encoding used for the WKD hash.
+var zbase32 = base32.NewEncoding("ybndrfg8ejkmcpqxot1uwisza345h769").
+	WithPadding(base32.NoPadding)
+
+// WKDOptions configures PublishWKD.
+type WKDOptions struct {
+	// Domain publishes only the mail addresses of this domain. If empty,
+	// the addresses of all domains are published.
+	Domain string
+	// Direct writes the layout of the direct method, for the web server
+	// of the mail domain itself, instead of the one of the advanced
+	// method. It supports only one domain.
+	Direct bool
+}
+
+// WKDFileType is a key file written by PublishWKD.
+type WKDFileType struct {
+	Address      string   // the mail address, in lower case
+	Fingerprints []string // the keys in the file
+	Path         string   // the name of the file
+}
+
+// WKDHash returns the WKD hash of the local part of the mail address
+// and its domain in lower case, e.g. "iy9q119eutrkn8s1mk4r39qejnbu3n5q"
+// and "example.org" for "Joe.Doe@Example.ORG".
+func WKDHash(address string) (hash, domain string, err error) {
+	local, domain, found := strings.Cut(strings.TrimSpace(address), "@")
+	if !found || local == "" || domain == "" || strings.Contains(domain, "@") {
+		return "", "", fmt.Errorf("WKDHash - not a mail address: %q", address)
+	}
+	sum := sha1.Sum([]byte(strings.ToLower(local)))
+	return zbase32.EncodeToString(sum[:]), strings.ToLower(domain), nil
+}
+
+// PublishWKD writes the keys matching the patterns, e.g. fingerprints or
+// mail addresses, into a Web Key Directory below the directory root,
+// which is served as document root, e.g. the file
+// `<root>/.well-known/openpgpkey/example.org/hu/<hash>` for the
+// advanced method. Each file holds the keys with a user ID of its mail
+// address, without other user IDs and third-party signatures. Revoked
+// and expired keys and user IDs are not published. Existing key files
+// are replaced, an existing policy file is kept.
+func PublishWKD(root string, patterns []string, opts WKDOptions) (
+	files []WKDFileType, err error) {
+
+	myContext, err := newContext(SessionOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("PublishWKD - %w", err)
+	}
+	defer myContext.Release()
+
+	files, err = publishWKD(myContext, gpgCommand{homeDir: contextHomeDir(myContext)},
+		root, patterns, opts)
+	if err != nil {
+		return files, fmt.Errorf("PublishWKD - %w", err)
+	}
+	return files, nil
+}
+
+// publishWKD implements PublishWKD, the keys are listed using myContext
+// and exported running the command cmd.
+func publishWKD(myContext *gpgme.Context, cmd gpgCommand, root string,
+	patterns []string, opts WKDOptions) (files []WKDFileType, err error) {
+
+	if len(patterns) == 0 {
+		return nil, errors.New("no keys given")
+	}
+	domain := strings.ToLower(opts.Domain)
+
+	// the fingerprints of the keys by mail address
+	byAddress := make(map[string][]string)
+	filter := KeyFilter{NotExpired: true, NotRevoked: true, WithoutSignatures: true}
+	for _, pattern := range patterns {
+		keys, err := keyListFiltered(context.Background(), myContext, pattern, filter)
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, pattern)
+		}
+		for _, key := range keys {
+			if key.Disabled || key.Invalid {
+				continue
+			}
+			for _, uid := range key.UserIDs {
+				address := strings.ToLower(uid.Address)
+				if address == "" || uid.Revoked || uid.Invalid {
+					continue
+				}
+				_, d, found := strings.Cut(address, "@")
+				if !found || domain != "" && d != domain {
+					continue
+				}
+				if !slices.Contains(byAddress[address], key.Fingerprint) {
+					byAddress[address] = append(byAddress[address], key.Fingerprint)
+				}
+			}
+		}
+	}
+	if len(byAddress) == 0 {
+		return nil, errors.New("no mail address to publish")
+	}
+
+	addresses := make([]string, 0, len(byAddress))
+	var domains []string
+	for address := range byAddress {
+		addresses = append(addresses, address)
+		_, d, _ := strings.Cut(address, "@")
+		if !slices.Contains(domains, d) {
+			domains = append(domains, d)
+		}
+	}
+	if opts.Direct && len(domains) > 1 {
+		slices.Sort(domains)
+		return nil, fmt.Errorf("the direct method supports only one domain, found %s",
+			strings.Join(domains, ", "))
+	}
+
+	slices.Sort(addresses)
+	for _, address := range addresses {
+		file, err := writeWKDFile(cmd, root, address, byAddress[address], opts.Direct)
+		if err != nil {
+			return files, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// wkdDir returns the directory below root containing the hu directory
+// and the policy file for the domain.
+func wkdDir(root, domain string, direct bool) string {
+	if direct {
+		return filepath.Join(root, ".well-known", "openpgpkey")
+	}
+	return filepath.Join(root, ".well-known", "openpgpkey", domain)
+}
+
+// writeWKDFile exports the keys with the fingerprints running the
+// command cmd into the WKD file of the mail address below root and
+// creates the policy file, if there is none.
+func writeWKDFile(cmd gpgCommand, root, address string, fingerprints []string,
+	direct bool) (file WKDFileType, err error) {
+
+	hash, domain, err := WKDHash(address)
+	if err != nil {
+		return file, err
+	}
+	dir := wkdDir(root, domain, direct)
+	if err := os.MkdirAll(filepath.Join(dir, "hu"), 0755); err != nil {
+		return file, err
+	}
+	if err := createWKDPolicy(filepath.Join(dir, "policy")); err != nil {
+		return file, err
+	}
+
+	keyData, err := exportAddressKeys(cmd, address, fingerprints)
+	if err != nil {
+		return file, err
+	}
+
+	file = WKDFileType{Address: address, Fingerprints: fingerprints,
+		Path: filepath.Join(dir, "hu", hash)}
+	fh, err := createTemp(file.Path)
+	if err != nil {
+		return file, err
+	}
+	if _, err := fh.Write(keyData); err != nil {
+		fh.Close()
+		_ = os.Remove(fh.Name())
+		return file, err
+	}
+	// the web server must be able to read the key
+	if err := fh.Chmod(0644); err != nil {
+		fh.Close()
+		_ = os.Remove(fh.Name())
+		return file, err
+	}
+	return file, commitTemp(fh, file.Path)
+}
+
+// exportAddressKeys exports the keys with the fingerprints running the
+// command cmd, with only the user IDs of the mail address and without
+// third-party signatures.
+func exportAddressKeys(cmd gpgCommand, address string, fingerprints []string) (
+	[]byte, error) {
+
+	var out bytes.Buffer
+	cmd.args = append([]string{"--export", "--export-options", "export-minimal",
+		"--export-filter", "keep-uid=mbox=" + address, "--"}, fingerprints...)
+	cmd.stdout = &out
+	if _, err := cmd.run(); err != nil {
+		return nil, err
+	}
+	if out.Len() == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, strings.Join(fingerprints, ", "))
+	}
+	return out.Bytes(), nil
+}
+
+// createWKDPolicy creates the empty policy file filename, which clients
+// require, unless it exists.
+func createWKDPolicy(filename string) error {
+	fh, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fh.Close()
+}
+
+// EOF
@@ -0,0 +1,74 @@
+/* wkd_test.go - tests of the Web Key Directory
+ * Copyright (C) 2025-2025 g10 Code GmbH
+ *
+ * This library is free software; you can redistribute it and/or
+ * modify it under the terms of the GNU Lesser General Public
+ * License as published by the Free Software Foundation; either
+ * version 2.1 of the License, or (at your option) any later version.
+ *
+ * This library is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+ * Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public
+ * License along with this library; if not, see
+ * <https://www.gnu.org/licenses/>.
+ *
+ * SPDX-License-Identifier: GPL-2.1-or-later
+ */
+
+package gpggohigh
+
+import "testing"
+
+func TestWKDHash(t *testing.T) {
+	tests := []struct {
+		address, hash, domain string
+		fails                 bool
+	}{
+		// the example of draft-koch-openpgp-webkey-service
+		{address: "Joe.Doe@Example.ORG", hash: "iy9q119eutrkn8s1mk4r39qejnbu3n5q",
+			domain: "example.org"},
+		{address: " joe.doe@example.org\n", hash: "iy9q119eutrkn8s1mk4r39qejnbu3n5q",
+			domain: "example.org"},
+		{address: "alice@example.com", hash: "kei1q4tipxxu1yj79k9kfukdhfy631xe",
+			domain: "example.com"},
+		{address: "example.org", fails: true},
+		{address: "@example.org", fails: true},
+		{address: "joe@", fails: true},
+		{address: "joe@doe@example.org", fails: true},
+		{address: "", fails: true},
+	}
+	for _, tt := range tests {
+		hash, domain, err := WKDHash(tt.address)
+		switch {
+		case tt.fails:
+			if err == nil {
+				t.Errorf("WKDHash(%q) = %q, %q, want an error", tt.address, hash, domain)
+			}
+		case err != nil:
+			t.Errorf("WKDHash(%q): %v", tt.address, err)
+		case hash != tt.hash || domain != tt.domain:
+			t.Errorf("WKDHash(%q) = %q, %q, want %q, %q", tt.address, hash, domain,
+				tt.hash, tt.domain)
+		}
+	}
+}
+
+func TestWKDDir(t *testing.T) {
+	tests := []struct {
+		direct bool
+		want   string
+	}{
+		{false, "/srv/www/.well-known/openpgpkey/example.org"},
+		{true, "/srv/www/.well-known/openpgpkey"},
+	}
+	for _, tt := range tests {
+		if got := wkdDir("/srv/www", "example.org", tt.direct); got != tt.want {
+			t.Errorf("wkdDir(direct %v) = %q, want %q", tt.direct, got, tt.want)
+		}
+	}
+}
+
+// EOF